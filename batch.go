@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"time"
+)
+
+// BatchResult is the outcome of a single invocation run by [RunBatch].
+type BatchResult struct {
+	// Args are the arguments of the invocation.
+	Args []string `json:"args"`
+	// Command is the path of the terminal command, e.g., "todo task add". It is empty if the
+	// arguments could not be parsed.
+	Command string `json:"command,omitempty"`
+	// Duration is how long parsing and running the command took. It is encoded in JSON as
+	// nanoseconds.
+	Duration time.Duration `json:"duration"`
+	// ExitCode is the exit code of the invocation: 0 on success, 2 for parse errors, the code of an
+	// [ExitError] returned by Exec, 130 for errors wrapping [ErrInterrupted], and 1 otherwise.
+	ExitCode int `json:"exitCode"`
+	// Error is the error message of a failed invocation.
+	Error string `json:"error,omitempty"`
+	// Stdout and Stderr hold everything the command wrote to the respective stream.
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	// Result is the result recorded by Exec with [State.SetResult], if any.
+	Result any `json:"result,omitempty"`
+}
+
+// BatchReport is the structured report returned by [RunBatch].
+type BatchReport struct {
+	// Results holds the outcome of each invocation, in the order they were given.
+	Results []BatchResult `json:"results"`
+}
+
+// Failed reports whether any invocation exited with a non-zero exit code.
+func (r *BatchReport) Failed() bool {
+	return slices.ContainsFunc(r.Results, func(res BatchResult) bool { return res.ExitCode != 0 })
+}
+
+// WriteJSON writes the report as indented JSON to w, for CI systems consuming many invocations at
+// once.
+func (r *BatchReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// RunBatch parses and runs each of the given argument lists against the command tree in order,
+// capturing the output of every invocation separately. A failing invocation does not stop the
+// batch; its exit code and error are recorded in the report. The command tree is parsed with
+// [ParseInvocation], so it is left unmodified.
+//
+// The options parameter may be nil, in which case default values are used. Stdout and Stderr of the
+// options are ignored, since output is captured in the report.
+func RunBatch(ctx context.Context, root *Command, invocations [][]string, options *RunOptions) *BatchReport {
+	var opt RunOptions
+	if options != nil {
+		opt = *options
+	}
+	report := &BatchReport{Results: make([]BatchResult, 0, len(invocations))}
+	for _, args := range invocations {
+		report.Results = append(report.Results, runBatchInvocation(ctx, root, args, opt))
+	}
+	return report
+}
+
+func runBatchInvocation(ctx context.Context, root *Command, args []string, opt RunOptions) BatchResult {
+	var stdout, stderr bytes.Buffer
+	opt.Stdout, opt.Stderr = &stdout, &stderr
+	res := BatchResult{Args: slices.Clone(args)}
+	start := time.Now()
+	inv, err := ParseInvocation(root, args)
+	if err != nil {
+		res.ExitCode = 2
+	} else {
+		res.Command = getCommandPath(inv.state.path)
+		var s *State
+		s, err = inv.run(ctx, &opt)
+		if s != nil {
+			res.Result, _ = s.Result()
+		}
+		if err != nil {
+			res.ExitCode = 1
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				res.ExitCode = exitErr.Code
+			} else if errors.Is(err, ErrInterrupted) {
+				res.ExitCode = 130
+			}
+		}
+	}
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.Error = err.Error()
+	}
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	return res
+}
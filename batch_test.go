@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBatch(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		SubCommands: []*Command{
+			{
+				Name: "echo",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Bool("err", false, "write to stderr")
+				}),
+				Exec: func(ctx context.Context, s *State) error {
+					w := s.Stdout
+					if GetFlag[bool](s, "err") {
+						w = s.Stderr
+					}
+					fmt.Fprint(w, s.Args)
+					s.SetResult(len(s.Args))
+					return nil
+				},
+			},
+			{
+				Name: "fail",
+				Exec: func(ctx context.Context, s *State) error {
+					return &ExitError{Code: 3, Err: errors.New("boom")}
+				},
+			},
+		},
+		Exec: func(ctx context.Context, s *State) error { return nil },
+	}
+
+	report := RunBatch(context.Background(), root, [][]string{
+		{"echo", "a", "b"},
+		{"echo", "--err", "c"},
+		{"fail"},
+		{"echo", "--unknown"},
+	}, nil)
+	require.Len(t, report.Results, 4)
+	for i := range report.Results {
+		require.Positive(t, report.Results[i].Duration)
+		report.Results[i].Duration = 0
+	}
+	require.Equal(t, []BatchResult{
+		{Args: []string{"echo", "a", "b"}, Command: "app echo", Stdout: "[a b]", Result: 2},
+		{Args: []string{"echo", "--err", "c"}, Command: "app echo", Stderr: "[c]", Result: 1},
+		{Args: []string{"fail"}, Command: "app fail", ExitCode: 3, Error: "boom"},
+		{Args: []string{"echo", "--unknown"}, ExitCode: 2, Error: `command "app echo": flag provided but not defined: -unknown`},
+	}, report.Results)
+	require.True(t, report.Failed())
+	require.Nil(t, root.state)
+
+	var buf bytes.Buffer
+	require.NoError(t, (&BatchReport{Results: report.Results[:1]}).WriteJSON(&buf))
+	require.Equal(t, `{
+  "results": [
+    {
+      "args": [
+        "echo",
+        "a",
+        "b"
+      ],
+      "command": "app echo",
+      "duration": 0,
+      "exitCode": 0,
+      "stdout": "[a b]",
+      "stderr": "",
+      "result": 2
+    }
+  ]
+}
+`, buf.String())
+}
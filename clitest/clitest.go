@@ -0,0 +1,123 @@
+// Package clitest provides helpers for testing commands built with the cli package. It runs a
+// command tree the same way a main function using [cli.ParseAndRun] would, capturing its output and
+// exit code, and compares output against golden files.
+//
+//	func TestVersion(t *testing.T) {
+//	    res := clitest.Run(t, buildRoot(), "--version")
+//	    require.Equal(t, 0, res.ExitCode)
+//	    require.Equal(t, "todo v1.0.0\n", res.Stdout)
+//	}
+//
+// Runs do not store parsed flag values in the command tree (see [cli.Options.Isolated]), so one
+// tree can be reused across runs and parallel tests. Commands must read their flags with
+// [cli.GetFlag] or [cli.LookupFlag] rather than through the variables they are bound to.
+package clitest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfridman/cli"
+)
+
+// UpdateEnv is the environment variable that, when set to a non-empty value, makes [Golden]
+// write the output to the golden file instead of comparing against it:
+//
+//	CLITEST_UPDATE=1 go test ./...
+const UpdateEnv = "CLITEST_UPDATE"
+
+// Result is the outcome of running a command tree.
+type Result struct {
+	// Stdout and Stderr hold everything the command wrote to the standard output and error
+	// streams, including usage and error messages written by [cli.ParseAndRun].
+	Stdout, Stderr string
+	// ExitCode is the exit code a main function would exit with: 0 on success, otherwise the code
+	// of the [cli.ExitError] returned by [cli.ParseAndRun].
+	ExitCode int
+	// Err is the error returned by [cli.ParseAndRun], if any.
+	Err error
+}
+
+// Run parses the arguments and runs the command tree with [cli.ParseAndRun], capturing its output.
+// Like [cli.Main], help requests exit with 0 and parse errors with 2. Stdin is empty and no environment variables are visible to the command. See [RunOptions] to
+// customize the invocation.
+func Run(t testing.TB, root *cli.Command, args ...string) *Result {
+	t.Helper()
+	return RunOptions(t, root, &cli.Options{Args: args})
+}
+
+// RunOptions is like [Run], but takes the full set of options. Stdout and Stderr are always
+// captured. Stdin defaults to an empty reader, LookupEnv to an empty environment, and Args to no
+// arguments. The arguments are always parsed with [cli.Options.Isolated] set.
+func RunOptions(t testing.TB, root *cli.Command, options *cli.Options) *Result {
+	t.Helper()
+	if options == nil {
+		options = &cli.Options{}
+	}
+	opt := *options
+	opt.Isolated = true
+	if opt.Args == nil {
+		opt.Args = []string{}
+	}
+	if opt.Stdin == nil {
+		opt.Stdin = strings.NewReader("")
+	}
+	if opt.LookupEnv == nil {
+		opt.LookupEnv = func(string) (string, bool) { return "", false }
+	}
+	var stdout, stderr bytes.Buffer
+	opt.Stdout = &stdout
+	opt.Stderr = &stderr
+
+	err := cli.ParseAndRun(context.Background(), root, &opt)
+	res := &Result{Err: err}
+	var exitErr *cli.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.Code
+	} else if err != nil {
+		res.ExitCode = 1
+	}
+	res.Stdout = stdout.String()
+	res.Stderr = stderr.String()
+	return res
+}
+
+// Help returns the help text of the command selected by args, as written to Stdout when --help is
+// passed. It fails the test if the help text cannot be rendered.
+func Help(t testing.TB, root *cli.Command, args ...string) string {
+	t.Helper()
+	res := Run(t, root, append(args, "--help")...)
+	if res.Err != nil {
+		t.Fatalf("clitest: help for %q: %v", strings.Join(args, " "), res.Err)
+	}
+	return res.Stdout
+}
+
+// Golden compares got against the contents of the golden file at path, usually under testdata,
+// and fails the test if they differ. If the [UpdateEnv] environment variable is set, the golden
+// file is written instead, creating its directory if needed.
+func Golden(t testing.TB, path string, got string) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("clitest: update golden file: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("clitest: update golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("clitest: read golden file (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if got != string(want) {
+		t.Errorf("clitest: output does not match golden file %s (set %s=1 to update it)\n\ngot:\n%s\n\nwant:\n%s",
+			path, UpdateEnv, got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package clitest
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfridman/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func newRoot() *cli.Command {
+	return &cli.Command{
+		Name:      "greet",
+		ShortHelp: "Print a greeting",
+		Flags: cli.FlagsFunc(func(f *flag.FlagSet) {
+			f.String("name", "world", "who to greet")
+		}),
+		FlagsMetadata: []cli.FlagMetadata{{Name: "name", EnvVar: "GREET_NAME"}},
+		SubCommands: []*cli.Command{
+			{
+				Name: "fail",
+				Exec: func(ctx context.Context, s *cli.State) error {
+					return &cli.ExitError{Code: 3, Err: errors.New("failed")}
+				},
+			},
+		},
+		Exec: func(ctx context.Context, s *cli.State) error {
+			fmt.Fprintf(s.Stdout, "hello, %s\n", cli.GetFlag[string](s, "name"))
+			return nil
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		res := Run(t, newRoot(), "--name", "gopher")
+		require.NoError(t, res.Err)
+		require.Equal(t, 0, res.ExitCode)
+		require.Equal(t, "hello, gopher\n", res.Stdout)
+		require.Empty(t, res.Stderr)
+	})
+	t.Run("environment", func(t *testing.T) {
+		t.Parallel()
+		res := Run(t, newRoot())
+		require.Equal(t, "hello, world\n", res.Stdout)
+
+		res = RunOptions(t, newRoot(), &cli.Options{
+			LookupEnv: func(key string) (string, bool) { return "env", key == "GREET_NAME" },
+		})
+		require.Equal(t, "hello, env\n", res.Stdout)
+	})
+	t.Run("exit codes", func(t *testing.T) {
+		t.Parallel()
+		res := Run(t, newRoot(), "fail")
+		require.Equal(t, 3, res.ExitCode)
+		require.Equal(t, "error: failed\n", res.Stderr)
+
+		res = Run(t, newRoot(), "--unknown")
+		require.Equal(t, 2, res.ExitCode)
+		require.Contains(t, res.Stderr, "flag provided but not defined: -unknown")
+	})
+	t.Run("reused tree", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		res := Run(t, root, "--name", "gopher")
+		require.Equal(t, "hello, gopher\n", res.Stdout)
+		res = Run(t, root)
+		require.Equal(t, "hello, world\n", res.Stdout)
+		res = Run(t, root, "fail")
+		require.Equal(t, 3, res.ExitCode)
+		require.Equal(t, "world", root.Flags.Lookup("name").Value.String())
+		require.Nil(t, root.Path())
+	})
+}
+
+func TestGolden(t *testing.T) {
+	t.Parallel()
+
+	Golden(t, filepath.Join("testdata", "help.golden"), Help(t, newRoot()))
+}
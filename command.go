@@ -4,7 +4,9 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mfridman/cli/pkg/suggest"
 )
@@ -24,6 +26,12 @@ type Command struct {
 	// when the command is shown.
 	ShortHelp string
 
+	// LongHelp is an optional full description of the command, e.g., with caveats or links. It is
+	// shown between ShortHelp and the usage pattern in help text, wrapped to the terminal width,
+	// and in generated docs. Paragraphs are separated by blank lines, and paragraphs with indented
+	// lines, such as examples, are shown as is.
+	LongHelp string
+
 	// UsageFunc is an optional function that can be used to generate a custom usage string for the
 	// command. It receives the current command and should return a string with the full usage
 	// pattern.
@@ -36,15 +44,97 @@ type Command struct {
 	// metadata. This is useful for tracking required flags.
 	FlagsMetadata []FlagMetadata
 
+	// FlagGroups is an optional list of flag groups attached to this command. A group's flags are
+	// available to this command and to the descendants selected by the group, without repeating
+	// the definitions in every subcommand. See [FlagGroup] for details.
+	FlagGroups []*FlagGroup
+
 	// SubCommands is a list of nested commands that exist under this command.
 	SubCommands []*Command
 
+	// Group is an optional name of a group of related commands, e.g., "Storage", to keep the help
+	// text of commands with many subcommands navigable. Commands of the same group are listed under
+	// a heading of their own in the help text of their parent, after the commands without a group.
+	// See [UsageOptions.MaxCommands] to summarize groups instead of listing their commands.
+	Group string
+
+	// CompleteArgs is an optional function that returns shell completion candidates for the
+	// command's positional arguments, e.g., live resource names fetched from an API. See
+	// [GenerateCompletion].
+	CompleteArgs CompleteFunc
+
+	// EnvDocs documents the environment variables the command reads beyond those bound to flags
+	// with [FlagMetadata.EnvVar]. They are listed in an "Environment:" section of the help text and
+	// in docs generated with [GenerateDocs].
+	EnvDocs []EnvDoc
+
+	// SeeAlso is an optional list of related commands, given as full command paths, e.g.,
+	// "backup restore" for a "backup create" command. They are listed in a "See Also:" section at the
+	// bottom of the help text and in docs generated with [GenerateDocs].
+	SeeAlso []string
+
+	// DisableHelpFlag disables the help flags (see [Options.HelpFlags]) for this command, so they
+	// are rejected as undefined flags unless the command hierarchy defines them. Application flags
+	// with the same name as a help flag always take precedence, e.g., -h for "host".
+	DisableHelpFlag bool
+
+	// DisableFlagSorting lists the command's flags in help text and generated docs in the order of
+	// their [FlagMetadata] entries, instead of sorted by name, e.g., to show -input before -output.
+	// Flags without metadata follow in lexicographical order, since a [flag.FlagSet] does not record
+	// the order flags are defined in.
+	DisableFlagSorting bool
+	// DisableCommandSorting lists the command's subcommands in help text and generated docs in the
+	// order of SubCommands, instead of sorted by name.
+	DisableCommandSorting bool
+
+	// Stdio is an optional hook that adjusts the standard streams of the [State] before Exec is
+	// called, e.g., to keep Stdout clean for piping by sending all other output to Stderr:
+	//
+	//	Stdio: func(s *cli.State) error {
+	//	    s.Stdout = s.Stderr
+	//	    return nil
+	//	},
+	//
+	// The hooks of all commands in the path are called in order, from the root command to the
+	// terminal command, after the streams from [RunOptions] and any [InputFlag] or [OutputFlag] were
+	// applied. The original streams are restored when Exec returns. A hook that opens a file can
+	// close it with [State.Cleanup].
+	Stdio func(s *State) error
+
+	// Destructive marks a command that performs an irreversible operation, such as deleting data.
+	// Before Exec is called, [Run] requires the user to confirm, either interactively when Stdin is
+	// a terminal, or with the -yes flag, which is added automatically unless the command hierarchy
+	// already defines it. Destructive commands are annotated in help text and generated docs.
+	Destructive bool
+
+	// Confirm is an optional question, e.g., "This will delete all data. Continue?", the user must
+	// answer with yes before Exec is called. Like for destructive commands, the question is asked
+	// when Stdin is a terminal, and can be skipped with the -yes flag or a flag marked with the
+	// [AssumeYes] convention. If Confirm is empty, destructive commands ask a default question.
+	Confirm string
+
+	// Timeout is an optional limit on how long Exec may run. The context passed to Exec is canceled
+	// once it expires, and if Exec then fails, [Run] returns a [*TimeoutError] naming the command.
+	// A -timeout flag defined with [TimeoutFlag] overrides it when set. Zero means no timeout.
+	Timeout time.Duration
+
+	// Retry is an optional policy to call Exec again when it fails with a transient error, e.g., a
+	// flaky network API. Every retry is announced on Stderr, and the wait between retries ends early
+	// when the context is canceled. Output written by failed attempts is not discarded. See
+	// [RetryPolicy].
+	Retry *RetryPolicy
+
 	// Exec defines the command's execution logic. It receives the current application [State] and
 	// returns an error if execution fails. This function is called when [Run] is invoked on the
-	// command.
+	// command. Return [ShowHelp] to show the command's usage text instead.
 	Exec func(ctx context.Context, s *State) error
 
-	state *State
+	// Metadata holds optional application-level information, such as the version and author. It is
+	// only used on the root command and is surfaced by [FormatVersion] and [GenerateDocs].
+	Metadata *AppMetadata
+
+	state     *State
+	flagCache *flagCache
 }
 
 // Path returns the command chain from root to current command. It can only be called after the root
@@ -64,13 +154,209 @@ func (c *Command) terminal() *Command {
 	return c.state.path[len(c.state.path)-1]
 }
 
+// AppMetadata holds application-level information for the root command, so generated artifacts
+// such as version output, man pages and markdown docs are complete without post-processing.
+type AppMetadata struct {
+	// Version is the application version, e.g., "v1.2.3".
+	Version string `json:"version,omitempty"`
+	// Author is the application author, e.g., "Jane Doe <jane@example.com>".
+	Author string `json:"author,omitempty"`
+	// Homepage is the URL of the application's homepage.
+	Homepage string `json:"homepage,omitempty"`
+	// License is the application license, e.g., "MIT".
+	License string `json:"license,omitempty"`
+	// BugReportURL is the URL where users can report bugs.
+	BugReportURL string `json:"bugReportURL,omitempty"`
+}
+
 // FlagMetadata holds additional metadata for a flag, such as whether it is required.
 type FlagMetadata struct {
 	// Name is the flag's name. Must match the flag name in the flag set.
 	Name string
 
-	// Required indicates whether the flag is required.
+	// Required indicates whether the flag is required. A required flag may also be satisfied by its
+	// environment variable or a config source.
+	//
+	// A flag given on the command line satisfies Required even if its value equals the default, so
+	// a required boolean flag can be set to false explicitly with "-force=false". Whether a flag was
+	// given is recorded while parsing, not inferred from its value.
 	Required bool
+
+	// Requires lists flags, by name without leading dashes, that must be set whenever this flag is
+	// set, e.g., "tls-key" for a "tls-cert" flag. Parsing fails if the flag is set by any source
+	// while one of them is not, and help text and generated docs annotate the relationship. A
+	// default value does not count as set.
+	Requires []string
+
+	// Persistent guarantees that a flag of [Command.Flags] is available to every descendant of the
+	// command, including subcommands added after the tree is built, and listed in their help text.
+	// A persistent flag is recognized anywhere after the command's name, e.g., "app db --region eu
+	// migrate" for a persistent flag -region of "app", while other inherited flags that take a
+	// value are only recognized before the next subcommand name or after the terminal command.
+	// Parsing fails if a descendant defines a flag with the same name. Generated docs list the flag
+	// once, on the command that defines it.
+	Persistent bool
+
+	// Secret marks a flag holding a sensitive value, such as an API token. Its value is masked in
+	// diagnostic output, such as flag source reports, error messages and [Trace] hooks, and its
+	// default value is not shown in help text and generated docs. When the user is prompted for it
+	// (see [Options.PromptMissing]), the typed value is not echoed.
+	//
+	// Besides the command line and its environment variable, the value of a secret flag "token" can
+	// be read from a file with the "-token-file" flag, which is added automatically unless the
+	// command hierarchy already defines it. A trailing newline in the file is ignored.
+	Secret bool
+
+	// EnvVar is an optional environment variable the flag's value is read from when the flag is
+	// not set on the command line.
+	EnvVar string
+
+	// DefaultFunc is an optional function that computes the flag's default value when the command
+	// line is parsed, e.g., from the current git branch or the host name, so dynamic defaults need
+	// not be computed before the command tree is built. It is called only if the flag was not set
+	// on the command line, by its environment variable or by a config source. An empty result keeps
+	// the flag's static default, and an error fails parsing.
+	DefaultFunc func() (string, error)
+
+	// DefaultText describes the default value in help text and generated docs instead of the
+	// flag's static default, e.g., "current branch" renders as "(default: current branch)". It is
+	// typically set along with DefaultFunc.
+	DefaultText string
+
+	// Placeholder is an optional name for the flag's value shown in help text, e.g., "FILE" renders
+	// as "-output FILE". If empty, a name quoted with back quotes in the flag's usage string is used,
+	// like [flag.UnquoteUsage] does, e.g., "write results to `FILE`".
+	Placeholder string
+
+	// Conventional marks a boolean flag with a well-known meaning, such as [DryRun], making its
+	// value available through a typed accessor like [State.DryRun]. See [Convention].
+	Conventional Convention
+
+	// Complete is an optional function that returns shell completion candidates for the flag's
+	// value. See [GenerateCompletion].
+	Complete CompleteFunc
+}
+
+// EnvDoc documents an environment variable read by a command, see [Command.EnvDocs].
+type EnvDoc struct {
+	// Name is the environment variable's name, e.g., "TODO_HOME".
+	Name string
+	// Usage is a brief description of how the variable is used.
+	Usage string
+}
+
+// FlagGroup is a set of flags attached to a command and inherited by a selected part of its
+// subtree. For example, database flags can be attached to a "db" command so that every "db ..."
+// subcommand can access them, without leaking them to unrelated commands.
+//
+// Include and Exclude select descendants by their path relative to the command the group is
+// attached to, with names separated by spaces (e.g., "migrate" or "migrate up"). An entry selects
+// the named command and all of its descendants. If Include is empty, all descendants are selected.
+// Exclude takes precedence over Include. The command the group is attached to always receives the
+// group's flags.
+//
+//	db := &cli.Command{
+//	    Name: "db",
+//	    FlagGroups: []*cli.FlagGroup{{
+//	        Flags: cli.FlagsFunc(func(f *flag.FlagSet) {
+//	            f.String("dsn", "", "database connection string")
+//	        }),
+//	        Exclude: []string{"version"},
+//	    }},
+//	    SubCommands: []*cli.Command{migrate, status, version},
+//	}
+type FlagGroup struct {
+	// Flags holds the group's flag definitions.
+	Flags *flag.FlagSet
+	// FlagsMetadata is an optional list of flag information for the group's flags, see
+	// [Command.FlagsMetadata].
+	FlagsMetadata []FlagMetadata
+
+	// Include is an optional list of descendant command paths that receive the group's flags.
+	Include []string
+	// Exclude is an optional list of descendant command paths that do not receive the group's
+	// flags.
+	Exclude []string
+}
+
+// appliesTo reports whether the group applies to the descendant at the given path, relative to the
+// command the group is attached to. An empty path refers to the command itself.
+func (g *FlagGroup) appliesTo(rel []string) bool {
+	if len(rel) == 0 {
+		return true
+	}
+	for _, p := range g.Exclude {
+		if hasPathPrefix(rel, strings.Fields(p)) {
+			return false
+		}
+	}
+	if len(g.Include) == 0 {
+		return true
+	}
+	for _, p := range g.Include {
+		if hasPathPrefix(rel, strings.Fields(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if !strings.EqualFold(path[i], prefix[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// pathFlagSets returns the flag sets contributed by the command at index i of path: the command's
+// own flags followed by the flags of any groups that apply to the terminal command of path.
+func pathFlagSets(path []*Command, i int) []*flag.FlagSet {
+	cmd := path[i]
+	var sets []*flag.FlagSet
+	if cmd.Flags != nil {
+		sets = append(sets, cmd.Flags)
+	}
+	if len(cmd.FlagGroups) == 0 {
+		return sets
+	}
+	rel := relativePath(path, i)
+	for _, g := range cmd.FlagGroups {
+		if g.Flags != nil && g.appliesTo(rel) {
+			sets = append(sets, g.Flags)
+		}
+	}
+	return sets
+}
+
+// relativePath returns the names of the commands that follow index i of path.
+func relativePath(path []*Command, i int) []string {
+	rel := make([]string, 0, len(path)-i-1)
+	for _, c := range path[i+1:] {
+		rel = append(rel, c.Name)
+	}
+	return rel
+}
+
+// pathFlagsMetadata returns the flag metadata contributed by the command at index i of path,
+// including metadata of any groups that apply to the terminal command of path.
+func pathFlagsMetadata(path []*Command, i int) []FlagMetadata {
+	cmd := path[i]
+	metadata := cmd.FlagsMetadata
+	if len(cmd.FlagGroups) == 0 {
+		return metadata
+	}
+	rel := relativePath(path, i)
+	for _, g := range cmd.FlagGroups {
+		if g.appliesTo(rel) {
+			metadata = append(slices.Clip(metadata), g.FlagsMetadata...)
+		}
+	}
+	return metadata
 }
 
 // FlagsFunc is a helper function that creates a new [flag.FlagSet] and applies the given function
@@ -98,18 +384,109 @@ func (c *Command) findSubCommand(name string) *Command {
 	return nil
 }
 
-func (c *Command) formatUnknownCommandError(unknownCmd string) error {
+// matchSubCommand returns the subcommand named name, or, if prefix is true and there is no such
+// subcommand, the only subcommand whose name starts with name. It returns an error if several
+// subcommands start with name, and nil if none matches.
+func (c *Command) matchSubCommand(name string, prefix bool, messages *Messages) (*Command, error) {
+	if sub := c.findSubCommand(name); sub != nil || !prefix {
+		return sub, nil
+	}
+	var matches []*Command
+	for _, sub := range c.SubCommands {
+		if len(sub.Name) > len(name) && strings.EqualFold(sub.Name[:len(name)], name) {
+			matches = append(matches, sub)
+		}
+	}
+	if len(matches) > 1 {
+		names := make([]string, 0, len(matches))
+		for _, sub := range matches {
+			names = append(names, sub.Name)
+		}
+		return nil, fmt.Errorf("%s\n\t%s", messages.ambiguousCommand(name), strings.Join(names, "\n\t"))
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+	return nil, nil
+}
+
+// UnknownCommandError is returned when parsing arguments that name a subcommand that does not exist.
+// Callers can use [errors.As] to render their own message, e.g., with a link to the docs.
+type UnknownCommandError struct {
+	// Name is the unknown command name given on the command line.
+	Name string
+	// Parent is the command whose subcommands were searched.
+	Parent *Command
+	// Suggestions are the names of similar subcommands of Parent, best match first, followed by
+	// the paths of similar commands deeper in the subtree of Parent, relative to Parent, e.g.,
+	// "nested sub". It may be empty.
+	Suggestions []string
+
+	messages *Messages
+}
+
+func (e *UnknownCommandError) Error() string {
+	if len(e.Suggestions) > 0 {
+		return fmt.Sprintf("%s. %s\n\t%s",
+			e.messages.unknownCommand(e.Name),
+			e.messages.didYouMean(len(e.Suggestions)),
+			strings.Join(e.Suggestions, "\n\t"))
+	}
+	return e.messages.unknownCommand(e.Name)
+}
+
+func (c *Command) newUnknownCommandError(unknownCmd string, cfg *suggest.Config, messages *Messages) error {
 	var known []string
 	for _, sub := range c.SubCommands {
 		known = append(known, sub.Name)
 	}
-	suggestions := suggest.FindSimilar(unknownCmd, known, 3)
-	if len(suggestions) > 0 {
-		return fmt.Errorf("unknown command %q. Did you mean one of these?\n\t%s",
-			unknownCmd,
-			strings.Join(suggestions, "\n\t"))
+	return &UnknownCommandError{
+		Name:        unknownCmd,
+		Parent:      c,
+		Suggestions: c.suggestCommands(unknownCmd, known, cfg),
+		messages:    messages,
+	}
+}
+
+// suggestCommands returns the names of the subcommands in known that are similar to name, followed
+// by the paths of similar commands deeper in the subtree of c, relative to c, e.g., "nested sub".
+// Deeper commands are matched by their own name.
+func (c *Command) suggestCommands(name string, known []string, cfg *suggest.Config) []string {
+	suggestions := cfg.Find(name, known)
+	maxResults, scorer := suggest.DefaultMaxResults, suggest.Scorer(suggest.Similarity)
+	var threshold float64
+	if cfg != nil {
+		threshold = cfg.Threshold
+		if cfg.MaxResults != 0 {
+			maxResults = cfg.MaxResults
+		}
+		if cfg.Scorer != nil {
+			scorer = cfg.Scorer
+		}
+	}
+	if len(suggestions) >= maxResults {
+		return suggestions
+	}
+	var nested []string
+	var walk func(cmd *Command, prefix string)
+	walk = func(cmd *Command, prefix string) {
+		for _, sub := range cmd.SubCommands {
+			p := prefix + " " + sub.Name
+			nested = append(nested, p)
+			walk(sub, p)
+		}
+	}
+	for _, sub := range c.SubCommands {
+		walk(sub, sub.Name)
+	}
+	nestedCfg := &suggest.Config{
+		Threshold:  threshold,
+		MaxResults: maxResults - len(suggestions),
+		Scorer: func(target, candidate string) float64 {
+			return scorer(target, candidate[strings.LastIndexByte(candidate, ' ')+1:])
+		},
 	}
-	return fmt.Errorf("unknown command %q", unknownCmd)
+	return append(suggestions, nestedCfg.Find(name, nested)...)
 }
 
 func formatFlagName(name string) string {
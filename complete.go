@@ -0,0 +1,382 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/mfridman/xflag"
+)
+
+// CompleteCommandName is the name of the hidden command invoked by the generated shell completion
+// scripts. When the first argument passed to [Parse] is this name, the remaining arguments are
+// treated as the command line being completed, with the last argument being the word under the
+// cursor (possibly empty). [Run] then writes the completion candidates to Stdout, one per line.
+const CompleteCommandName = "__complete"
+
+// CompleteFunc returns completion candidates for the word under the cursor, toComplete. The State
+// holds the command path, flags and positional arguments parsed from the command line so far.
+// Candidates need not be filtered by toComplete, the shell filters them.
+type CompleteFunc func(ctx context.Context, s *State, toComplete string) []string
+
+// GenerateCompletion writes a shell completion script for the root command to w. Supported shells
+// are "bash", "zsh" and "fish". The script calls back into the binary using the hidden
+// [CompleteCommandName] command, so completions stay in sync with the command tree and dynamic
+// callbacks, such as [Command.CompleteArgs], are honored.
+//
+// For the completion engines of other ecosystems, "fig" writes a Fig autocomplete spec in
+// TypeScript, and "carapace" writes a carapace spec in JSON, which carapace reads as YAML. Both
+// describe the commands and flags of the tree, with the allowed values of [Choice] flags, and file
+// or directory completion for flags with a FILE, PATH or DIR placeholder. The Fig spec also calls
+// back into the binary for dynamic callbacks, while the carapace spec is static.
+//
+// A common approach is to expose the script through a "completion" subcommand:
+//
+//	Exec: func(ctx context.Context, s *cli.State) error {
+//	    return cli.GenerateCompletion(s.Stdout, root, s.Args[0])
+//	}
+func GenerateCompletion(w io.Writer, root *Command, shell string) error {
+	if root == nil {
+		return fmt.Errorf("failed to generate completion: root command is nil")
+	}
+	if err := validateName(root); err != nil {
+		return fmt.Errorf("failed to generate completion: %w", err)
+	}
+	var script string
+	switch shell {
+	case "fig", "carapace":
+		generate := figSpec
+		if shell == "carapace" {
+			generate = carapaceSpec
+		}
+		spec, err := generate(root)
+		if err != nil {
+			return fmt.Errorf("failed to generate completion: %w", err)
+		}
+		_, err = io.WriteString(w, spec)
+		return err
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return fmt.Errorf("failed to generate completion: unsupported shell %q, must be one of: bash, zsh, fish, fig, carapace", shell)
+	}
+	r := strings.NewReplacer(
+		"{{name}}", root.Name,
+		"{{func}}", nonIdentRegex.ReplaceAllString(root.Name, "_"),
+		"{{complete}}", CompleteCommandName,
+	)
+	_, err := io.WriteString(w, r.Replace(script))
+	return err
+}
+
+var nonIdentRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// The completion scripts treat a last candidate line starting with ":files", ":dirs" or ":hosts"
+// as a directive to add the shell's native completions, see [CompleteFiles].
+const bashCompletion = `# bash completion for {{name}}
+_{{func}}_complete() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    local -a candidates patterns
+    local directive pattern
+    candidates=($("${COMP_WORDS[0]}" {{complete}} "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    directive="${candidates[${#candidates[@]}-1]}"
+    case "${directive}" in
+    :files*|:dirs|:hosts) unset 'candidates[${#candidates[@]}-1]' ;;
+    *) directive="" ;;
+    esac
+    COMPREPLY=($(compgen -W "${candidates[*]}" -- "${cur}"))
+    case "${directive}" in
+    :files)
+        COMPREPLY+=($(compgen -f -- "${cur}")) ;;
+    :files\ *)
+        IFS=' ' read -r -a patterns <<< "${directive#:files }"
+        for pattern in "${patterns[@]}"; do
+            COMPREPLY+=($(compgen -f -X "!${pattern}" -- "${cur}"))
+        done
+        COMPREPLY+=($(compgen -d -- "${cur}")) ;;
+    :dirs)
+        COMPREPLY+=($(compgen -d -- "${cur}")) ;;
+    :hosts)
+        COMPREPLY+=($(compgen -A hostname -- "${cur}")) ;;
+    esac
+}
+complete -o default -F _{{func}}_complete {{name}}
+`
+
+const zshCompletion = `#compdef {{name}}
+# zsh completion for {{name}}
+_{{func}}() {
+    local -a candidates
+    local directive pattern
+    candidates=("${(@f)$(${words[1]} {{complete}} "${(@)words[2,$CURRENT]}" 2>/dev/null)}")
+    directive="${candidates[-1]}"
+    case "${directive}" in
+    :files*|:dirs|:hosts) candidates[-1]=() ;;
+    *) directive="" ;;
+    esac
+    compadd -a candidates
+    case "${directive}" in
+    :files) _files ;;
+    :files\ *)
+        for pattern in ${(s: :)${directive#:files }}; do
+            _files -g "${pattern}"
+        done ;;
+    :dirs) _files -/ ;;
+    :hosts) _hosts ;;
+    esac
+}
+compdef _{{func}} {{name}}
+`
+
+const fishCompletion = `# fish completion for {{name}}
+function __{{func}}_complete
+    set -l args (commandline -opc)
+    set -e args[1]
+    set -l token (commandline -ct)
+    set -l candidates ({{name}} {{complete}} $args $token 2>/dev/null)
+    set -l directive ""
+    if test (count $candidates) -gt 0; and string match -qr '^:(files|dirs|hosts)' -- $candidates[-1]
+        set directive $candidates[-1]
+        set -e candidates[-1]
+    end
+    printf '%s\n' $candidates
+    switch $directive
+        case ':files'
+            __fish_complete_path $token
+        case ':files *'
+            set -l patterns (string split ' ' -- (string replace ':files ' '' -- $directive))
+            for path in (__fish_complete_path $token)
+                if string match -q -- '*/' $path
+                    echo $path
+                    continue
+                end
+                set -l name (string replace -r '.*/' '' -- $path)
+                for pattern in $patterns
+                    if string match -q -- $pattern $name
+                        echo $path
+                        break
+                    end
+                end
+            end
+        case ':dirs'
+            __fish_complete_directories $token
+        case ':hosts'
+            __fish_print_hostnames
+    end
+end
+complete -c {{name}} -f -a '(__{{func}}_complete)'
+`
+
+// newCompleteCommand returns the hidden command that completes the given words against the root
+// command tree.
+func newCompleteCommand(root *Command, words []string) *Command {
+	return &Command{
+		Name: CompleteCommandName,
+		Exec: func(ctx context.Context, s *State) error {
+			writeCandidates(s.Stdout, complete(ctx, root, words, s))
+			return nil
+		},
+	}
+}
+
+const (
+	filesDirective = ":files"
+	dirsDirective  = ":dirs"
+	hostsDirective = ":hosts"
+)
+
+// CompleteFiles returns a [CompleteFunc] that lets the shell complete file names natively, limited
+// to names matching one of the given glob patterns, e.g., "*.yaml". Directories are always
+// offered, so the user can navigate to the file. Without patterns, all files are offered.
+//
+//	FlagsMetadata: []cli.FlagMetadata{
+//	    {Name: "config", Complete: cli.CompleteFiles("*.yaml", "*.yml")},
+//	},
+func CompleteFiles(patterns ...string) CompleteFunc {
+	directive := strings.Join(append([]string{filesDirective}, patterns...), " ")
+	return func(context.Context, *State, string) []string {
+		return []string{directive}
+	}
+}
+
+// CompleteDirs returns a [CompleteFunc] that lets the shell complete directory names natively.
+func CompleteDirs() CompleteFunc {
+	return func(context.Context, *State, string) []string {
+		return []string{dirsDirective}
+	}
+}
+
+// CompleteHosts returns a [CompleteFunc] that lets the shell complete host names natively, e.g.,
+// from /etc/hosts.
+func CompleteHosts() CompleteFunc {
+	return func(context.Context, *State, string) []string {
+		return []string{hostsDirective}
+	}
+}
+
+// isDirective reports whether a completion candidate is a directive for native shell completion.
+func isDirective(candidate string) bool {
+	return candidate == filesDirective || strings.HasPrefix(candidate, filesDirective+" ") ||
+		candidate == dirsDirective || candidate == hostsDirective
+}
+
+// writeCandidates writes completion candidates to w, one per line. Directives for native shell
+// completion are written last, and only the first one is kept.
+func writeCandidates(w io.Writer, candidates []string) {
+	var directive string
+	for _, c := range candidates {
+		if isDirective(c) {
+			if directive == "" {
+				directive = c
+			}
+			continue
+		}
+		fmt.Fprintln(w, c)
+	}
+	if directive != "" {
+		fmt.Fprintln(w, directive)
+	}
+}
+
+// complete returns completion candidates for the last of the given words, which is the word under
+// the cursor. The preceding words are traversed the same way [Parse] does, but errors are ignored
+// since the command line is incomplete by definition.
+func complete(ctx context.Context, root *Command, words []string, base *State) []string {
+	var toComplete string
+	if len(words) > 0 {
+		toComplete = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	chain := []*Command{root}
+	current := root
+	var (
+		positional  []string
+		flagArgs    []string
+		pendingFlag *flag.Flag
+	)
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			positional = append(positional, words[i+1:]...)
+			break
+		}
+		if strings.HasPrefix(word, "-") && word != "-" {
+			flagArgs = append(flagArgs, word)
+			if strings.Contains(word, "=") {
+				continue
+			}
+			f := lookupPathFlag(chain, strings.TrimLeft(word, "-"))
+			if f == nil || isBoolFlag(f) {
+				continue
+			}
+			if i+1 == len(words) {
+				pendingFlag = f
+				break
+			}
+			i++
+			flagArgs = append(flagArgs, words[i])
+			continue
+		}
+		if len(positional) == 0 && len(current.SubCommands) > 0 {
+			sub := current.findSubCommand(word)
+			if sub == nil {
+				// Unknown command, nothing sensible to complete
+				return nil
+			}
+			chain = append(chain, sub)
+			current = sub
+			continue
+		}
+		positional = append(positional, word)
+	}
+
+	// Best-effort parse of the flags seen so far, so callbacks can inspect their values. The flags
+	// are parsed into copies of the flag sets, like ParseInvocation does, so completing a command
+	// line neither modifies the variables flags are bound to nor races with other completions.
+	clones := &flagClones{}
+	combined := combineFlags(chain, clones)
+	_ = xflag.ParseToEnd(combined, flagArgs)
+	s := &State{
+		Args:     positional,
+		Stdin:    base.Stdin,
+		Stdout:   base.Stdout,
+		Stderr:   base.Stderr,
+		path:     chain,
+		setFlags: make(map[string]bool),
+		clones:   clones,
+	}
+	combined.Visit(func(f *flag.Flag) {
+		s.setFlags[f.Name] = true
+	})
+
+	if pendingFlag != nil {
+		return completeFlagValue(ctx, s, pendingFlag.Name, toComplete)
+	}
+	if strings.HasPrefix(toComplete, "-") {
+		dashes := "-"
+		if strings.HasPrefix(toComplete, "--") {
+			dashes = "--"
+		}
+		if name, value, ok := strings.Cut(strings.TrimLeft(toComplete, "-"), "="); ok {
+			var candidates []string
+			for _, c := range completeFlagValue(ctx, s, name, value) {
+				if isDirective(c) {
+					// The shell cannot complete a file name within the "-flag=" word.
+					continue
+				}
+				candidates = append(candidates, dashes+name+"="+c)
+			}
+			return candidates
+		}
+		var candidates []string
+		combined.VisitAll(func(f *flag.Flag) {
+			if candidate := dashes + f.Name; strings.HasPrefix(candidate, toComplete) {
+				candidates = append(candidates, candidate)
+			}
+		})
+		return candidates
+	}
+
+	var candidates []string
+	if len(positional) == 0 {
+		for _, sub := range current.SubCommands {
+			if strings.HasPrefix(strings.ToLower(sub.Name), strings.ToLower(toComplete)) {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+	}
+	if current.CompleteArgs != nil {
+		candidates = append(candidates, current.CompleteArgs(ctx, s, toComplete)...)
+	}
+	return candidates
+}
+
+// completeFlagValue returns completion candidates for the value of the named flag, using the
+// Complete callback of the nearest command in the state's path that defines metadata for the flag,
+// or the allowed values of a flag defined with [Choice].
+func completeFlagValue(ctx context.Context, s *State, name, toComplete string) []string {
+	for i := len(s.path) - 1; i >= 0; i-- {
+		for _, m := range pathFlagsMetadata(s.path, i) {
+			if m.Name == name && m.Complete != nil {
+				return m.Complete(ctx, s, toComplete)
+			}
+		}
+	}
+	for i := len(s.path) - 1; i >= 0; i-- {
+		for _, fset := range pathFlagSets(s.path, i) {
+			if f := fset.Lookup(name); f != nil {
+				return flagChoices(f)
+			}
+		}
+	}
+	return nil
+}
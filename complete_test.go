@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplete(t *testing.T) {
+	t.Parallel()
+
+	// newTree returns the following command tree:
+	//
+	//	kube --verbose --namespace
+	//	├── get --output
+	//	│   ├── pods <name>
+	//	│   └── services
+	//	└── logs <pod>
+	newTree := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		pods := &Command{
+			Name: "pods",
+			Exec: exec,
+			CompleteArgs: func(ctx context.Context, s *State, toComplete string) []string {
+				ns := GetFlag[string](s, "namespace")
+				return []string{ns + "-pod-a", ns + "-pod-b"}
+			},
+		}
+		get := &Command{
+			Name: "get",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("output", "", "output format")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{
+					Name: "output",
+					Complete: func(ctx context.Context, s *State, toComplete string) []string {
+						return []string{"json", "yaml"}
+					},
+				},
+			},
+			SubCommands: []*Command{pods, {Name: "services", Exec: exec}},
+			Exec:        exec,
+		}
+		logs := &Command{
+			Name: "logs",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				Choice(f, "format", []string{"text", "json"}, "text", "log format")
+			}),
+			Exec: exec,
+			CompleteArgs: func(ctx context.Context, s *State, toComplete string) []string {
+				if len(s.Args) > 0 {
+					return nil
+				}
+				return []string{"web", "worker"}
+			},
+		}
+		return &Command{
+			Name: "kube",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("verbose", false, "verbose")
+				f.String("namespace", "default", "namespace")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{
+					Name: "namespace",
+					Complete: func(ctx context.Context, s *State, toComplete string) []string {
+						return []string{"default", "kube-system"}
+					},
+				},
+			},
+			SubCommands: []*Command{get, logs},
+			Exec:        exec,
+		}
+	}
+	run := func(t *testing.T, args ...string) []string {
+		t.Helper()
+		root := newTree()
+		err := Parse(root, append([]string{CompleteCommandName}, args...))
+		require.NoError(t, err)
+		var stdout bytes.Buffer
+		err = Run(context.Background(), root, &RunOptions{Stdout: &stdout})
+		require.NoError(t, err)
+		return strings.Fields(stdout.String())
+	}
+
+	t.Run("subcommands", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []string{"get", "logs"}, run(t, ""))
+		require.Equal(t, []string{"get"}, run(t, "g"))
+		require.Equal(t, []string{"pods", "services"}, run(t, "--verbose", "get", ""))
+		require.Equal(t, []string{"pods"}, run(t, "get", "p"))
+	})
+	t.Run("flag names", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []string{"--namespace", "--output", "--verbose"}, run(t, "get", "--"))
+		require.Equal(t, []string{"-namespace"}, run(t, "-n"))
+	})
+	t.Run("flag values", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []string{"json", "yaml"}, run(t, "get", "--output", ""))
+		require.Equal(t, []string{"--output=json", "--output=yaml"}, run(t, "get", "--output="))
+		// Parent flag metadata is used by subcommands
+		require.Equal(t, []string{"default", "kube-system"}, run(t, "get", "pods", "-namespace", "k"))
+		// Choice flags complete their allowed values
+		require.Equal(t, []string{"text", "json"}, run(t, "logs", "--format", ""))
+		// Flags without a completion callback have no candidates
+		require.Empty(t, run(t, "get", "--output", "json", "pods", "--verbose="))
+	})
+	t.Run("dynamic args", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, []string{"default-pod-a", "default-pod-b"}, run(t, "get", "pods", ""))
+		require.Equal(t, []string{"prod-pod-a", "prod-pod-b"}, run(t, "--namespace", "prod", "get", "pods", ""))
+		require.Equal(t, []string{"web", "worker"}, run(t, "logs", ""))
+		require.Empty(t, run(t, "logs", "web", ""))
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, run(t, "unknown", ""))
+	})
+	t.Run("bound variables unchanged", func(t *testing.T) {
+		t.Parallel()
+		var name string
+		var seen []string
+		root := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.StringVar(&name, "name", "default", "name")
+				StringSlice(f, "tag", nil, "tag")
+			}),
+			SubCommands: []*Command{{
+				Name: "sub",
+				Exec: func(ctx context.Context, s *State) error { return nil },
+				CompleteArgs: func(ctx context.Context, s *State, toComplete string) []string {
+					seen = append(seen, GetFlag[string](s, "name")+" "+strings.Join(GetFlag[[]string](s, "tag"), ","))
+					return nil
+				},
+			}},
+		}
+		args := []string{CompleteCommandName, "--name", "polluted", "--tag", "x", "sub", ""}
+		for i := 0; i < 2; i++ {
+			require.NoError(t, RunArgs(context.Background(), root, args, &RunOptions{Stdout: &bytes.Buffer{}}))
+			require.NoError(t, Parse(root, args))
+			require.NoError(t, Run(context.Background(), root, &RunOptions{Stdout: &bytes.Buffer{}}))
+		}
+		require.Equal(t, []string{"polluted x", "polluted x", "polluted x", "polluted x"}, seen)
+		require.Equal(t, "default", name)
+		require.NoError(t, Parse(root, []string{"sub"}))
+		require.Empty(t, GetFlag[[]string](root.state, "tag"))
+	})
+}
+
+func TestCompleteFiles(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("config", "", "config file")
+			f.String("dir", "", "work directory")
+			f.String("host", "", "remote host")
+		}),
+		FlagsMetadata: []FlagMetadata{
+			{Name: "config", Complete: CompleteFiles("*.yaml", "*.yml")},
+			{Name: "dir", Complete: CompleteDirs()},
+			{Name: "host", Complete: CompleteHosts()},
+		},
+		SubCommands: []*Command{
+			{Name: "apply", CompleteArgs: CompleteFiles(), Exec: func(ctx context.Context, s *State) error { return nil }},
+		},
+		CompleteArgs: CompleteDirs(),
+		Exec:         func(ctx context.Context, s *State) error { return nil },
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		var stdout bytes.Buffer
+		err := RunArgs(context.Background(), root, append([]string{CompleteCommandName}, args...), &RunOptions{Stdout: &stdout})
+		require.NoError(t, err)
+		return stdout.String()
+	}
+
+	require.Equal(t, ":files *.yaml *.yml\n", run("--config", ""))
+	require.Equal(t, ":dirs\n", run("--dir", ""))
+	require.Equal(t, ":hosts\n", run("--host", "x"))
+	require.Equal(t, ":files\n", run("apply", ""))
+	// Directives are written after the other candidates.
+	require.Equal(t, "apply\n:dirs\n", run(""))
+	// The shell cannot complete a file name within a "-flag=" word.
+	require.Empty(t, run("--config="))
+}
+
+func TestGenerateCompletion(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{Name: "my-app"}
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		err := GenerateCompletion(&buf, root, shell)
+		require.NoError(t, err)
+		require.Contains(t, buf.String(), "my_app")
+		require.Contains(t, buf.String(), CompleteCommandName)
+	}
+	err := GenerateCompletion(&bytes.Buffer{}, root, "powershell")
+	require.ErrorContains(t, err, `unsupported shell "powershell"`)
+}
+
+func BenchmarkComplete(b *testing.B) {
+	root := newBenchmarkTree()
+	words := []string{"group5", "-root-verbose", "-group5-output", "x", "cmd5", "-cmd5-count", "3", "-cmd5-"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if len(complete(context.Background(), root, words, &State{})) == 0 {
+			b.Fatal("no candidates")
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// figGenerator is the placeholder replaced with a reference to the generator that calls back into
+// the binary in a Fig spec.
+const figGenerator = "__completeGenerator__"
+
+// figSpec returns a Fig autocomplete spec for the command tree, as TypeScript. Flags and choices
+// are listed statically, and arguments of commands with [Command.CompleteArgs] and flags with a
+// [FlagMetadata.Complete] callback are completed by calling back into the binary with the hidden
+// [CompleteCommandName] command.
+func figSpec(root *Command) (string, error) {
+	spec, err := NewSpec(root)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(newFigCommand(root, spec.Command), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Fig autocomplete spec for %s.\n", root.Name)
+	fmt.Fprintf(&b, "const completeGenerator: Fig.Generator = {\n")
+	fmt.Fprintf(&b, "  script: (tokens) => [%q, %q, ...tokens.slice(1)],\n", root.Name, CompleteCommandName)
+	b.WriteString(`  postProcess: (out) =>
+    out
+      .split("\n")
+      .filter((line) => line !== "" && !line.startsWith(":"))
+      .map((name) => ({ name })),
+};
+
+`)
+	b.WriteString("const completionSpec: Fig.Spec = ")
+	b.Write(bytes.ReplaceAll(data, []byte(`"`+figGenerator+`"`), []byte("completeGenerator")))
+	b.WriteString(";\n\nexport default completionSpec;\n")
+	return b.String(), nil
+}
+
+type figCommand struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Subcommands []figCommand `json:"subcommands,omitempty"`
+	Options     []figOption  `json:"options,omitempty"`
+	Args        *figArg      `json:"args,omitempty"`
+}
+
+type figOption struct {
+	Name         string  `json:"name"`
+	Description  string  `json:"description,omitempty"`
+	IsPersistent bool    `json:"isPersistent,omitempty"`
+	IsRequired   bool    `json:"isRequired,omitempty"`
+	Args         *figArg `json:"args,omitempty"`
+}
+
+type figArg struct {
+	Name        string   `json:"name"`
+	IsOptional  bool     `json:"isOptional,omitempty"`
+	IsVariadic  bool     `json:"isVariadic,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+	Template    string   `json:"template,omitempty"`
+	Generators  string   `json:"generators,omitempty"`
+}
+
+// newFigCommand returns the Fig subcommand for cmd, whose spec is sc.
+func newFigCommand(cmd *Command, sc SpecCommand) figCommand {
+	fc := figCommand{Name: sc.Name, Description: sc.ShortHelp}
+	for _, sf := range specCommandFlags(sc) {
+		opt := figOption{
+			Name:         "-" + sf.Name,
+			Description:  sf.Usage,
+			IsPersistent: len(sc.SubCommands) > 0,
+			IsRequired:   sf.Required,
+		}
+		if !sf.Bool {
+			opt.Args = &figArg{Name: valueName(sf), Suggestions: sf.Choices}
+			switch {
+			case hasFlagCompletion(cmd, sf.Name):
+				opt.Args.Generators = figGenerator
+			case len(sf.Choices) == 0:
+				opt.Args.Template = map[string]string{"files": "filepaths", "dirs": "folders"}[valueKind(sf)]
+			}
+		}
+		fc.Options = append(fc.Options, opt)
+	}
+	if cmd.CompleteArgs != nil {
+		fc.Args = &figArg{Name: "args", IsOptional: true, IsVariadic: true, Generators: figGenerator}
+	}
+	for i, sub := range sc.SubCommands {
+		fc.Subcommands = append(fc.Subcommands, newFigCommand(cmd.SubCommands[i], sub))
+	}
+	return fc
+}
+
+// carapaceSpec returns a carapace spec for the command tree, as JSON, which is also valid YAML.
+// Flags of commands with subcommands are persistent, as they are available to all descendants.
+func carapaceSpec(root *Command) (string, error) {
+	spec, err := NewSpec(root)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(newCarapaceCommand(spec.Command), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+type carapaceCommand struct {
+	Name            string            `json:"name"`
+	Description     string            `json:"description,omitempty"`
+	Flags           map[string]string `json:"flags,omitempty"`
+	PersistentFlags map[string]string `json:"persistentflags,omitempty"`
+	Completion      *carapaceComplete `json:"completion,omitempty"`
+	Commands        []carapaceCommand `json:"commands,omitempty"`
+}
+
+type carapaceComplete struct {
+	Flag map[string][]string `json:"flag,omitempty"`
+}
+
+func newCarapaceCommand(sc SpecCommand) carapaceCommand {
+	cc := carapaceCommand{Name: sc.Name, Description: sc.ShortHelp}
+	flags := make(map[string]string)
+	completions := make(map[string][]string)
+	for _, sf := range specCommandFlags(sc) {
+		key := "-" + sf.Name
+		if !sf.Bool {
+			key += "="
+		}
+		if sf.Required {
+			key += "!"
+		}
+		flags[key] = sf.Usage
+		switch {
+		case len(sf.Choices) > 0:
+			completions[sf.Name] = sf.Choices
+		case valueKind(sf) == "files":
+			completions[sf.Name] = []string{"$files"}
+		case valueKind(sf) == "dirs":
+			completions[sf.Name] = []string{"$directories"}
+		}
+	}
+	if len(flags) > 0 {
+		if len(sc.SubCommands) > 0 {
+			cc.PersistentFlags = flags
+		} else {
+			cc.Flags = flags
+		}
+	}
+	if len(completions) > 0 {
+		cc.Completion = &carapaceComplete{Flag: completions}
+	}
+	for _, sub := range sc.SubCommands {
+		cc.Commands = append(cc.Commands, newCarapaceCommand(sub))
+	}
+	return cc
+}
+
+// valueName returns the name of the value of the flag sf, e.g., "FILE".
+func valueName(sf SpecFlag) string {
+	if sf.Placeholder != "" {
+		return sf.Placeholder
+	}
+	return "value"
+}
+
+// valueKind returns "files" or "dirs" if the placeholder of the flag sf names a file or directory,
+// e.g., "FILE", "PATH" or "DIR", and "" otherwise.
+func valueKind(sf SpecFlag) string {
+	switch strings.ToUpper(sf.Placeholder) {
+	case "FILE", "PATH", "FILENAME":
+		return "files"
+	case "DIR", "DIRECTORY", "FOLDER":
+		return "dirs"
+	}
+	return ""
+}
+
+// hasFlagCompletion reports whether cmd defines a [FlagMetadata.Complete] callback for the flag.
+func hasFlagCompletion(cmd *Command, name string) bool {
+	for _, m := range cmd.FlagsMetadata {
+		if m.Name == name && m.Complete != nil {
+			return true
+		}
+	}
+	for _, g := range cmd.FlagGroups {
+		for _, m := range g.FlagsMetadata {
+			if m.Name == name && m.Complete != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
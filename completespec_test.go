@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCompletionSpecRoot() *Command {
+	return &Command{
+		Name:      "app",
+		ShortHelp: "Manage deployments",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("verbose", false, "enable verbose output")
+			f.String("config", "", "read configuration from `FILE`")
+		}),
+		SubCommands: []*Command{
+			{
+				Name:      "deploy",
+				ShortHelp: "Deploy a service",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					Choice(f, "env", []string{"dev", "prod"}, "dev", "target environment")
+					f.String("region", "", "cloud region")
+				}),
+				FlagsMetadata: []FlagMetadata{
+					{Name: "region", Required: true, Complete: func(ctx context.Context, s *State, toComplete string) []string {
+						return []string{"eu", "us"}
+					}},
+				},
+				CompleteArgs: func(ctx context.Context, s *State, toComplete string) []string { return nil },
+				Exec:         func(ctx context.Context, s *State) error { return nil },
+			},
+		},
+	}
+}
+
+func TestCompletionSpecs(t *testing.T) {
+	t.Parallel()
+
+	generate := func(t *testing.T, shell string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		require.NoError(t, GenerateCompletion(&buf, newCompletionSpecRoot(), shell))
+		return buf.String()
+	}
+
+	t.Run("fig", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `// Fig autocomplete spec for app.
+const completeGenerator: Fig.Generator = {
+  script: (tokens) => ["app", "__complete", ...tokens.slice(1)],
+  postProcess: (out) =>
+    out
+      .split("\n")
+      .filter((line) => line !== "" && !line.startsWith(":"))
+      .map((name) => ({ name })),
+};
+
+const completionSpec: Fig.Spec = {
+  "name": "app",
+  "description": "Manage deployments",
+  "subcommands": [
+    {
+      "name": "deploy",
+      "description": "Deploy a service",
+      "options": [
+        {
+          "name": "-env",
+          "description": "target environment",
+          "args": {
+            "name": "value",
+            "suggestions": [
+              "dev",
+              "prod"
+            ]
+          }
+        },
+        {
+          "name": "-region",
+          "description": "cloud region",
+          "isRequired": true,
+          "args": {
+            "name": "value",
+            "generators": completeGenerator
+          }
+        }
+      ],
+      "args": {
+        "name": "args",
+        "isOptional": true,
+        "isVariadic": true,
+        "generators": completeGenerator
+      }
+    }
+  ],
+  "options": [
+    {
+      "name": "-config",
+      "description": "read configuration from FILE",
+      "isPersistent": true,
+      "args": {
+        "name": "FILE",
+        "template": "filepaths"
+      }
+    },
+    {
+      "name": "-verbose",
+      "description": "enable verbose output",
+      "isPersistent": true
+    }
+  ]
+};
+
+export default completionSpec;
+`, generate(t, "fig"))
+	})
+	t.Run("carapace", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `{
+  "name": "app",
+  "description": "Manage deployments",
+  "persistentflags": {
+    "-config=": "read configuration from FILE",
+    "-verbose": "enable verbose output"
+  },
+  "completion": {
+    "flag": {
+      "config": [
+        "$files"
+      ]
+    }
+  },
+  "commands": [
+    {
+      "name": "deploy",
+      "description": "Deploy a service",
+      "flags": {
+        "-env=": "target environment",
+        "-region=!": "cloud region"
+      },
+      "completion": {
+        "flag": {
+          "env": [
+            "dev",
+            "prod"
+          ]
+        }
+      }
+    }
+  ]
+}
+`, generate(t, "carapace"))
+	})
+}
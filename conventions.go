@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Convention identifies a conventional flag, a boolean flag with a well-known meaning across CLIs,
+// such as --dry-run. Marking a flag with [FlagMetadata.Conventional] makes its value available
+// through a typed accessor on [State], and generated docs annotate it consistently.
+type Convention int
+
+const (
+	// ConventionNone marks a regular flag.
+	ConventionNone Convention = iota
+	// DryRun marks a flag that makes the command report what it would do without doing it, see
+	// [State.DryRun].
+	DryRun
+	// AssumeYes marks a flag that answers yes to all prompts, see [State.AssumeYes].
+	AssumeYes
+	// Quiet marks a flag that suppresses non-essential output, see [State.Quiet].
+	Quiet
+	// NoColor marks a flag that disables colored output, see [State.NoColor].
+	NoColor
+	// Verbose marks a flag that enables diagnostic output, see [State.Verbose]. The flag may be a
+	// boolean flag or a flag defined with [CountFlag].
+	Verbose
+)
+
+func (c Convention) String() string {
+	switch c {
+	case ConventionNone:
+		return "none"
+	case DryRun:
+		return "dry-run"
+	case AssumeYes:
+		return "assume-yes"
+	case Quiet:
+		return "quiet"
+	case NoColor:
+		return "no-color"
+	case Verbose:
+		return "verbose"
+	default:
+		return fmt.Sprintf("Convention(%d)", int(c))
+	}
+}
+
+// note returns the annotation of a conventional flag in generated docs.
+func (c Convention) note() string {
+	switch c {
+	case DryRun:
+		return "makes no changes"
+	case AssumeYes:
+		return "answers yes to all prompts"
+	case Quiet:
+		return "suppresses non-essential output"
+	case NoColor:
+		return "disables colored output"
+	case Verbose:
+		return "enables diagnostic output"
+	default:
+		return ""
+	}
+}
+
+// DryRun reports whether the flag marked with the [DryRun] convention is set to true.
+func (s *State) DryRun() bool {
+	return s.conventionalFlag(DryRun)
+}
+
+// AssumeYes reports whether the flag marked with the [AssumeYes] convention is set to true, or a
+// destructive command was confirmed with the -yes flag (see [Command.Destructive]).
+func (s *State) AssumeYes() bool {
+	return s.conventionalFlag(AssumeYes) || s.confirmed
+}
+
+// Quiet reports whether the flag marked with the [Quiet] convention is set to true.
+func (s *State) Quiet() bool {
+	return s.conventionalFlag(Quiet)
+}
+
+// NoColor reports whether the flag marked with the [NoColor] convention is set to true, or the
+// NO_COLOR environment variable is set to a non-empty value.
+func (s *State) NoColor() bool {
+	return s.conventionalFlag(NoColor) || os.Getenv("NO_COLOR") != ""
+}
+
+// Verbose reports whether the flag marked with the [Verbose] convention is set to true, or to a
+// count greater than zero.
+func (s *State) Verbose() bool {
+	return s.conventionalFlag(Verbose)
+}
+
+// Printf writes a message to Stdout, unless the flag marked with the [Quiet] convention is set. A
+// newline is appended if the message does not end with one.
+func (s *State) Printf(format string, args ...any) {
+	if !s.Quiet() {
+		printLine(s.Stdout, format, args...)
+	}
+}
+
+// Verbosef writes a diagnostic message to Stderr if the flag marked with the [Verbose] convention
+// is set, and the flag marked with the [Quiet] convention is not. A newline is appended if the
+// message does not end with one.
+func (s *State) Verbosef(format string, args ...any) {
+	if s.Verbose() && !s.Quiet() {
+		printLine(s.Stderr, format, args...)
+	}
+}
+
+// Errorf writes an error message to Stderr, regardless of the [Quiet] and [Verbose] conventions,
+// e.g., for a failure the command recovers from. A newline is appended if the message does not end
+// with one. Errors that end the command should be returned from Exec instead.
+func (s *State) Errorf(format string, args ...any) {
+	printLine(s.Stderr, format, args...)
+}
+
+func printLine(w io.Writer, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	_, _ = io.WriteString(w, msg)
+}
+
+// conventionalFlag returns the value of the boolean flag marked with convention c in the command
+// hierarchy, or false if there is none. A count flag is true if its count is greater than zero.
+func (s *State) conventionalFlag(c Convention) bool {
+	for i := len(s.path) - 1; i >= 0; i-- {
+		for _, m := range pathFlagsMetadata(s.path, i) {
+			if m.Conventional != c {
+				continue
+			}
+			for _, fset := range s.clones.pathFlagSets(s.path, i) {
+				f := fset.Lookup(m.Name)
+				if f == nil {
+					continue
+				}
+				s.markAccessed(m.Name)
+				if getter, ok := f.Value.(flag.Getter); ok {
+					switch v := getter.Get().(type) {
+					case bool:
+						return v
+					case int:
+						return v > 0
+					}
+				}
+				return false
+			}
+		}
+	}
+	return false
+}
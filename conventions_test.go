@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newConventionsRoot() *Command {
+	return &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("quiet", false, "less output")
+			f.Bool("no-color", false, "plain output")
+		}),
+		FlagsMetadata: []FlagMetadata{
+			{Name: "quiet", Conventional: Quiet},
+			{Name: "no-color", Conventional: NoColor},
+		},
+		SubCommands: []*Command{
+			{
+				Name: "apply",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Bool("dry-run", false, "show changes only")
+					f.Bool("assume-yes", false, "do not prompt")
+				}),
+				FlagsMetadata: []FlagMetadata{
+					{Name: "dry-run", Conventional: DryRun},
+					{Name: "assume-yes", Conventional: AssumeYes},
+				},
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			},
+			{
+				Name:        "purge",
+				Destructive: true,
+				Exec:        func(ctx context.Context, s *State) error { return nil },
+			},
+		},
+	}
+}
+
+func TestConventionalFlags(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	root := newConventionsRoot()
+	require.NoError(t, Parse(root, []string{"apply", "--dry-run", "-quiet"}))
+	s := root.state
+	assert.True(t, s.DryRun())
+	assert.True(t, s.Quiet())
+	assert.False(t, s.AssumeYes())
+	assert.False(t, s.NoColor())
+	// Reading a conventional flag counts as using it.
+	assert.Empty(t, s.unusedParentFlags())
+
+	root = newConventionsRoot()
+	require.NoError(t, Parse(root, []string{"apply", "--assume-yes", "--no-color"}))
+	s = root.state
+	assert.False(t, s.DryRun())
+	assert.True(t, s.AssumeYes())
+	assert.True(t, s.NoColor())
+
+	// The -yes flag of destructive commands also counts as assuming yes.
+	require.NoError(t, Parse(root, []string{"purge", "-yes"}))
+	assert.True(t, root.state.AssumeYes())
+	assert.False(t, root.state.DryRun())
+
+	t.Setenv("NO_COLOR", "1")
+	assert.True(t, root.state.NoColor())
+}
+
+func TestConventionalFlagsDocs(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	require.NoError(t, GenerateDocs(&b, newConventionsRoot(), "markdown"))
+	assert.Contains(t, b.String(), "show changes only (makes no changes)")
+	assert.Contains(t, b.String(), "do not prompt (answers yes to all prompts)")
+	assert.Equal(t, "dry-run", DryRun.String())
+	assert.Equal(t, "Convention(42)", Convention(42).String())
+}
+
+func TestOutputLevels(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("q", false, "less output")
+				CountFlag(f, "v", "more output, may be repeated")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "q", Conventional: Quiet},
+				{Name: "v", Conventional: Verbose},
+			},
+			Exec: func(ctx context.Context, s *State) error {
+				s.Printf("created %d items", 2)
+				s.Verbosef("took %s\n", "1s")
+				s.Errorf("skipped %q", "c")
+				return nil
+			},
+		}
+	}
+	run := func(t *testing.T, args ...string) (string, string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		err := RunArgs(context.Background(), newRoot(), args, &RunOptions{Stdout: &stdout, Stderr: &stderr})
+		require.NoError(t, err)
+		return stdout.String(), stderr.String()
+	}
+
+	stdout, stderr := run(t)
+	assert.Equal(t, "created 2 items\n", stdout)
+	assert.Equal(t, "skipped \"c\"\n", stderr)
+
+	stdout, stderr = run(t, "-v", "-v")
+	assert.Equal(t, "created 2 items\n", stdout)
+	assert.Equal(t, "took 1s\nskipped \"c\"\n", stderr)
+
+	stdout, stderr = run(t, "-q", "-v")
+	assert.Empty(t, stdout)
+	assert.Equal(t, "skipped \"c\"\n", stderr)
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DebugFlagName is the hidden argument that enables [RunOptions.Debug] for a single invocation,
+// see [Options.DebugFlag].
+const DebugFlagName = "--debug"
+
+// debugLog writes the diagnostics enabled by [RunOptions.Debug], each line prefixed with "debug:"
+// and the time elapsed since the invocation started. A nil *debugLog discards them.
+type debugLog struct {
+	w     io.Writer
+	start time.Time
+}
+
+func newDebugLog(w io.Writer) *debugLog {
+	return &debugLog{w: w, start: time.Now()}
+}
+
+func (l *debugLog) printf(format string, args ...any) {
+	if l == nil {
+		return
+	}
+	elapsed := time.Since(l.start).Round(time.Microsecond)
+	fmt.Fprintf(l.w, "debug: [+%s] %s\n", elapsed, fmt.Sprintf(format, args...))
+}
+
+// parsed reports the outcome of parsing: the resolved command, the flags not left at their
+// default values and where their values came from, and the positional arguments.
+func (l *debugLog) parsed(s *State, d time.Duration) {
+	if l == nil {
+		return
+	}
+	l.printf("resolved command %q in %s", getCommandPath(s.path), d.Round(time.Microsecond))
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	secrets := pathSecretFlags(s.path)
+	for _, name := range names {
+		f, ok := s.Flag(name)
+		if !ok {
+			continue
+		}
+		value := f.Value.String()
+		if secrets[name] {
+			value = maskedValue
+		}
+		l.printf("flag %s=%s (from %s)", formatFlagName(name), value, s.sources[name])
+	}
+	if len(s.Args) > 0 {
+		l.printf("positional arguments: %s", strings.Join(s.Args, " "))
+	}
+}
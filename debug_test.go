@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugFlag(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("region", "us-east-1", "cloud region")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "region", EnvVar: "APP_REGION"},
+			},
+			SubCommands: []*Command{
+				{
+					Name: "deploy",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("token", "", "api token")
+						f.Int("replicas", 1, "number of replicas")
+					}),
+					FlagsMetadata: []FlagMetadata{
+						{Name: "token", Secret: true},
+					},
+					Timeout: time.Minute,
+					Stdio:   func(s *State) error { return nil },
+					Exec: func(ctx context.Context, s *State) error {
+						s.Cleanup(func() {})
+						fmt.Fprint(s.Stdout, GetFlag[int](s, "replicas"))
+						return nil
+					},
+				},
+			},
+		}
+	}
+	run := func(root *Command, options *Options) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		options.Stdout, options.Stderr = &stdout, &stderr
+		err := ParseAndRun(context.Background(), root, options)
+		return stdout.String(), stderr.String(), err
+	}
+	// lines strips the elapsed time from the debug output.
+	lines := func(stderr string) []string {
+		var lines []string
+		for _, line := range strings.Split(strings.TrimSpace(stderr), "\n") {
+			_, msg, ok := strings.Cut(line, "] ")
+			require.True(t, ok, line)
+			lines = append(lines, msg)
+		}
+		return lines
+	}
+
+	t.Run("debug output", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(newRoot(), &Options{
+			Args:      []string{"deploy", DebugFlagName, "--token=abc", "--replicas=3", "web"},
+			DebugFlag: true,
+			LookupEnv: func(key string) (string, bool) {
+				return "eu-west-1", key == "APP_REGION"
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "3", stdout)
+		got := lines(stderr)
+		require.True(t, strings.HasPrefix(got[1], `resolved command "app deploy" in `), got[1])
+		got[1] = `resolved command "app deploy"`
+		require.Len(t, got, 11)
+		require.Equal(t, []string{
+			"arguments: deploy --token=**** --replicas=3 web",
+			`resolved command "app deploy"`,
+			"flag -region=eu-west-1 (from env)",
+			"flag -replicas=3 (from command line)",
+			"flag -token=**** (from command line)",
+			"positional arguments: web",
+			`calling the Stdio hook of "app deploy"`,
+			`running "app deploy"`,
+			`running "app deploy" with a timeout of 1m0s`,
+		}, got[:9])
+		require.True(t, strings.HasPrefix(got[9], `exec of "app deploy" returned after `), got[9])
+		require.True(t, strings.HasSuffix(got[9], ": <nil>"), got[9])
+		require.Equal(t, "running 1 cleanup function(s)", got[10])
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := run(newRoot(), &Options{Args: []string{"deploy", DebugFlagName}})
+		require.ErrorContains(t, err, "flag provided but not defined: -debug")
+
+		stdout, stderr, err := run(newRoot(), &Options{Args: []string{"deploy"}, DebugFlag: true})
+		require.NoError(t, err)
+		require.Equal(t, "1", stdout)
+		require.Empty(t, stderr)
+	})
+	t.Run("after delimiter", func(t *testing.T) {
+		t.Parallel()
+		root := &Command{
+			Name: "echo",
+			Exec: func(ctx context.Context, s *State) error {
+				fmt.Fprint(s.Stdout, s.Args)
+				return nil
+			},
+		}
+		stdout, stderr, err := run(root, &Options{Args: []string{"--", DebugFlagName}, DebugFlag: true})
+		require.NoError(t, err)
+		require.Equal(t, "[--debug]", stdout)
+		require.Empty(t, stderr)
+	})
+	t.Run("defined by the application", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		root.Flags.Bool("debug", false, "enable debug logging")
+		_, stderr, err := run(root, &Options{Args: []string{DebugFlagName, "deploy"}, DebugFlag: true})
+		require.NoError(t, err)
+		require.Empty(t, stderr)
+		require.True(t, GetFlag[bool](root.state, "debug"))
+	})
+	t.Run("run options", func(t *testing.T) {
+		t.Parallel()
+		options := &Options{Args: []string{"deploy"}}
+		options.Debug = true
+		_, stderr, err := run(newRoot(), options)
+		require.NoError(t, err)
+		require.Contains(t, stderr, `debug: [+`)
+		require.Contains(t, stderr, `] running "app deploy"`+"\n")
+		require.Nil(t, options.debugLog)
+	})
+}
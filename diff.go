@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeKind is the kind of a [TreeChange].
+type ChangeKind int
+
+const (
+	// ChangeAdded is a command or flag that only exists in the new tree.
+	ChangeAdded ChangeKind = iota + 1
+	// ChangeRemoved is a command or flag that only exists in the old tree.
+	ChangeRemoved
+	// ChangeRenamed is a command or flag that was removed and added under a different name with the
+	// same description.
+	ChangeRenamed
+	// ChangeModified is a flag whose definition changed, e.g., it became required.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeRenamed:
+		return "renamed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// TreeChange is a single difference between two command trees, see [DiffTrees].
+type TreeChange struct {
+	// Kind is the kind of change.
+	Kind ChangeKind
+	// Command is the path of the command that changed, or that owns the flag that changed, e.g.,
+	// "todo task add". For renamed commands, it is the old path.
+	Command string
+	// Flag is the name of the flag that changed, without dashes. It is empty for command changes.
+	// For renamed flags, it is the old name.
+	Flag string
+	// NewName is the new command or flag name of a renamed command or flag.
+	NewName string
+	// Detail describes a modification, e.g., "now required".
+	Detail string
+	// Breaking reports whether the change may break existing invocations.
+	Breaking bool
+}
+
+func (c TreeChange) String() string {
+	var b strings.Builder
+	if c.Breaking {
+		b.WriteString("BREAKING: ")
+	}
+	if c.Flag != "" {
+		fmt.Fprintf(&b, "flag %s of command %q %s", formatFlagName(c.Flag), c.Command, c.Kind)
+	} else {
+		fmt.Fprintf(&b, "command %q %s", c.Command, c.Kind)
+	}
+	if c.NewName != "" {
+		fmt.Fprintf(&b, " to %q", c.NewName)
+	}
+	if c.Detail != "" {
+		b.WriteString(": " + c.Detail)
+	}
+	return b.String()
+}
+
+// TreeDiff is the structured report returned by [DiffTrees].
+type TreeDiff struct {
+	// Changes lists the changes in depth-first order of the command trees.
+	Changes []TreeChange
+}
+
+// Breaking reports whether any change may break existing invocations.
+func (d *TreeDiff) Breaking() bool {
+	return slices.ContainsFunc(d.Changes, func(c TreeChange) bool { return c.Breaking })
+}
+
+// String returns the changes, one per line, suitable for release notes.
+func (d *TreeDiff) String() string {
+	lines := make([]string, 0, len(d.Changes))
+	for _, c := range d.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffTrees compares two versions of a command tree and reports the added, removed, renamed and
+// modified commands and flags, so release notes and breaking-change checks can be automated.
+// Flags of flag groups are compared as flags of the command the group is attached to.
+//
+// A removed and an added sibling with the same non-empty description are reported as a rename.
+// Removals, renames, flags that became required, new required flags and flags that changed between
+// boolean and value-taking are reported as breaking.
+func DiffTrees(old, new *Command) (*TreeDiff, error) {
+	oldSpec, err := NewSpec(old)
+	if err != nil {
+		return nil, fmt.Errorf("old tree: %w", err)
+	}
+	newSpec, err := NewSpec(new)
+	if err != nil {
+		return nil, fmt.Errorf("new tree: %w", err)
+	}
+	d := &TreeDiff{}
+	d.diffCommand(oldSpec.Command, newSpec.Command, oldSpec.Command.Name)
+	return d, nil
+}
+
+func (d *TreeDiff) diffCommand(old, new SpecCommand, path string) {
+	d.diffFlags(specCommandFlags(old), specCommandFlags(new), path)
+
+	matched := make(map[string]bool)
+	var removed []SpecCommand
+	for _, o := range old.SubCommands {
+		if n, ok := findSpecCommand(new.SubCommands, o.Name); ok {
+			matched[o.Name] = true
+			d.diffCommand(o, n, path+" "+o.Name)
+			continue
+		}
+		removed = append(removed, o)
+	}
+	var added []SpecCommand
+	for _, n := range new.SubCommands {
+		if !matched[n.Name] {
+			added = append(added, n)
+		}
+	}
+	for _, o := range removed {
+		i := slices.IndexFunc(added, func(n SpecCommand) bool {
+			return o.ShortHelp != "" && n.ShortHelp == o.ShortHelp
+		})
+		if i < 0 {
+			d.Changes = append(d.Changes, TreeChange{Kind: ChangeRemoved, Command: path + " " + o.Name, Breaking: true})
+			continue
+		}
+		n := added[i]
+		added = slices.Delete(added, i, i+1)
+		d.Changes = append(d.Changes, TreeChange{Kind: ChangeRenamed, Command: path + " " + o.Name, NewName: n.Name, Breaking: true})
+		d.diffCommand(o, n, path+" "+o.Name)
+	}
+	for _, n := range added {
+		d.Changes = append(d.Changes, TreeChange{Kind: ChangeAdded, Command: path + " " + n.Name})
+	}
+}
+
+func (d *TreeDiff) diffFlags(old, new []SpecFlag, path string) {
+	matched := make(map[string]bool)
+	var removed []SpecFlag
+	for _, o := range old {
+		i := slices.IndexFunc(new, func(n SpecFlag) bool { return n.Name == o.Name })
+		if i < 0 {
+			removed = append(removed, o)
+			continue
+		}
+		matched[o.Name] = true
+		n := new[i]
+		if !o.Required && n.Required {
+			d.Changes = append(d.Changes, TreeChange{Kind: ChangeModified, Command: path, Flag: o.Name, Detail: "now required", Breaking: true})
+		}
+		if o.Bool != n.Bool {
+			detail := "now takes a value"
+			if n.Bool {
+				detail = "no longer takes a value"
+			}
+			d.Changes = append(d.Changes, TreeChange{Kind: ChangeModified, Command: path, Flag: o.Name, Detail: detail, Breaking: true})
+		}
+		if o.Default != n.Default {
+			d.Changes = append(d.Changes, TreeChange{Kind: ChangeModified, Command: path, Flag: o.Name,
+				Detail: fmt.Sprintf("default changed from %q to %q", o.Default, n.Default)})
+		}
+	}
+	var added []SpecFlag
+	for _, n := range new {
+		if !matched[n.Name] {
+			added = append(added, n)
+		}
+	}
+	for _, o := range removed {
+		i := slices.IndexFunc(added, func(n SpecFlag) bool {
+			return o.Usage != "" && n.Usage == o.Usage
+		})
+		if i < 0 {
+			d.Changes = append(d.Changes, TreeChange{Kind: ChangeRemoved, Command: path, Flag: o.Name, Breaking: true})
+			continue
+		}
+		n := added[i]
+		added = slices.Delete(added, i, i+1)
+		d.Changes = append(d.Changes, TreeChange{Kind: ChangeRenamed, Command: path, Flag: o.Name, NewName: n.Name, Breaking: true})
+	}
+	for _, n := range added {
+		d.Changes = append(d.Changes, TreeChange{Kind: ChangeAdded, Command: path, Flag: n.Name, Breaking: n.Required})
+	}
+}
+
+// specCommandFlags returns the command's own flags and the flags of its flag groups.
+func specCommandFlags(sc SpecCommand) []SpecFlag {
+	flags := slices.Clone(sc.Flags)
+	for _, g := range sc.FlagGroups {
+		flags = append(flags, g.Flags...)
+	}
+	return flags
+}
+
+func findSpecCommand(cmds []SpecCommand, name string) (SpecCommand, bool) {
+	i := slices.IndexFunc(cmds, func(c SpecCommand) bool { return c.Name == name })
+	if i < 0 {
+		return SpecCommand{}, false
+	}
+	return cmds[i], true
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTrees(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+
+	t.Run("no changes", func(t *testing.T) {
+		t.Parallel()
+		newRoot := func() *Command {
+			return &Command{
+				Name:        "app",
+				SubCommands: []*Command{{Name: "run", Exec: exec}},
+				Exec:        exec,
+			}
+		}
+		d, err := DiffTrees(newRoot(), newRoot())
+		require.NoError(t, err)
+		require.Empty(t, d.Changes)
+		require.False(t, d.Breaking())
+	})
+	t.Run("changes", func(t *testing.T) {
+		t.Parallel()
+		old := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("verbose", false, "enable verbose output")
+				f.String("region", "us-east-1", "cloud region")
+				f.String("token", "", "API token")
+			}),
+			SubCommands: []*Command{
+				{Name: "run", ShortHelp: "Run the app", Exec: exec},
+				{Name: "rm", ShortHelp: "Remove an item", Exec: exec},
+				{Name: "legacy", Exec: exec},
+			},
+			Exec: exec,
+		}
+		new := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("debug", false, "enable verbose output")
+				f.String("region", "eu-west-1", "cloud region")
+				f.String("token", "", "API token")
+				f.String("project", "", "project ID")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "token", Required: true},
+			},
+			SubCommands: []*Command{
+				{Name: "run", ShortHelp: "Run the app", Exec: exec},
+				{Name: "remove", ShortHelp: "Remove an item", Exec: exec},
+				{Name: "status", Exec: exec},
+			},
+			Exec: exec,
+		}
+		d, err := DiffTrees(old, new)
+		require.NoError(t, err)
+		require.Equal(t, []TreeChange{
+			{Kind: ChangeModified, Command: "app", Flag: "region", Detail: `default changed from "us-east-1" to "eu-west-1"`},
+			{Kind: ChangeModified, Command: "app", Flag: "token", Detail: "now required", Breaking: true},
+			{Kind: ChangeRenamed, Command: "app", Flag: "verbose", NewName: "debug", Breaking: true},
+			{Kind: ChangeAdded, Command: "app", Flag: "project"},
+			{Kind: ChangeRenamed, Command: "app rm", NewName: "remove", Breaking: true},
+			{Kind: ChangeRemoved, Command: "app legacy", Breaking: true},
+			{Kind: ChangeAdded, Command: "app status"},
+		}, d.Changes)
+		require.True(t, d.Breaking())
+		require.Equal(t, `flag -region of command "app" modified: default changed from "us-east-1" to "eu-west-1"
+BREAKING: flag -token of command "app" modified: now required
+BREAKING: flag -verbose of command "app" renamed to "debug"
+flag -project of command "app" added
+BREAKING: command "app rm" renamed to "remove"
+BREAKING: command "app legacy" removed
+command "app status" added`, d.String())
+	})
+	t.Run("invalid tree", func(t *testing.T) {
+		t.Parallel()
+		_, err := DiffTrees(nil, &Command{Name: "app"})
+		require.ErrorContains(t, err, "old tree: root command is nil")
+	})
+}
@@ -0,0 +1,454 @@
+package cli
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FormatVersion returns version information for the root command, built from its [AppMetadata].
+// The first line holds the command name and version, followed by any of the author, homepage,
+// license and bug report URL that are set. It is intended for use in a --version handler:
+//
+//	if cli.GetFlag[bool](s, "version") {
+//	    fmt.Fprintln(s.Stdout, cli.FormatVersion(root))
+//	    return nil
+//	}
+func FormatVersion(root *Command) string {
+	if root == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(root.Name)
+	md := root.Metadata
+	if md == nil {
+		return b.String()
+	}
+	if md.Version != "" {
+		b.WriteString(" " + md.Version)
+	}
+	for _, field := range []struct{ label, value string }{
+		{"Author", md.Author},
+		{"Homepage", md.Homepage},
+		{"License", md.License},
+		{"Report bugs", md.BugReportURL},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "\n%s: %s", field.label, field.value)
+		}
+	}
+	return b.String()
+}
+
+// GenerateDocs writes documentation for the entire command tree to w. Supported formats are
+// "markdown" and "man". The root command's [AppMetadata] is included as front matter in markdown
+// and in the header and trailing sections of the man page.
+//
+// Unlike [DefaultUsage], the command tree does not need to be parsed first.
+func GenerateDocs(w io.Writer, root *Command, format string) error {
+	if root == nil {
+		return fmt.Errorf("failed to generate docs: root command is nil")
+	}
+	if err := validateCommands(root, nil); err != nil {
+		return fmt.Errorf("failed to generate docs: %w", err)
+	}
+	var doc string
+	switch format {
+	case "markdown":
+		doc = markdownDocs(root)
+	case "man":
+		doc = manDocs(root)
+	default:
+		return fmt.Errorf("failed to generate docs: unsupported format %q, must be one of: markdown, man", format)
+	}
+	_, err := io.WriteString(w, doc)
+	return err
+}
+
+// DocsCommandName is the name of the hidden subcommand that generates documentation for the
+// command tree, see [Options.DocsCommand].
+const DocsCommandName = "docs"
+
+// newDocsCommand returns a root command named like root, with the hidden [DocsCommandName]
+// subcommand that writes the documentation of root with [GenerateDocs].
+func newDocsCommand(root *Command) *Command {
+	return &Command{
+		Name: root.Name,
+		SubCommands: []*Command{{
+			Name:      DocsCommandName,
+			ShortHelp: "Generate documentation",
+			LongHelp: "Writes markdown or man page documentation for all commands to stdout, or to a file " +
+				"named after the application in the given directory, e.g., \"" + root.Name + ".1\".\n\n" +
+				"For packaging, -package writes the shell completion scripts and the man page into a " +
+				"directory instead, and -packager prints the snippet a package manifest needs to install them.",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				Choice(f, "format", []string{"markdown", "man"}, "markdown", "documentation format")
+				f.String("dir", "", "write documentation to a file in `DIR` instead of stdout")
+				f.String("package", "", "write completion scripts and the man page for packaging to `DIR`")
+				Choice(f, "packager", []string{"homebrew", "scoop"}, "",
+					"print the install snippet for a package manager")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "dir", Complete: CompleteDirs()},
+				{Name: "package", Complete: CompleteDirs()},
+			},
+			Exec: func(ctx context.Context, s *State) error {
+				format := GetFlag[string](s, "format")
+				dir := GetFlag[string](s, "dir")
+				packageDir := GetFlag[string](s, "package")
+				packager := GetFlag[string](s, "packager")
+				if len(s.Args) > 0 {
+					return fmt.Errorf("unexpected arguments: %s", strings.Join(s.Args, " "))
+				}
+				if packageDir != "" || packager != "" {
+					if dir != "" {
+						return errors.New("-dir cannot be combined with -package or -packager")
+					}
+					if packageDir != "" {
+						if _, err := WritePackageFiles(packageDir, root); err != nil {
+							return err
+						}
+					}
+					if packager != "" {
+						return GeneratePackagingHints(s.Stdout, root, packager)
+					}
+					return nil
+				}
+				if dir == "" {
+					return GenerateDocs(s.Stdout, root, format)
+				}
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					return fmt.Errorf("failed to create docs directory: %w", err)
+				}
+				ext := map[string]string{"markdown": ".md", "man": ".1"}[format]
+				var b bytes.Buffer
+				if err := GenerateDocs(&b, root, format); err != nil {
+					return err
+				}
+				return os.WriteFile(filepath.Join(dir, root.Name+ext), b.Bytes(), 0o644)
+			},
+		}},
+	}
+}
+
+// destructiveNote annotates destructive commands in generated docs.
+const destructiveNote = "This command is destructive and asks for confirmation, unless -yes is set."
+
+// docCommand is a command in the tree, with its path from the root.
+type docCommand struct {
+	cmd  *Command
+	path []*Command
+}
+
+// walkDocCommands returns all commands in the tree in depth-first order, with subcommands sorted
+// by name unless [Command.DisableCommandSorting] is set.
+func walkDocCommands(root *Command) []docCommand {
+	var cmds []docCommand
+	var walk func(path []*Command)
+	walk = func(path []*Command) {
+		cmd := path[len(path)-1]
+		cmds = append(cmds, docCommand{cmd: cmd, path: path})
+		for _, sub := range cmd.sortedSubCommands() {
+			walk(append(slices.Clip(path), sub))
+		}
+	}
+	walk([]*Command{root})
+	return cmds
+}
+
+// docFlag is a flag defined by a command, including flags of groups attached to it.
+type docFlag struct {
+	key         string
+	name        string
+	description string
+}
+
+// docFlags returns the flags defined by cmd, sorted by name unless [Command.DisableFlagSorting] is
+// set.
+func docFlags(cmd *Command) []docFlag {
+	metadata := make(map[string]FlagMetadata)
+	sets := []*flag.FlagSet{cmd.Flags}
+	allMetadata := slices.Clone(cmd.FlagsMetadata)
+	for _, g := range cmd.FlagGroups {
+		sets = append(sets, g.Flags)
+		allMetadata = append(allMetadata, g.FlagsMetadata...)
+	}
+	for _, m := range allMetadata {
+		metadata[m.Name] = m
+	}
+	var flags []docFlag
+	var secrets []string
+	for _, fset := range sets {
+		if fset == nil {
+			continue
+		}
+		fset.VisitAll(func(f *flag.Flag) {
+			m := metadata[f.Name]
+			placeholder, description := flagPlaceholder(f, m)
+			if choices := flagChoices(f); len(choices) > 0 {
+				description += " " + (*Messages)(nil).flagChoices(choices)
+			}
+			if note := m.Conventional.note(); note != "" {
+				description += fmt.Sprintf(" (%s)", note)
+			}
+			name := f.Name
+			if placeholder != "" {
+				name += " " + placeholder
+			}
+			defValue := f.DefValue
+			if m.DefaultText != "" {
+				defValue = m.DefaultText
+			}
+			if defValue != "" && !m.Secret {
+				description += fmt.Sprintf(" (default: %s)", defValue)
+			}
+			if m.EnvVar != "" {
+				description += fmt.Sprintf(" (env: %s)", m.EnvVar)
+			}
+			if m.Required {
+				description += " (required)"
+			}
+			if len(m.Requires) > 0 {
+				requires := make([]string, len(m.Requires))
+				for i, name := range m.Requires {
+					requires[i] = formatFlagName(name)
+				}
+				description += " " + (*Messages)(nil).flagRequires(requires)
+			}
+			if m.Persistent && len(cmd.SubCommands) > 0 {
+				description += " (applies to all subcommands)"
+			}
+			flags = append(flags, docFlag{key: f.Name, name: name, description: description})
+			if m.Secret {
+				secrets = append(secrets, f.Name)
+			}
+		})
+	}
+	for _, name := range secrets {
+		fileFlag := secretFileFlag(name)
+		if slices.ContainsFunc(sets, func(fset *flag.FlagSet) bool { return fset != nil && fset.Lookup(fileFlag.Name) != nil }) {
+			continue
+		}
+		placeholder, description := flagPlaceholder(fileFlag, FlagMetadata{})
+		flags = append(flags, docFlag{key: name, name: fileFlag.Name + " " + placeholder, description: description})
+	}
+	if cmd.needsConfirmation() && !slices.ContainsFunc(flags, func(f docFlag) bool { return f.name == confirmFlagName }) {
+		flags = append(flags, docFlag{key: confirmFlagName, name: confirmFlagName, description: cmd.confirmFlagUsage() + " (default: false)"})
+	}
+	if cmd.DisableFlagSorting {
+		order := metadataOrder(allMetadata)
+		slices.SortStableFunc(flags, func(a, b docFlag) int {
+			return order(a.key, b.key)
+		})
+		return flags
+	}
+	slices.SortFunc(flags, func(a, b docFlag) int {
+		return cmp.Compare(a.name, b.name)
+	})
+	return flags
+}
+
+func markdownDocs(root *Command) string {
+	var b strings.Builder
+	if md := root.Metadata; md != nil {
+		b.WriteString("---\n")
+		fmt.Fprintf(&b, "title: %s\n", yamlQuote(root.Name))
+		for _, field := range []struct{ key, value string }{
+			{"version", md.Version},
+			{"author", md.Author},
+			{"homepage", md.Homepage},
+			{"license", md.License},
+			{"bugs", md.BugReportURL},
+		} {
+			if field.value != "" {
+				fmt.Fprintf(&b, "%s: %s\n", field.key, yamlQuote(field.value))
+			}
+		}
+		b.WriteString("---\n\n")
+	}
+	for i, dc := range walkDocCommands(root) {
+		cmdPath := getCommandPath(dc.path)
+		if i == 0 {
+			fmt.Fprintf(&b, "# %s\n\n", cmdPath)
+		} else {
+			fmt.Fprintf(&b, "## %s\n\n", cmdPath)
+		}
+		if dc.cmd.ShortHelp != "" {
+			b.WriteString(dc.cmd.ShortHelp + "\n\n")
+		}
+		if longHelp := strings.TrimSpace(dc.cmd.LongHelp); longHelp != "" {
+			b.WriteString(longHelp + "\n\n")
+		}
+		if dc.cmd.Destructive {
+			b.WriteString(destructiveNote + "\n\n")
+		}
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", usageLine(dc.cmd, cmdPath))
+		if flags := docFlags(dc.cmd); len(flags) > 0 {
+			b.WriteString("Flags:\n\n")
+			for _, f := range flags {
+				fmt.Fprintf(&b, "- `-%s`: %s\n", f.name, f.description)
+			}
+			b.WriteString("\n")
+		}
+		if len(dc.cmd.EnvDocs) > 0 {
+			b.WriteString("Environment:\n\n")
+			for _, env := range dc.cmd.EnvDocs {
+				fmt.Fprintf(&b, "- `%s`: %s\n", env.Name, env.Usage)
+			}
+			b.WriteString("\n")
+		}
+		if len(dc.cmd.SubCommands) > 0 {
+			b.WriteString("Commands:\n\n")
+			for _, sub := range walkDocCommands(dc.cmd)[1:] {
+				if len(sub.path) != 2 {
+					continue
+				}
+				fmt.Fprintf(&b, "- `%s %s`", cmdPath, sub.cmd.Name)
+				if sub.cmd.ShortHelp != "" {
+					b.WriteString(": " + sub.cmd.ShortHelp)
+				}
+				b.WriteString("\n")
+			}
+			b.WriteString("\n")
+		}
+		if len(dc.cmd.SeeAlso) > 0 {
+			b.WriteString("See also:\n\n")
+			for _, p := range dc.cmd.SeeAlso {
+				fmt.Fprintf(&b, "- `%s`\n", p)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func manDocs(root *Command) string {
+	var b strings.Builder
+	md := root.Metadata
+	if md == nil {
+		md = &AppMetadata{}
+	}
+	source := strings.TrimSpace(root.Name + " " + md.Version)
+	fmt.Fprintf(&b, ".TH %q 1 \"\" %q %q\n", strings.ToUpper(root.Name), source, root.Name+" Manual")
+
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffEscape(root.Name))
+	if root.ShortHelp != "" {
+		b.WriteString(" \\- " + roffEscape(root.ShortHelp))
+	}
+	b.WriteString("\n")
+	b.WriteString(".SH SYNOPSIS\n")
+	fmt.Fprintf(&b, ".B %s\n", roffEscape(usageLine(root, root.Name)))
+	if root.LongHelp != "" {
+		b.WriteString(".SH DESCRIPTION\n")
+		b.WriteString(roffParagraphs(root.LongHelp))
+	}
+
+	writeFlags := func(flags []docFlag) {
+		for _, f := range flags {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape("-"+f.name), roffEscape(f.description))
+		}
+	}
+	if flags := docFlags(root); len(flags) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		writeFlags(flags)
+	}
+	if cmds := walkDocCommands(root)[1:]; len(cmds) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, dc := range cmds {
+			cmdPath := getCommandPath(dc.path)
+			fmt.Fprintf(&b, ".SS %s\n", roffEscape(cmdPath))
+			if dc.cmd.ShortHelp != "" {
+				b.WriteString(roffEscape(dc.cmd.ShortHelp) + "\n")
+			}
+			if dc.cmd.LongHelp != "" {
+				b.WriteString(".PP\n" + roffParagraphs(dc.cmd.LongHelp))
+			}
+			if dc.cmd.Destructive {
+				fmt.Fprintf(&b, ".PP\n%s\n", roffEscape(destructiveNote))
+			}
+			fmt.Fprintf(&b, ".PP\n.B %s\n", roffEscape(usageLine(dc.cmd, cmdPath)))
+			writeFlags(docFlags(dc.cmd))
+			if len(dc.cmd.SeeAlso) > 0 {
+				fmt.Fprintf(&b, ".PP\nSee also: %s\n", roffEscape(strings.Join(dc.cmd.SeeAlso, ", ")))
+			}
+		}
+	}
+	var envDocs []EnvDoc
+	for _, dc := range walkDocCommands(root) {
+		for _, env := range dc.cmd.EnvDocs {
+			if !slices.ContainsFunc(envDocs, func(e EnvDoc) bool { return e.Name == env.Name }) {
+				envDocs = append(envDocs, env)
+			}
+		}
+	}
+	if len(envDocs) > 0 {
+		b.WriteString(".SH ENVIRONMENT\n")
+		for _, env := range envDocs {
+			fmt.Fprintf(&b, ".TP\n.B %s\n%s\n", roffEscape(env.Name), roffEscape(env.Usage))
+		}
+	}
+	for _, section := range []struct{ title, value string }{
+		{"AUTHOR", md.Author},
+		{"LICENSE", md.License},
+		{"BUGS", md.BugReportURL},
+	} {
+		if section.value != "" {
+			fmt.Fprintf(&b, ".SH %s\n%s\n", section.title, roffEscape(section.value))
+		}
+	}
+	var seeAlso []string
+	if len(root.SeeAlso) > 0 {
+		seeAlso = append(seeAlso, roffEscape(strings.Join(root.SeeAlso, ", ")))
+	}
+	if md.Homepage != "" {
+		seeAlso = append(seeAlso, roffEscape(md.Homepage))
+	}
+	if len(seeAlso) > 0 {
+		fmt.Fprintf(&b, ".SH SEE ALSO\n%s\n", strings.Join(seeAlso, "\n.PP\n"))
+	}
+	return b.String()
+}
+
+// roffEscape escapes text for use in a roff document.
+func roffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// roffParagraphs escapes text for use in a roff document, separating its paragraphs with .PP
+// requests.
+func roffParagraphs(text string) string {
+	var b strings.Builder
+	blank := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank {
+			b.WriteString(".PP\n")
+			blank = false
+		}
+		b.WriteString(roffEscape(line) + "\n")
+	}
+	return b.String()
+}
+
+// yamlQuote returns s as a double-quoted YAML string.
+func yamlQuote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatVersion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no metadata", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "app", FormatVersion(&Command{Name: "app"}))
+		require.Empty(t, FormatVersion(nil))
+	})
+	t.Run("full metadata", func(t *testing.T) {
+		t.Parallel()
+		root := &Command{
+			Name: "app",
+			Metadata: &AppMetadata{
+				Version:      "v1.2.3",
+				Author:       "Jane Doe",
+				Homepage:     "https://example.com",
+				License:      "MIT",
+				BugReportURL: "https://example.com/issues",
+			},
+		}
+		require.Equal(t, "app v1.2.3\n"+
+			"Author: Jane Doe\n"+
+			"Homepage: https://example.com\n"+
+			"License: MIT\n"+
+			"Report bugs: https://example.com/issues", FormatVersion(root))
+	})
+}
+
+func TestGenerateDocs(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+	newRoot := func() *Command {
+		return &Command{
+			Name:      "app",
+			ShortHelp: "An example app",
+			Metadata: &AppMetadata{
+				Version: "v1.2.3",
+				Author:  "Jane Doe",
+				License: "MIT",
+			},
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("verbose", false, "enable verbose output")
+			}),
+			SubCommands: []*Command{
+				{
+					Name:      "run",
+					ShortHelp: "Run the app",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("config", "", "path to the config file")
+					}),
+					FlagsMetadata: []FlagMetadata{
+						{Name: "config", Required: true, EnvVar: "APP_CONFIG"},
+					},
+					EnvDocs: []EnvDoc{{Name: "APP_CACHE_DIR", Usage: "directory for cached results"}},
+					Exec:    exec,
+				},
+				{
+					Name:      "init",
+					ShortHelp: "Initialize the app",
+					LongHelp:  "Creates the config file.\n\nExisting files are kept.",
+					SeeAlso:   []string{"app run"},
+					Exec:      exec,
+				},
+			},
+			Exec: exec,
+		}
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, GenerateDocs(&buf, newRoot(), "markdown"))
+		want := "---\n" +
+			"title: \"app\"\n" +
+			"version: \"v1.2.3\"\n" +
+			"author: \"Jane Doe\"\n" +
+			"license: \"MIT\"\n" +
+			"---\n" +
+			"\n" +
+			"# app\n" +
+			"\n" +
+			"An example app\n" +
+			"\n" +
+			"```\napp [flags] <command>\n```\n" +
+			"\n" +
+			"Flags:\n" +
+			"\n" +
+			"- `-verbose`: enable verbose output (default: false)\n" +
+			"\n" +
+			"Commands:\n" +
+			"\n" +
+			"- `app init`: Initialize the app\n" +
+			"- `app run`: Run the app\n" +
+			"\n" +
+			"## app init\n" +
+			"\n" +
+			"Initialize the app\n" +
+			"\n" +
+			"Creates the config file.\n" +
+			"\n" +
+			"Existing files are kept.\n" +
+			"\n" +
+			"```\napp init\n```\n" +
+			"\n" +
+			"See also:\n" +
+			"\n" +
+			"- `app run`\n" +
+			"\n" +
+			"## app run\n" +
+			"\n" +
+			"Run the app\n" +
+			"\n" +
+			"```\napp run [flags]\n```\n" +
+			"\n" +
+			"Flags:\n" +
+			"\n" +
+			"- `-config`: path to the config file (env: APP_CONFIG) (required)\n" +
+			"\n" +
+			"Environment:\n" +
+			"\n" +
+			"- `APP_CACHE_DIR`: directory for cached results\n"
+		require.Equal(t, want, buf.String())
+	})
+	t.Run("man", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		root := newRoot()
+		root.SeeAlso = []string{"app-extras"}
+		root.Metadata.Homepage = "https://example.com"
+		root.LongHelp = "An app.\n\n.dotfiles are ignored."
+		require.NoError(t, GenerateDocs(&buf, root, "man"))
+		got := buf.String()
+		require.Contains(t, got, ".TH \"APP\" 1 \"\" \"app v1.2.3\" \"app Manual\"\n")
+		require.Contains(t, got, ".SH NAME\napp \\- An example app\n")
+		require.Contains(t, got, ".SH DESCRIPTION\nAn app.\n.PP\n\\&.dotfiles are ignored.\n.SH OPTIONS\n")
+		require.Contains(t, got, ".SS app run\nRun the app\n.PP\n.B app run [flags]\n")
+		require.Contains(t, got, ".TP\n.B \\-config\npath to the config file (env: APP_CONFIG) (required)\n")
+		require.Contains(t, got, ".SH ENVIRONMENT\n.TP\n.B APP_CACHE_DIR\ndirectory for cached results\n.SH AUTHOR\n")
+		require.Contains(t, got, ".SH AUTHOR\nJane Doe\n.SH LICENSE\nMIT\n")
+		require.Contains(t, got, ".SS app init\nInitialize the app\n.PP\nCreates the config file.\n.PP\nExisting files are kept.\n.PP\n.B app init\n")
+		require.Contains(t, got, ".B app init\n.PP\nSee also: app run\n")
+		require.Contains(t, got, ".SH SEE ALSO\napp\\-extras\n.PP\nhttps://example.com\n")
+	})
+	t.Run("unsupported format", func(t *testing.T) {
+		t.Parallel()
+		err := GenerateDocs(&bytes.Buffer{}, newRoot(), "html")
+		require.ErrorContains(t, err, `unsupported format "html"`)
+	})
+}
+
+func TestDocsCommand(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name:      "app",
+			ShortHelp: "An example app",
+			SubCommands: []*Command{
+				{Name: "run", ShortHelp: "Run the app", Exec: func(ctx context.Context, s *State) error { return nil }},
+			},
+		}
+	}
+	run := func(t *testing.T, root *Command, options *Options) (stdout, stderr string, err error) {
+		t.Helper()
+		var outBuf, errBuf bytes.Buffer
+		options.Stdout = &outBuf
+		options.Stderr = &errBuf
+		err = ParseAndRun(context.Background(), root, options)
+		return outBuf.String(), errBuf.String(), err
+	}
+
+	t.Run("stdout", func(t *testing.T) {
+		t.Parallel()
+		stdout, _, err := run(t, newRoot(), &Options{Args: []string{"docs", "--format", "man"}, DocsCommand: true})
+		require.NoError(t, err)
+		var want bytes.Buffer
+		require.NoError(t, GenerateDocs(&want, newRoot(), "man"))
+		require.Equal(t, want.String(), stdout)
+	})
+	t.Run("dir", func(t *testing.T) {
+		t.Parallel()
+		dir := filepath.Join(t.TempDir(), "docs")
+		_, _, err := run(t, newRoot(), &Options{Args: []string{"docs", "--dir", dir}, DocsCommand: true})
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(dir, "app.md"))
+		require.NoError(t, err)
+		var want bytes.Buffer
+		require.NoError(t, GenerateDocs(&want, newRoot(), "markdown"))
+		require.Equal(t, want.String(), string(data))
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		stdout, _, err := run(t, newRoot(), &Options{Args: []string{"docs", "--help"}, DocsCommand: true})
+		require.NoError(t, err)
+		require.Contains(t, stdout, "app docs [flags]")
+		require.Contains(t, stdout, "-format")
+
+		stdout, _, err = run(t, newRoot(), &Options{Args: []string{"--help"}, DocsCommand: true})
+		require.NoError(t, err)
+		require.NotContains(t, stdout, "docs")
+	})
+	t.Run("package", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		stdout, _, err := run(t, newRoot(), &Options{
+			Args:        []string{"docs", "--package", dir, "--packager", "homebrew"},
+			DocsCommand: true,
+		})
+		require.NoError(t, err)
+		var want bytes.Buffer
+		require.NoError(t, GeneratePackagingHints(&want, newRoot(), "homebrew"))
+		require.Equal(t, want.String(), stdout)
+		for _, f := range PackageFiles(newRoot()) {
+			require.FileExists(t, filepath.Join(dir, filepath.FromSlash(f.Path)))
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "man", "app.1"))
+		require.NoError(t, err)
+		want.Reset()
+		require.NoError(t, GenerateDocs(&want, newRoot(), "man"))
+		require.Equal(t, want.String(), string(data))
+
+		stdout, _, err = run(t, newRoot(), &Options{Args: []string{"docs", "--packager", "scoop"}, DocsCommand: true})
+		require.NoError(t, err)
+		require.Contains(t, stdout, `"bin": "app.exe"`)
+
+		_, stderr, err := run(t, newRoot(), &Options{Args: []string{"docs", "--packager", "apt"}, DocsCommand: true})
+		require.Error(t, err)
+		require.Contains(t, stderr, "must be one of: homebrew, scoop")
+
+		_, stderr, err = run(t, newRoot(), &Options{
+			Args:        []string{"docs", "--package", dir, "--dir", dir},
+			DocsCommand: true,
+		})
+		require.Error(t, err)
+		require.Contains(t, stderr, "-dir cannot be combined with -package or -packager")
+	})
+	t.Run("invalid format", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, newRoot(), &Options{Args: []string{"docs", "--format", "html"}, DocsCommand: true})
+		require.Error(t, err)
+		require.Contains(t, stderr, "must be one of: markdown, man")
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, newRoot(), &Options{Args: []string{"docs"}})
+		require.Error(t, err)
+		require.Contains(t, stderr, `unknown command "docs"`)
+	})
+	t.Run("defined by root", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		root.SubCommands = append(root.SubCommands, &Command{
+			Name: "docs",
+			Exec: func(ctx context.Context, s *State) error {
+				fmt.Fprintln(s.Stdout, "own docs")
+				return nil
+			},
+		})
+		stdout, _, err := run(t, root, &Options{Args: []string{"docs"}, DocsCommand: true})
+		require.NoError(t, err)
+		require.Equal(t, "own docs\n", stdout)
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || windows)
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func disableTerminalEcho(*os.File) (restore func() error, err error) {
+	return nil, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,32 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package cli
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// disableTerminalEcho turns off echoing of typed characters on the terminal f and returns a function
+// that restores the previous terminal state.
+func disableTerminalEcho(f *os.File) (restore func() error, err error) {
+	var state syscall.Termios
+	if err := termios(f, ioctlGetTermios, &state); err != nil {
+		return nil, err
+	}
+	noEcho := state
+	noEcho.Lflag &^= syscall.ECHO
+	if err := termios(f, ioctlSetTermios, &noEcho); err != nil {
+		return nil, err
+	}
+	return func() error { return termios(f, ioctlSetTermios, &state) }, nil
+}
+
+func termios(f *os.File, request uintptr, state *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(state)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
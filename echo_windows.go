@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableEchoInput is the console mode flag that echoes typed characters.
+const enableEchoInput = 0x4
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// disableTerminalEcho turns off echoing of typed characters on the console f and returns a function
+// that restores the previous console mode.
+func disableTerminalEcho(f *os.File) (restore func() error, err error) {
+	h := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return nil, err
+	}
+	if err := setConsoleMode(h, mode&^enableEchoInput); err != nil {
+		return nil, err
+	}
+	return func() error { return setConsoleMode(h, mode) }, nil
+}
+
+func setConsoleMode(h syscall.Handle, mode uint32) error {
+	if ok, _, err := procSetConsoleMode.Call(uintptr(h), uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
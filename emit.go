@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"flag"
+
+	"github.com/mfridman/cli/output"
+)
+
+const outputFormatFlagName = "output"
+
+// OutputFormatFlag defines a flag named name, or -output if name is empty, in the style of kubectl's
+// -output flag. It selects how [State.Emit] renders the result of a command: "table" (the
+// default), "json", "yaml", or "go-template=TEMPLATE", see [output.Parse]. Invalid formats are
+// rejected when parsing. The flag is typically defined on the root command, so it applies to every
+// subcommand.
+//
+// By default the flag has the same name as the flag defined by [OutputFlag]. A command hierarchy
+// that uses both gives one of them another name, e.g., OutputFormatFlag(f, "format"). The flag
+// value can be retrieved with GetFlag[output.Format].
+//
+//	root.Flags = cli.FlagsFunc(func(f *flag.FlagSet) {
+//	    cli.OutputFormatFlag(f, "")
+//	})
+func OutputFormatFlag(f *flag.FlagSet, name string) *output.Format {
+	if name == "" {
+		name = outputFormatFlagName
+	}
+	p := new(output.Format)
+	f.Var((*formatValue)(p), name, "output `FORMAT`: table, json, yaml, or go-template=TEMPLATE")
+	return p
+}
+
+// formatValue is the value of a flag defined by [OutputFormatFlag].
+type formatValue output.Format
+
+func (v *formatValue) cloneValue() flag.Value { return new(formatValue) }
+
+func (v *formatValue) Set(s string) error {
+	f, err := output.Parse(s)
+	if err != nil {
+		return err
+	}
+	*v = formatValue(f)
+	return nil
+}
+
+func (v *formatValue) Get() any { return output.Format(*v) }
+
+func (v *formatValue) String() string {
+	if v == nil {
+		return output.FormatTable
+	}
+	return output.Format(*v).String()
+}
+
+// Emit renders v to Stdout in the format selected with the flag defined by [OutputFormatFlag], or
+// as a table if the command hierarchy has no such flag, see [output.Write]. Emit also records v as
+// the result of the command with [State.SetResult], so programs embedding the CLI receive the value
+// itself rather than its rendering.
+//
+//	users, err := listUsers(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	return s.Emit(users)
+func (s *State) Emit(v any) error {
+	s.SetResult(v)
+	return output.Write(s.Stdout, s.outputFormat(), v)
+}
+
+// outputFormat returns the value of the flag defined by [OutputFormatFlag] in the command
+// hierarchy, or the zero format if there is none.
+func (s *State) outputFormat() output.Format {
+	for i := len(s.path) - 1; i >= 0; i-- {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			var format *formatValue
+			var name string
+			fset.VisitAll(func(f *flag.Flag) {
+				if v, ok := f.Value.(*formatValue); ok && format == nil {
+					format, name = v, f.Name
+				}
+			})
+			if format != nil {
+				s.markAccessed(name)
+				return output.Format(*format)
+			}
+		}
+	}
+	return output.Format{}
+}
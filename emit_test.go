@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfridman/cli/output"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmit(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	items := []item{{Name: "apples", Count: 3}, {Name: "pears", Count: 12}}
+	newRoot := func(withFlag bool) *Command {
+		root := &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name: "list",
+					Exec: func(ctx context.Context, s *State) error {
+						return s.Emit(items)
+					},
+				},
+			},
+		}
+		if withFlag {
+			root.Flags = FlagsFunc(func(f *flag.FlagSet) {
+				OutputFormatFlag(f, "")
+			})
+		}
+		return root
+	}
+	run := func(t *testing.T, root *Command, args ...string) (string, error) {
+		t.Helper()
+		var stdout bytes.Buffer
+		err := RunArgs(context.Background(), root, args, &RunOptions{Stdout: &stdout})
+		return stdout.String(), err
+	}
+
+	t.Run("default table", func(t *testing.T) {
+		t.Parallel()
+		want := "NAME     COUNT\napples   3\npears    12\n"
+		got, err := run(t, newRoot(true), "list")
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+		got, err = run(t, newRoot(false), "list")
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	})
+	t.Run("formats", func(t *testing.T) {
+		t.Parallel()
+		got, err := run(t, newRoot(true), "-output", "json", "list")
+		require.NoError(t, err)
+		require.Equal(t, `[
+  {
+    "name": "apples",
+    "count": 3
+  },
+  {
+    "name": "pears",
+    "count": 12
+  }
+]
+`, got)
+		got, err = run(t, newRoot(true), "-output=yaml", "list")
+		require.NoError(t, err)
+		require.Equal(t, "- count: 3\n  name: apples\n- count: 12\n  name: pears\n", got)
+		got, err = run(t, newRoot(true), "list", "-output", "go-template={{range .}}{{.Name}} {{end}}")
+		require.NoError(t, err)
+		require.Equal(t, "apples pears ", got)
+	})
+	t.Run("invalid format", func(t *testing.T) {
+		t.Parallel()
+		_, err := run(t, newRoot(true), "-output", "xml", "list")
+		require.ErrorContains(t, err, `unknown format "xml"`)
+	})
+	t.Run("result", func(t *testing.T) {
+		t.Parallel()
+		got, err := RunResult[[]item](context.Background(), newRoot(true), []string{"-output", "json", "list"},
+			&RunOptions{Stdout: &bytes.Buffer{}})
+		require.NoError(t, err)
+		require.Equal(t, items, got)
+	})
+	t.Run("get flag", func(t *testing.T) {
+		t.Parallel()
+		var format output.Format
+		root := newRoot(true)
+		root.SubCommands[0].Exec = func(ctx context.Context, s *State) error {
+			format = GetFlag[output.Format](s, "output")
+			return nil
+		}
+		_, err := run(t, root, "-output", "yaml", "list")
+		require.NoError(t, err)
+		require.Equal(t, output.FormatYAML, format.Name)
+	})
+	t.Run("with output flag", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot(false)
+		root.Flags = FlagsFunc(func(f *flag.FlagSet) {
+			OutputFormatFlag(f, "format")
+			OutputFlag(f)
+		})
+		path := filepath.Join(t.TempDir(), "items.json")
+		got, err := run(t, root, "-format", "json", "-output", path, "list")
+		require.NoError(t, err)
+		require.Empty(t, got)
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"name": "apples"`)
+	})
+}
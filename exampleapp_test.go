@@ -0,0 +1,146 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfridman/cli"
+	"github.com/mfridman/cli/examples/exampleapp"
+	"github.com/stretchr/testify/require"
+)
+
+// runApp parses and runs a fresh exampleapp command tree with the given arguments and stdin,
+// returning the captured stdout and stderr.
+func runApp(t *testing.T, stdin string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+
+	root := exampleapp.BuildRoot()
+	if err := cli.Parse(root, args); err != nil {
+		return "", "", err
+	}
+	var outBuf, errBuf bytes.Buffer
+	err = cli.Run(context.Background(), root, &cli.RunOptions{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &outBuf,
+		Stderr: &errBuf,
+	})
+	return outBuf.String(), errBuf.String(), err
+}
+
+func TestExampleApp(t *testing.T) {
+	t.Parallel()
+
+	t.Run("version", func(t *testing.T) {
+		t.Parallel()
+		stdout, _, err := runApp(t, "", "--version")
+		require.NoError(t, err)
+		require.Equal(t, "todo v1.0.0\n"+
+			"Homepage: https://github.com/mfridman/cli\n"+
+			"License: MIT\n"+
+			"Report bugs: https://github.com/mfridman/cli/issues\n", stdout)
+	})
+	t.Run("no subcommand", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := runApp(t, "")
+		require.NoError(t, err)
+		require.Contains(t, stderr, "subcommand required")
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		root := exampleapp.BuildRoot()
+		err := cli.Parse(root, []string{"task", "add", "--help"})
+		require.ErrorIs(t, err, flag.ErrHelp)
+		usage := cli.DefaultUsage(root)
+		require.Contains(t, usage, "todo task add <text> [flags]")
+		require.Contains(t, usage, "-tags")
+		require.Contains(t, usage, "Global Flags:")
+		require.Contains(t, usage, "-file")
+	})
+	t.Run("unknown command suggestion", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := runApp(t, "", "lst")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `unknown command "lst"`)
+		require.Contains(t, err.Error(), "list")
+	})
+	t.Run("missing required flag", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := runApp(t, "", "task", "add", "buy milk")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `command "todo task add": required flag "-file" not set`)
+	})
+	t.Run("task lifecycle", func(t *testing.T) {
+		t.Parallel()
+		file := filepath.Join(t.TempDir(), "tasks.json")
+
+		stdout, _, err := runApp(t, "", "task", "add", "--file", file, "--tags=home,errand", "buy", "milk")
+		require.NoError(t, err)
+		require.Equal(t, "Task added with ID 1\n", stdout)
+		stdout, _, err = runApp(t, "", "task", "add", "write tests", "--file="+file)
+		require.NoError(t, err)
+		require.Equal(t, "Task added with ID 2\n", stdout)
+
+		stdout, _, err = runApp(t, "", "list", "overdue", "--file", file)
+		require.NoError(t, err)
+		require.Contains(t, stdout, "1: buy milk")
+		require.Contains(t, stdout, "[home,errand]")
+		require.Contains(t, stdout, "2: write tests")
+
+		_, _, err = runApp(t, "", "task", "done", "1", "--file", file)
+		require.NoError(t, err)
+		stdout, _, err = runApp(t, "", "list", "overdue", "--file", file)
+		require.NoError(t, err)
+		require.NotContains(t, stdout, "buy milk")
+		require.Contains(t, stdout, "2: write tests")
+
+		_, _, err = runApp(t, "", "task", "remove", "2", "--file", file)
+		require.NoError(t, err)
+		stdout, _, err = runApp(t, "", "list", "overdue", "--file", file)
+		require.NoError(t, err)
+		require.Equal(t, "No overdue tasks, enjoy your day!\n", stdout)
+	})
+	t.Run("remove all with confirmation", func(t *testing.T) {
+		t.Parallel()
+		file := filepath.Join(t.TempDir(), "tasks.json")
+		_, _, err := runApp(t, "", "task", "add", "--file", file, "one")
+		require.NoError(t, err)
+
+		stdout, _, err := runApp(t, "n\n", "task", "remove", "--all", "--file", file)
+		require.NoError(t, err)
+		require.Contains(t, stdout, "Operation cancelled")
+		stdout, _, err = runApp(t, "", "list", "overdue", "--file", file)
+		require.NoError(t, err)
+		require.Contains(t, stdout, "1: one")
+
+		_, _, err = runApp(t, "y\n", "task", "remove", "--all", "--file", file)
+		require.NoError(t, err)
+		stdout, _, err = runApp(t, "", "list", "overdue", "--file", file)
+		require.NoError(t, err)
+		require.Equal(t, "No overdue tasks, enjoy your day!\n", stdout)
+	})
+	t.Run("completion", func(t *testing.T) {
+		t.Parallel()
+		file := filepath.Join(t.TempDir(), "tasks.json")
+		for _, text := range []string{"one", "two", "three"} {
+			_, _, err := runApp(t, "", "task", "add", "--file", file, text)
+			require.NoError(t, err)
+		}
+		_, _, err := runApp(t, "", "task", "done", "2", "--file", file)
+		require.NoError(t, err)
+
+		stdout, _, err := runApp(t, "", cli.CompleteCommandName, "")
+		require.NoError(t, err)
+		require.Equal(t, "list\ntask\ncompletion\n", stdout)
+		stdout, _, err = runApp(t, "", cli.CompleteCommandName, "task", "--file", file, "done", "")
+		require.NoError(t, err)
+		require.Equal(t, "1\n3\n", stdout)
+
+		stdout, _, err = runApp(t, "", "completion", "bash")
+		require.NoError(t, err)
+		require.Contains(t, stdout, "complete -o default -F _todo_complete todo")
+	})
+}
@@ -36,16 +36,5 @@ func main() {
 			return nil
 		},
 	}
-	if err := cli.Parse(root, os.Args[1:]); err != nil {
-		if errors.Is(err, flag.ErrHelp) {
-			fmt.Fprintf(os.Stdout, "%s\n", cli.DefaultUsage(root))
-			return
-		}
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
-	}
-	if err := cli.Run(context.Background(), root, nil); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
-	}
+	os.Exit(cli.Main(context.Background(), root, nil))
 }
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"flag"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// flagCache caches, per command path, the flags that make up the combined flag set of the path,
+// see combineFlags. Listing them visits every flag set of the path in sorted order, which dominates
+// the cost of parsing a large command tree, e.g., when completing a command line word by word.
+//
+// Entries are keyed by the terminal command and are rebuilt when the path or its flag sets change,
+// e.g., when Flags or FlagGroups of a command are replaced or a flag is defined after a parse.
+type flagCache struct {
+	mu      sync.Mutex
+	entries map[*Command]*flagCacheEntry
+}
+
+type flagCacheEntry struct {
+	chain  []*Command
+	sets   []*flag.FlagSet
+	counts []int
+	flags  []pathFlag
+}
+
+// flagCacheMu guards the creation of the flag caches of root commands.
+var flagCacheMu sync.Mutex
+
+// pathFlagCache returns the flag cache of the root command c, creating it on first use.
+func (c *Command) pathFlagCache() *flagCache {
+	flagCacheMu.Lock()
+	defer flagCacheMu.Unlock()
+	if c.flagCache == nil {
+		c.flagCache = &flagCache{}
+	}
+	return c.flagCache
+}
+
+// pathFlag is a flag of a command path with the flag set defining it.
+type pathFlag struct {
+	flag *flag.Flag
+	set  *flag.FlagSet
+}
+
+// pathFlags returns the flags of chain, with a subcommand's flag shadowing a parent flag with the
+// same name.
+func (c *flagCache) pathFlags(chain []*Command) []pathFlag {
+	var sets []*flag.FlagSet
+	for i := len(chain) - 1; i >= 0; i-- {
+		sets = append(sets, pathFlagSets(chain, i)...)
+	}
+	counts := make([]int, len(sets))
+	for i, fset := range sets {
+		counts[i] = flagCount(fset)
+	}
+	terminal := chain[len(chain)-1]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[terminal]; ok && slices.Equal(e.chain, chain) && slices.Equal(e.sets, sets) &&
+		slices.Equal(e.counts, counts) {
+		return e.flags
+	}
+	var flags []pathFlag
+	seen := make(map[string]bool)
+	for _, fset := range sets {
+		fset.VisitAll(func(f *flag.Flag) {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				flags = append(flags, pathFlag{flag: f, set: fset})
+			}
+		})
+	}
+	if c.entries == nil {
+		c.entries = make(map[*Command]*flagCacheEntry)
+	}
+	c.entries[terminal] = &flagCacheEntry{chain: slices.Clone(chain), sets: sets, counts: counts, flags: flags}
+	return flags
+}
+
+// formalField is the field of a flag.FlagSet holding its defined flags, if it has the expected
+// shape. Its length tells whether flags were defined without sorting them like VisitAll does.
+var formalField = func() *reflect.StructField {
+	f, ok := reflect.TypeOf(flag.FlagSet{}).FieldByName("formal")
+	if !ok || f.Type.Kind() != reflect.Map {
+		return nil
+	}
+	return &f
+}()
+
+// flagCount returns the number of flags defined in fset. Flags cannot be removed from a flag set,
+// so a changed count means that flags were defined.
+func flagCount(fset *flag.FlagSet) int {
+	if formalField != nil {
+		return reflect.ValueOf(fset).Elem().FieldByIndex(formalField.Index).Len()
+	}
+	n := 0
+	fset.VisitAll(func(*flag.Flag) { n++ })
+	return n
+}
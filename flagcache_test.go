@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagCache(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+	sub := &Command{
+		Name: "sub",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("name", "sub", "shadows the root flag")
+		}),
+		Exec: exec,
+	}
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("name", "root", "name")
+			f.Bool("verbose", false, "verbose output")
+		}),
+		SubCommands: []*Command{sub},
+		Exec:        exec,
+	}
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, Parse(root, []string{"sub", "-name=x", "-verbose"}))
+		require.Equal(t, "x", GetFlag[string](root.state, "name"))
+		require.Equal(t, "root", root.Flags.Lookup("name").Value.String())
+		require.True(t, GetFlag[bool](root.state, "verbose"))
+	}
+	require.Len(t, root.flagCache.entries, 1)
+
+	// Replaced flag sets and added flag groups are observed.
+	sub.Flags = FlagsFunc(func(f *flag.FlagSet) {
+		f.Int("count", 0, "count")
+	})
+	sub.FlagGroups = []*FlagGroup{{
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "", "region")
+		}),
+	}}
+	require.NoError(t, Parse(root, []string{"sub", "-count=2", "-region=eu", "-name=y"}))
+	require.Equal(t, 2, GetFlag[int](root.state, "count"))
+	require.Equal(t, "eu", GetFlag[string](root.state, "region"))
+	require.Equal(t, "y", root.Flags.Lookup("name").Value.String())
+
+	// Flags defined after a parse are observed.
+	root.Flags.String("late", "", "defined after parsing")
+	require.NoError(t, Parse(root, []string{"sub", "-late=z"}))
+	require.Equal(t, "z", GetFlag[string](root.state, "late"))
+}
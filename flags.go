@@ -0,0 +1,384 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FlagType is the set of types of flags defined with [Flag].
+type FlagType interface {
+	string | bool | int | int64 | uint | uint64 | float64 | time.Duration |
+		[]string | []int | map[string]string
+}
+
+// Flag defines a flag of type T with the specified name, default value, and usage string, and
+// returns a pointer to the flag's value. Slice and map flags are repeatable, like those defined
+// with [StringSlice], [IntSlice] and [StringMap].
+//
+// The flag value can be retrieved with GetFlag[T], so keeping the type parameters of Flag and
+// [GetFlag] aligned avoids type mismatches at run time.
+//
+//	cmd.Flags = cli.FlagsFunc(func(f *flag.FlagSet) {
+//	    cli.Flag(f, "limit", int64(100), "maximum number of results")
+//	    cli.Flag[[]string](f, "tag", nil, "tag to apply, may be repeated")
+//	})
+func Flag[T FlagType](f *flag.FlagSet, name string, value T, usage string) *T {
+	p := new(T)
+	*p = value
+	varFlag(f, p, name, usage)
+	return p
+}
+
+// varFlag defines a flag with the specified name and usage string bound to p, with the value p
+// points to as its default value. It reports whether the type of p is supported.
+func varFlag(fset *flag.FlagSet, p any, name, usage string) bool {
+	switch p := p.(type) {
+	case flag.Value:
+		fset.Var(p, name, usage)
+	case *string:
+		fset.StringVar(p, name, *p, usage)
+	case *bool:
+		fset.BoolVar(p, name, *p, usage)
+	case *int:
+		fset.IntVar(p, name, *p, usage)
+	case *int64:
+		fset.Int64Var(p, name, *p, usage)
+	case *uint:
+		fset.UintVar(p, name, *p, usage)
+	case *uint64:
+		fset.Uint64Var(p, name, *p, usage)
+	case *float64:
+		fset.Float64Var(p, name, *p, usage)
+	case *time.Duration:
+		fset.DurationVar(p, name, *p, usage)
+	case *[]string:
+		fset.Var(newSliceValue(p, *p, parseString), name, usage)
+	case *[]int:
+		fset.Var(newSliceValue(p, *p, parseInt), name, usage)
+	case *map[string]string:
+		fset.Var(newMapValue(p, *p), name, usage)
+	default:
+		return false
+	}
+	return true
+}
+
+// StringSlice defines a repeatable string flag with the specified name, default value, and usage
+// string. Each occurrence of the flag on the command line appends a value, e.g., "--tag foo --tag
+// bar" yields ["foo", "bar"]. The first occurrence replaces the default value.
+//
+// The flag value can be retrieved with GetFlag[[]string].
+//
+//	cmd.Flags = cli.FlagsFunc(func(f *flag.FlagSet) {
+//	    cli.StringSlice(f, "tag", nil, "tag to apply, may be repeated")
+//	})
+func StringSlice(f *flag.FlagSet, name string, value []string, usage string) *[]string {
+	p := new([]string)
+	f.Var(newSliceValue(p, value, parseString), name, usage)
+	return p
+}
+
+// IntSlice defines a repeatable int flag with the specified name, default value, and usage string.
+// Each occurrence of the flag on the command line appends a value, e.g., "--id 1 --id 2" yields [1,
+// 2]. The first occurrence replaces the default value.
+//
+// The flag value can be retrieved with GetFlag[[]int].
+func IntSlice(f *flag.FlagSet, name string, value []int, usage string) *[]int {
+	p := new([]int)
+	f.Var(newSliceValue(p, value, parseInt), name, usage)
+	return p
+}
+
+// StringMap defines a repeatable key=value flag with the specified name, default value, and usage
+// string. Each occurrence of the flag on the command line adds an entry, e.g., "--label env=prod
+// --label team=infra" yields {"env": "prod", "team": "infra"}. A repeated key overwrites the
+// previous value. The first occurrence replaces the default value.
+//
+// The flag value can be retrieved with GetFlag[map[string]string].
+func StringMap(f *flag.FlagSet, name string, value map[string]string, usage string) *map[string]string {
+	p := new(map[string]string)
+	f.Var(newMapValue(p, value), name, usage)
+	return p
+}
+
+// CountFlag defines a flag with the specified name and usage string whose value is the number of
+// times it appears on the command line. It is typically used for graded verbosity: "-v -v -v" or
+// the bundled form "-vvv" yields 3. The flag does not take a value, but an explicit count may be set
+// with "-v=2", and "-v=false" resets the count to zero.
+//
+// The flag value can be retrieved with GetFlag[int].
+//
+//	cmd.Flags = cli.FlagsFunc(func(f *flag.FlagSet) {
+//	    cli.CountFlag(f, "v", "increase verbosity, may be repeated")
+//	})
+func CountFlag(f *flag.FlagSet, name string, usage string) *int {
+	p := new(int)
+	f.Var((*countValue)(p), name, usage)
+	return p
+}
+
+// Choice defines a string flag with the specified name, default value, and usage string, whose
+// value must be one of choices. Invalid values are rejected when parsing, the allowed values are
+// listed in help text, and they are offered as shell completion candidates. The default value must
+// be empty or one of choices.
+//
+// The flag value can be retrieved with GetFlag[string].
+//
+//	cmd.Flags = cli.FlagsFunc(func(f *flag.FlagSet) {
+//	    cli.Choice(f, "format", []string{"json", "yaml", "table"}, "table", "output format")
+//	})
+func Choice(f *flag.FlagSet, name string, choices []string, value string, usage string) *string {
+	if value != "" && !slices.Contains(choices, value) {
+		panic(fmt.Sprintf("flag -%s: default value %q is not one of: %s", name, value, strings.Join(choices, ", ")))
+	}
+	p := new(string)
+	f.Var(newChoiceValue(p, choices, value), name, usage)
+	return p
+}
+
+type choiceValue struct {
+	p        *string
+	choices  []string
+	defValue string
+}
+
+func newChoiceValue(p *string, choices []string, value string) *choiceValue {
+	*p = value
+	return &choiceValue{p: p, choices: slices.Clone(choices), defValue: value}
+}
+
+func (v *choiceValue) cloneValue() flag.Value {
+	return newChoiceValue(new(string), v.choices, v.defValue)
+}
+
+func (v *choiceValue) Set(s string) error {
+	if !slices.Contains(v.choices, s) {
+		return fmt.Errorf("must be one of: %s", strings.Join(v.choices, ", "))
+	}
+	*v.p = s
+	return nil
+}
+
+func (v *choiceValue) Get() any { return *v.p }
+
+func (v *choiceValue) String() string {
+	if v == nil || v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+// flagChoices returns the allowed values of a flag defined with [Choice], or nil.
+func flagChoices(f *flag.Flag) []string {
+	if v, ok := f.Value.(*choiceValue); ok {
+		return v.choices
+	}
+	return nil
+}
+
+type countValue int
+
+// resetValue resets the count to zero, the default of every flag defined with [CountFlag].
+func (v *countValue) resetValue() { *v = 0 }
+
+func (v *countValue) Set(s string) error {
+	switch s {
+	case "true":
+		*v++
+		return nil
+	case "false":
+		*v = 0
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 0, strconv.IntSize)
+	if err != nil {
+		return numError(err)
+	}
+	if n < 0 {
+		return fmt.Errorf("count must not be negative")
+	}
+	*v = countValue(n)
+	return nil
+}
+
+func (v *countValue) Get() any { return int(*v) }
+
+func (v *countValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v))
+}
+
+func (v *countValue) IsBoolFlag() bool { return true }
+
+// expandCountFlags expands bundled count flags, such as "-vvv", into separate occurrences ("-v -v
+// -v"). An argument is only expanded if it is not a defined flag itself and every character names
+// the same count flag. Arguments consumed as the value of a preceding flag are left untouched.
+func expandCountFlags(fset *flag.FlagSet, args []string) []string {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name := strings.TrimLeft(arg, "-")
+		if name == arg || name == "" || strings.Contains(name, "=") {
+			expanded = append(expanded, arg)
+			continue
+		}
+		if f := fset.Lookup(name); f != nil {
+			expanded = append(expanded, arg)
+			if !isBoolFlag(f) && i+1 < len(args) {
+				// Keep the flag's value as is
+				i++
+				expanded = append(expanded, args[i])
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, "--") || len(name) < 2 || strings.Trim(name, name[:1]) != "" {
+			expanded = append(expanded, arg)
+			continue
+		}
+		if f := fset.Lookup(name[:1]); f == nil {
+			expanded = append(expanded, arg)
+			continue
+		} else if _, ok := f.Value.(*countValue); !ok {
+			expanded = append(expanded, arg)
+			continue
+		}
+		for range name {
+			expanded = append(expanded, "-"+name[:1])
+		}
+	}
+	return expanded
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+type sliceValue[T any] struct {
+	p        *[]T
+	defValue []T
+	parse    func(string) (T, error)
+	changed  bool
+}
+
+func newSliceValue[T any](p *[]T, value []T, parse func(string) (T, error)) *sliceValue[T] {
+	*p = slices.Clone(value)
+	return &sliceValue[T]{p: p, defValue: slices.Clone(value), parse: parse}
+}
+
+func (v *sliceValue[T]) cloneValue() flag.Value {
+	return newSliceValue(new([]T), v.defValue, v.parse)
+}
+
+func (v *sliceValue[T]) resetValue() {
+	*v.p = slices.Clone(v.defValue)
+	v.changed = false
+}
+
+func (v *sliceValue[T]) Set(s string) error {
+	parsed, err := v.parse(s)
+	if err != nil {
+		return err
+	}
+	if !v.changed {
+		*v.p = nil
+		v.changed = true
+	}
+	*v.p = append(*v.p, parsed)
+	return nil
+}
+
+func (v *sliceValue[T]) Get() any { return *v.p }
+
+func (v *sliceValue[T]) String() string {
+	if v == nil || v.p == nil {
+		return ""
+	}
+	values := make([]string, 0, len(*v.p))
+	for _, e := range *v.p {
+		values = append(values, fmt.Sprint(e))
+	}
+	return strings.Join(values, ",")
+}
+
+type mapValue struct {
+	p        *map[string]string
+	defValue map[string]string
+	changed  bool
+}
+
+func newMapValue(p *map[string]string, value map[string]string) *mapValue {
+	*p = make(map[string]string, len(value))
+	for k, v := range value {
+		(*p)[k] = v
+	}
+	return &mapValue{p: p, defValue: maps.Clone(value)}
+}
+
+func (v *mapValue) cloneValue() flag.Value {
+	return newMapValue(new(map[string]string), v.defValue)
+}
+
+func (v *mapValue) resetValue() {
+	*v.p = make(map[string]string, len(v.defValue))
+	for k, value := range v.defValue {
+		(*v.p)[k] = value
+	}
+	v.changed = false
+}
+
+func (v *mapValue) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", s)
+	}
+	if !v.changed {
+		*v.p = make(map[string]string)
+		v.changed = true
+	}
+	(*v.p)[key] = value
+	return nil
+}
+
+func (v *mapValue) Get() any { return *v.p }
+
+func (v *mapValue) String() string {
+	if v == nil || v.p == nil {
+		return ""
+	}
+	keys := make([]string, 0, len(*v.p))
+	for k := range *v.p {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+(*v.p)[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func parseString(s string) (string, error) { return s, nil }
+
+func parseInt(s string) (int, error) {
+	v, err := strconv.ParseInt(s, 0, strconv.IntSize)
+	if err != nil {
+		return 0, numError(err)
+	}
+	return int(v), nil
+}
+
+// numError unwraps a [strconv.NumError] to match the error messages of the flag package.
+func numError(err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		return ne.Err
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSliceAndMapFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "root",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				StringSlice(f, "tag", []string{"default"}, "tags")
+				IntSlice(f, "id", nil, "ids")
+				StringMap(f, "label", nil, "labels")
+			}),
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := Parse(root, nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{"default"}, GetFlag[[]string](root.state, "tag"))
+		require.Empty(t, GetFlag[[]int](root.state, "id"))
+		require.Empty(t, GetFlag[map[string]string](root.state, "label"))
+		require.Equal(t, "default", root.Flags.Lookup("tag").DefValue)
+		require.Equal(t, "", root.Flags.Lookup("id").DefValue)
+	})
+	t.Run("repeated", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := Parse(root, []string{
+			"--tag", "foo", "arg", "--tag=bar",
+			"--id", "1", "--id=0x10",
+			"--label", "env=prod", "--label=team=infra", "--label", "env=dev",
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"foo", "bar"}, GetFlag[[]string](root.state, "tag"))
+		require.Equal(t, []int{1, 16}, GetFlag[[]int](root.state, "id"))
+		require.Equal(t, map[string]string{"env": "dev", "team": "infra"}, GetFlag[map[string]string](root.state, "label"))
+		require.Equal(t, []string{"arg"}, root.state.Args)
+		require.Equal(t, "env=dev,team=infra", root.Flags.Lookup("label").Value.String())
+	})
+	t.Run("parsed twice", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--tag=a", "--id=1", "--label=a=1"}))
+		require.NoError(t, Parse(root, []string{"--tag=b", "--id=2", "--label=b=2"}))
+		require.Equal(t, []string{"b"}, GetFlag[[]string](root.state, "tag"))
+		require.Equal(t, []int{2}, GetFlag[[]int](root.state, "id"))
+		require.Equal(t, map[string]string{"b": "2"}, GetFlag[map[string]string](root.state, "label"))
+
+		require.NoError(t, Parse(root, nil))
+		require.Equal(t, []string{"default"}, GetFlag[[]string](root.state, "tag"))
+		require.Empty(t, GetFlag[[]int](root.state, "id"))
+		require.Empty(t, GetFlag[map[string]string](root.state, "label"))
+	})
+	t.Run("invalid int", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := Parse(root, []string{"--id", "one"})
+		require.ErrorContains(t, err, `invalid value "one" for flag -id: invalid syntax`)
+	})
+	t.Run("invalid map entry", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := Parse(root, []string{"--label", "env"})
+		require.ErrorContains(t, err, `invalid value "env" for flag -label: expected key=value, got "env"`)
+	})
+	t.Run("returned pointers", func(t *testing.T) {
+		t.Parallel()
+		fset := flag.NewFlagSet("test", flag.ContinueOnError)
+		tags := StringSlice(fset, "tag", nil, "tags")
+		labels := StringMap(fset, "label", map[string]string{"a": "b"}, "labels")
+		require.NoError(t, fset.Parse([]string{"--tag=x", "--tag=y"}))
+		require.Equal(t, []string{"x", "y"}, *tags)
+		require.Equal(t, map[string]string{"a": "b"}, *labels)
+	})
+}
+
+func TestFlag(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "root",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			Flag(f, "limit", int64(100), "maximum number of results")
+			Flag(f, "ratio", 0.5, "sampling ratio")
+			Flag(f, "size", uint(1), "batch size")
+			Flag(f, "wait", time.Second, "wait time")
+			Flag[[]string](f, "tag", nil, "tags")
+			Flag(f, "label", map[string]string{"a": "b"}, "labels")
+		}),
+		Exec: func(ctx context.Context, s *State) error { return nil },
+	}
+	err := Parse(root, []string{"-limit=5", "-wait=1m", "-tag=x", "-tag=y"})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), GetFlag[int64](root.state, "limit"))
+	require.Equal(t, 0.5, GetFlag[float64](root.state, "ratio"))
+	require.Equal(t, uint(1), GetFlag[uint](root.state, "size"))
+	require.Equal(t, time.Minute, GetFlag[time.Duration](root.state, "wait"))
+	require.Equal(t, []string{"x", "y"}, GetFlag[[]string](root.state, "tag"))
+	require.Equal(t, map[string]string{"a": "b"}, GetFlag[map[string]string](root.state, "label"))
+
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	limit := Flag(fset, "limit", 10, "limit")
+	verbose := Flag(fset, "verbose", false, "verbose")
+	require.NoError(t, fset.Parse([]string{"-limit=20", "-verbose"}))
+	require.Equal(t, 20, *limit)
+	require.True(t, *verbose)
+}
+
+func TestCountFlag(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "root",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				CountFlag(f, "v", "verbosity")
+				f.String("pattern", "", "pattern")
+				f.Bool("vv", false, "a flag that shadows bundling")
+			}),
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		want     int
+		wantArgs []string
+	}{
+		{name: "not set", args: nil, want: 0},
+		{name: "single", args: []string{"-v"}, want: 1},
+		{name: "repeated", args: []string{"-v", "arg", "--v", "-v"}, want: 3, wantArgs: []string{"arg"}},
+		{name: "bundled", args: []string{"-vvv"}, want: 3},
+		{name: "bundled and repeated", args: []string{"-vvvv", "-v"}, want: 5},
+		{name: "explicit count", args: []string{"-v=4"}, want: 4},
+		{name: "reset", args: []string{"-v", "-v=false"}, want: 0},
+		{name: "defined flag not expanded", args: []string{"-vv"}, want: 0},
+		{name: "flag value not expanded", args: []string{"--pattern", "-vvv"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newRoot()
+			err := Parse(root, tt.args)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, GetFlag[int](root.state, "v"))
+			require.Equal(t, tt.wantArgs, root.state.Args)
+		})
+	}
+	t.Run("parsed twice", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"-v", "-v"}))
+		require.NoError(t, Parse(root, []string{"-vvv"}))
+		require.Equal(t, 3, GetFlag[int](root.state, "v"))
+		require.NoError(t, Parse(root, nil))
+		require.Equal(t, 0, GetFlag[int](root.state, "v"))
+	})
+	t.Run("mixed bundle not expanded", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := Parse(root, []string{"-vvx"})
+		require.ErrorContains(t, err, "flag provided but not defined: -vvx")
+	})
+	t.Run("negative count", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := Parse(root, []string{"-v=-1"})
+		require.ErrorContains(t, err, `invalid boolean value "-1" for -v: count must not be negative`)
+	})
+}
+
+func TestChoiceFlag(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "root",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				Choice(f, "format", []string{"json", "yaml", "table"}, "table", "output format")
+			}),
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, nil))
+		require.Equal(t, "table", GetFlag[string](root.state, "format"))
+	})
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--format=yaml"}))
+		require.Equal(t, "yaml", GetFlag[string](root.state, "format"))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		err := Parse(newRoot(), []string{"--format", "xml"})
+		require.ErrorContains(t, err, `invalid value "xml" for flag -format: must be one of: json, yaml, table`)
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.ErrorIs(t, Parse(root, []string{"--help"}), flag.ErrHelp)
+		require.Contains(t, DefaultUsage(root), "-format    output format (one of: json, yaml, table) (default: table)")
+	})
+	t.Run("invalid default", func(t *testing.T) {
+		t.Parallel()
+		require.PanicsWithValue(t, `flag -format: default value "xml" is not one of: json, yaml`, func() {
+			Choice(flag.NewFlagSet("root", flag.ContinueOnError), "format", []string{"json", "yaml"}, "xml", "")
+		})
+	})
+}
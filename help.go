@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// HelpCommandName is the name of the hidden subcommand that shows the help text of a command or the
+// commands of a command group, see [Options.HelpCommand].
+const HelpCommandName = "help"
+
+// newHelpCommand returns a root command named like root, with the hidden [HelpCommandName]
+// subcommand that writes the usage text of the commands of root, rendered with the usage options of
+// options.
+func newHelpCommand(root *Command, options *Options) *Command {
+	return &Command{
+		Name: root.Name,
+		SubCommands: []*Command{{
+			Name:      HelpCommandName,
+			Usage:     root.Name + " " + HelpCommandName + " [command]... [group] [flags]",
+			ShortHelp: "Show help for a command or a command group",
+			LongHelp: "Shows the help text of the command given by its path, e.g., \"" + root.Name +
+				" help db migrate\", or lists the commands of one of its command groups when the path is " +
+				"followed by the name of the group.",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("all", false, "list all commands instead of summarizing command groups")
+			}),
+			Exec: func(ctx context.Context, s *State) error {
+				path := []*Command{root}
+				args := s.Args
+				for len(args) > 0 {
+					sub := path[len(path)-1].findSubCommand(args[0])
+					if sub == nil {
+						break
+					}
+					path = append(path, sub)
+					args = args[1:]
+				}
+				opt := usageOptions(options, s.Stdout)
+				if GetFlag[bool](s, "all") {
+					opt.AllCommands = true
+				}
+				var usage string
+				switch len(args) {
+				case 0:
+					usage = formatUsage(root, path, opt)
+				case 1:
+					var ok bool
+					if usage, ok = formatGroupUsage(path, args[0], opt); !ok {
+						return fmt.Errorf("command %q: unknown command or command group %q", getCommandPath(path), args[0])
+					}
+				default:
+					return fmt.Errorf("command %q: unexpected arguments: %s", getCommandPath(path), strings.Join(args, " "))
+				}
+				writeHelp(s.Stdout, usage, options.HelpPager)
+				return nil
+			},
+		}},
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelpCommand(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		root := &Command{
+			Name: "api",
+			SubCommands: []*Command{
+				{Name: "login", ShortHelp: "Log in", Exec: exec},
+				{
+					Name:      "db",
+					ShortHelp: "Manage databases",
+					SubCommands: []*Command{
+						{Name: "migrate", ShortHelp: "Run migrations", Group: "Schema", Exec: exec},
+						{
+							Name:      "backup",
+							ShortHelp: "Back up a database",
+							Flags: FlagsFunc(func(f *flag.FlagSet) {
+								f.String("dest", "", "backup destination")
+							}),
+							Exec: exec,
+						},
+					},
+				},
+			},
+		}
+		for i := 0; i < 3; i++ {
+			root.SubCommands = append(root.SubCommands,
+				&Command{Name: fmt.Sprint("bucket", i), ShortHelp: "Manage bucket", Group: "Storage", Exec: exec},
+				&Command{Name: fmt.Sprint("vm", i), ShortHelp: "Manage vm", Group: "Compute", Exec: exec},
+			)
+		}
+		return root
+	}
+	run := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:         args,
+			RunOptions:   RunOptions{Stdout: &stdout, Stderr: &stdout},
+			HelpCommand:  true,
+			UsageOptions: &UsageOptions{Width: 80, MaxCommands: 5},
+		})
+		return stdout.String(), err
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		t.Parallel()
+		output, err := run("help")
+		require.NoError(t, err)
+		require.Equal(t, `Usage:
+  api <command>
+
+Available Commands:
+  db       Manage databases
+  login    Log in
+
+Command Groups:
+  Compute    3 commands
+  Storage    3 commands
+
+Use "api [command] --help" for more information about a command.
+Use "api help <group>" to list the commands of a group, or "api help --all" to list all commands.
+`, output)
+
+		output, err = run("--help")
+		require.NoError(t, err)
+		require.Contains(t, output, "Command Groups:\n  Compute    3 commands\n")
+		require.Contains(t, output, `"api help --all"`)
+	})
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+		output, err := run("help", "--all")
+		require.NoError(t, err)
+		require.Contains(t, output, "Compute Commands:\n  vm0    Manage vm\n")
+		require.Contains(t, output, "Storage Commands:\n  bucket0    Manage bucket\n")
+		require.NotContains(t, output, "Command Groups:")
+		require.NotContains(t, output, "api help <group>")
+	})
+	t.Run("group", func(t *testing.T) {
+		t.Parallel()
+		output, err := run("help", "storage")
+		require.NoError(t, err)
+		require.Equal(t, `Storage Commands:
+  bucket0    Manage bucket
+  bucket1    Manage bucket
+  bucket2    Manage bucket
+
+Use "api [command] --help" for more information about a command.
+`, output)
+
+		output, err = run("help", "db", "Schema")
+		require.NoError(t, err)
+		require.Contains(t, output, "Schema Commands:\n  migrate    Run migrations\n")
+	})
+	t.Run("command", func(t *testing.T) {
+		t.Parallel()
+		output, err := run("help", "db", "backup")
+		require.NoError(t, err)
+		require.Contains(t, output, "api db backup [flags]")
+		require.Contains(t, output, "-dest    backup destination")
+	})
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := run("help", "db", "unknown")
+		require.ErrorContains(t, err, `command "api db": unknown command or command group "unknown"`)
+		_, err = run("help", "login", "a", "b")
+		require.ErrorContains(t, err, `command "api login": unexpected arguments: a b`)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		var stdout bytes.Buffer
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:            []string{"help"},
+			RunOptions:      RunOptions{Stdout: &stdout, Stderr: &stdout},
+			SkipHelpOnError: true,
+		})
+		require.ErrorContains(t, err, `unknown command "help"`)
+	})
+	t.Run("defined by the application", func(t *testing.T) {
+		t.Parallel()
+		var stdout bytes.Buffer
+		root := newRoot()
+		root.SubCommands = append(root.SubCommands, &Command{
+			Name: "help",
+			Exec: func(ctx context.Context, s *State) error {
+				fmt.Fprint(s.Stdout, "custom help")
+				return nil
+			},
+		})
+		err := ParseAndRun(context.Background(), root, &Options{
+			Args:        []string{"help"},
+			RunOptions:  RunOptions{Stdout: &stdout},
+			HelpCommand: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "custom help", stdout.String())
+	})
+}
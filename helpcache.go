@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/mfridman/cli/pkg/style"
+)
+
+// HelpCache memoizes usage text rendered by [FormatUsage], for REPL and server-style programs that
+// render help for the same commands repeatedly. Entries are keyed by the identity of the root and
+// the parsed command, their path and the rendering options, so trees that share command names do
+// not share entries. The zero value is ready to use, and a HelpCache is safe for concurrent use.
+//
+// The cache does not observe changes to the command tree. Call [HelpCache.Invalidate] after adding,
+// removing or modifying commands or flags.
+type HelpCache struct {
+	mu      sync.Mutex
+	entries map[helpCacheKey]string
+}
+
+type helpCacheKey struct {
+	root       *Command
+	command    *Command
+	path       string
+	width      int
+	style      *UsageStyle
+	flagOrigin bool
+	messages   *Messages
+	renderer   HelpRenderer
+
+	maxCommands int
+	allCommands bool
+	helpCommand bool
+}
+
+// FormatUsage is like the package-level [FormatUsage], but returns the cached usage text if the
+// same command was rendered with equivalent options before. Usage text rendered by a
+// [UsageOptions.Renderer] whose type is not comparable, e.g., a struct holding a slice, is not
+// cached.
+func (c *HelpCache) FormatUsage(root *Command, options *UsageOptions) string {
+	if root == nil || root.state == nil || len(root.state.path) == 0 {
+		return FormatUsage(root, options)
+	}
+	if options == nil {
+		options = &UsageOptions{}
+	}
+	if options.Renderer != nil && !reflect.TypeOf(options.Renderer).Comparable() {
+		return FormatUsage(root, options)
+	}
+	output := options.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	key := helpCacheKey{
+		root:       root,
+		command:    root.state.path[len(root.state.path)-1],
+		path:       getCommandPath(root.state.path),
+		width:      usageWidth(options, output),
+		flagOrigin: options.ShowFlagOrigin,
+		messages:   options.Messages,
+		renderer:   options.Renderer,
+
+		maxCommands: options.MaxCommands,
+		allCommands: options.AllCommands,
+		helpCommand: options.helpCommand,
+	}
+	if options.Style != nil && style.Enabled(output) {
+		key.style = options.Style
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if usage, ok := c.entries[key]; ok {
+		return usage
+	}
+	usage := FormatUsage(root, options)
+	if c.entries == nil {
+		c.entries = make(map[helpCacheKey]string)
+	}
+	c.entries[key] = usage
+	return usage
+}
+
+// Invalidate removes all cached usage text.
+func (c *HelpCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
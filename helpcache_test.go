@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHelpCache(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	root := &Command{
+		Name: "app",
+		SubCommands: []*Command{
+			{
+				Name:      "run",
+				ShortHelp: "Run the app",
+				UsageFunc: func(c *Command) string {
+					calls++
+					return "usage of run"
+				},
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			},
+		},
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("verbose", false, "enable verbose output")
+		}),
+		Exec: func(ctx context.Context, s *State) error { return nil },
+	}
+	var cache HelpCache
+	options := &UsageOptions{Width: 80}
+
+	require.NoError(t, Parse(root, []string{"run"}))
+	require.Equal(t, "usage of run", cache.FormatUsage(root, options))
+	require.Equal(t, "usage of run", cache.FormatUsage(root, &UsageOptions{Width: 80}))
+	require.Equal(t, 1, calls)
+
+	// Different options are cached separately.
+	cache.FormatUsage(root, &UsageOptions{Width: 100})
+	require.Equal(t, 2, calls)
+
+	// Different commands are cached separately.
+	require.NoError(t, Parse(root, nil))
+	require.Equal(t, FormatUsage(root, options), cache.FormatUsage(root, options))
+
+	// Renderers are part of the key.
+	withFooter := &UsageOptions{Width: 80, Renderer: footerRenderer{footer: "footer"}}
+	require.Equal(t, FormatUsage(root, withFooter), cache.FormatUsage(root, withFooter))
+	require.NotEqual(t, cache.FormatUsage(root, options), cache.FormatUsage(root, withFooter))
+
+	root.SubCommands[0].ShortHelp = "Run the application"
+	require.Contains(t, cache.FormatUsage(root, options), "Run the app\n")
+	cache.Invalidate()
+	require.Contains(t, cache.FormatUsage(root, options), "Run the application\n")
+
+	// Trees that share command names are cached separately.
+	other := &Command{
+		Name: "app",
+		SubCommands: []*Command{
+			{Name: "run", ShortHelp: "Run another app", Exec: root.Exec},
+		},
+		Exec: root.Exec,
+	}
+	require.NoError(t, Parse(other, nil))
+	require.Contains(t, cache.FormatUsage(other, options), "Run another app\n")
+	require.Contains(t, cache.FormatUsage(root, options), "Run the application\n")
+
+	require.NoError(t, Parse(other, []string{"run"}))
+	require.NoError(t, Parse(root, []string{"run"}))
+	require.NotEqual(t, cache.FormatUsage(root, options), cache.FormatUsage(other, options))
+}
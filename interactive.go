@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// InteractiveOptions configures [Interactive].
+type InteractiveOptions struct {
+	// RunOptions configures how commands are executed, see [RunOptions]. Lines are read from Stdin,
+	// which commands share with the interactive shell through a buffered reader. Since that reader
+	// is not a terminal, destructive commands must be confirmed with the -yes flag.
+	RunOptions
+
+	// Prompt is written to Stdout before each line is read. Defaults to the root command's name
+	// followed by "> ".
+	Prompt string
+	// History holds previously entered lines, e.g., restored from a file.
+	History []string
+	// OnLine, if set, is called with every line added to the history, e.g., to persist it.
+	OnLine func(line string)
+
+	// UsageOptions configures how usage text is rendered for "help", the help flags and
+	// [ShowHelp], see [Options.UsageOptions].
+	UsageOptions *UsageOptions
+	// Messages translates the text produced by the framework, see [Options.Messages].
+	Messages *Messages
+}
+
+// Interactive runs an interactive shell for the command tree: it reads lines from Stdin, splits
+// them into arguments with [SplitCommandLine], and parses and runs each line like [RunArgs], until
+// Stdin is exhausted, the context is canceled, or the "exit" or "quit" command is entered. Errors
+// are written to Stderr and do not end the session. Flag values do not carry over between lines.
+//
+// Besides the commands of the tree, unless shadowed by a subcommand of the root command, the shell
+// understands "help [command]", "history" to list previous lines, "!!" to repeat the previous line,
+// and "exit" and "quit". Completion candidates for a partial line are available from
+// [CompleteLine], e.g., for use with a line editing library.
+//
+// The options parameter may be nil, in which case default values are used.
+func Interactive(ctx context.Context, root *Command, options *InteractiveOptions) error {
+	if root == nil {
+		return errors.New("root command is nil")
+	}
+	if options == nil {
+		options = &InteractiveOptions{}
+	}
+	runOptions := options.RunOptions
+	checkAndSetRunOptions(&runOptions)
+	prompt := options.Prompt
+	if prompt == "" {
+		prompt = root.Name + "> "
+	}
+	r := bufio.NewReader(runOptions.Stdin)
+	runOptions.Stdin = r
+	history := append([]string(nil), options.History...)
+	if err := validateCommands(root, nil); err != nil {
+		return fmt.Errorf("failed to parse: %w", err)
+	}
+	usage := usageOptions(&Options{UsageOptions: options.UsageOptions, Messages: options.Messages}, runOptions.Stdout)
+	cfg := &parseConfig{lookupEnv: os.LookupEnv, messages: options.Messages, usage: usage}
+
+	for {
+		if err := CheckCtx(ctx); err != nil {
+			return err
+		}
+		fmt.Fprint(runOptions.Stdout, prompt)
+		line, err := r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if errors.Is(err, io.EOF) && line == "" {
+			fmt.Fprintln(runOptions.Stdout)
+			return nil
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "!!" {
+			if len(history) == 0 {
+				fmt.Fprintln(runOptions.Stderr, "error: "+options.Messages.noPreviousLine())
+				continue
+			}
+			line = history[len(history)-1]
+			fmt.Fprintln(runOptions.Stdout, line)
+		}
+		history = append(history, line)
+		if options.OnLine != nil {
+			options.OnLine(line)
+		}
+
+		args, err := SplitCommandLine(line)
+		if err != nil {
+			fmt.Fprintf(runOptions.Stderr, "error: %v\n", err)
+			continue
+		}
+		if root.findSubCommand(args[0]) == nil {
+			switch args[0] {
+			case "exit", "quit":
+				return nil
+			case "history":
+				for i, h := range history {
+					fmt.Fprintf(runOptions.Stdout, "%5d  %s\n", i+1, h)
+				}
+				continue
+			case "help":
+				args = append(args[1:], "--help")
+			}
+		}
+		// Parse into a fresh state with copies of the flag sets, so flag values of one line do not
+		// leak into the next.
+		runOptions.Trace.parseStart(root, args)
+		s := &State{clones: &flagClones{}}
+		if err := parseState(root, s, args, cfg); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(runOptions.Stdout, formatUsage(root, s.path, usage))
+				continue
+			}
+			fmt.Fprintf(runOptions.Stderr, "error: %v\n", err)
+			continue
+		}
+		runOptions.Trace.commandResolved(slices.Clone(s.path))
+		inv := &Invocation{state: s}
+		if err := inv.Run(ctx, &runOptions); err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(runOptions.Stderr, "error: %v\n", err)
+		}
+	}
+}
+
+// SplitCommandLine splits a command line into arguments the way a POSIX shell does, without
+// expansions: arguments are separated by unquoted whitespace, single quotes preserve their content
+// literally, double quotes preserve it except for backslash escapes of '"', '\', '$' and '`', and
+// an unquoted backslash escapes the next character.
+func SplitCommandLine(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+	)
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		case c == '\\':
+			inArg = true
+			if i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			}
+		case c == '\'':
+			inArg = true
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(string(runes[i+1 : end]))
+			i = end
+		case c == '"':
+			inArg = true
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				current.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, errors.New("unterminated double quote")
+			}
+		default:
+			inArg = true
+			current.WriteRune(c)
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// shellQuote quotes s for a POSIX shell, so that [SplitCommandLine] returns it as a single
+// argument. Words made of safe characters only are returned unchanged.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_=+.,/:@%", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// CompleteLine returns completion candidates for the last word of a partial command line, using
+// the same engine as the shell completion scripts (see [GenerateCompletion]). If the line ends with
+// whitespace, candidates for a new word are returned. Candidates are filtered by the word being
+// completed. Native shell completions requested with [CompleteFiles] and similar are not
+// available.
+func CompleteLine(ctx context.Context, root *Command, line string) []string {
+	words, err := SplitCommandLine(line)
+	if err != nil {
+		return nil
+	}
+	if len(words) == 0 || strings.TrimRight(line, " \t") != line {
+		words = append(words, "")
+	}
+	toComplete := words[len(words)-1]
+	var candidates []string
+	for _, c := range complete(ctx, root, words, &State{}) {
+		if strings.HasPrefix(c, toComplete) && !isDirective(c) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
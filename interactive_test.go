@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractive(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name:      "greet",
+					ShortHelp: "greet someone",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.Bool("loud", false, "shout")
+					}),
+					Exec: func(ctx context.Context, s *State) error {
+						msg := "hello " + strings.Join(s.Args, ",")
+						if GetFlag[bool](s, "loud") {
+							msg = strings.ToUpper(msg)
+						}
+						fmt.Fprintln(s.Stdout, msg)
+						return nil
+					},
+				},
+				{
+					Name: "fail",
+					Exec: func(ctx context.Context, s *State) error {
+						return errors.New("boom")
+					},
+				},
+			},
+		}
+	}
+	run := func(t *testing.T, root *Command, input string, opt *InteractiveOptions) (string, string, error) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		if opt == nil {
+			opt = &InteractiveOptions{}
+		}
+		opt.Stdin = strings.NewReader(input)
+		opt.Stdout = &stdout
+		opt.Stderr = &stderr
+		err := Interactive(context.Background(), root, opt)
+		return stdout.String(), stderr.String(), err
+	}
+
+	t.Run("runs lines until EOF", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(t, newRoot(), "greet --loud 'big world'\n\ngreet bob\n", nil)
+		require.NoError(t, err)
+		assert.Empty(t, stderr)
+		// Flag values do not carry over between lines.
+		assert.Equal(t, "app> HELLO BIG WORLD\napp> app> hello bob\napp> \n", stdout)
+	})
+	t.Run("errors do not end the session", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(t, newRoot(), "fail\nunknown\ngreet 'oops\ngreet x\n", nil)
+		require.NoError(t, err)
+		assert.Contains(t, stderr, "error: boom\n")
+		assert.Contains(t, stderr, `error: unknown command "unknown"`)
+		assert.Contains(t, stderr, "error: unterminated single quote\n")
+		assert.Contains(t, stdout, "hello x\n")
+	})
+	t.Run("exit", func(t *testing.T) {
+		t.Parallel()
+		stdout, _, err := run(t, newRoot(), "exit\ngreet x\n", &InteractiveOptions{Prompt: "$ "})
+		require.NoError(t, err)
+		assert.Equal(t, "$ ", stdout)
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(t, newRoot(), "help greet\n", nil)
+		require.NoError(t, err)
+		assert.Empty(t, stderr)
+		assert.Contains(t, stdout, "greet someone")
+		assert.Contains(t, stdout, "-loud")
+	})
+	t.Run("help with usage options", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(t, newRoot(), "help\nunknown\n", &InteractiveOptions{
+			UsageOptions: &UsageOptions{Renderer: footerRenderer{footer: "Docs: https://example.com"}},
+			Messages: &Messages{
+				AvailableCommands: "Comandos disponibles:",
+				UnknownCommand:    func(name string) string { return fmt.Sprintf("comando desconocido %q", name) },
+			},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, stdout, "Comandos disponibles:")
+		assert.Contains(t, stdout, "Docs: https://example.com")
+		assert.Contains(t, stderr, `error: comando desconocido "unknown"`)
+	})
+	t.Run("history", func(t *testing.T) {
+		t.Parallel()
+		var lines []string
+		opt := &InteractiveOptions{
+			History: []string{"greet old"},
+			OnLine:  func(line string) { lines = append(lines, line) },
+		}
+		stdout, stderr, err := run(t, newRoot(), "!!\ngreet new\nhistory\n", opt)
+		require.NoError(t, err)
+		assert.Empty(t, stderr)
+		assert.Contains(t, stdout, "hello old\n")
+		assert.Contains(t, stdout, "    1  greet old\n    2  greet old\n    3  greet new\n    4  history\n")
+		assert.Equal(t, []string{"greet old", "greet new", "history"}, lines)
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := Interactive(ctx, newRoot(), &InteractiveOptions{
+			RunOptions: RunOptions{Stdin: strings.NewReader("greet\n"), Stdout: new(bytes.Buffer)},
+		})
+		require.ErrorIs(t, err, ErrInterrupted)
+	})
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		line string
+		want []string
+		err  string
+	}{
+		{line: "", want: nil},
+		{line: "  a  b\tc ", want: []string{"a", "b", "c"}},
+		{line: `a 'b c' "d e"`, want: []string{"a", "b c", "d e"}},
+		{line: `--name='x y'z`, want: []string{"--name=x yz"}},
+		{line: `a\ b ''`, want: []string{"a b", ""}},
+		{line: `"say \"hi\" \n"`, want: []string{`say "hi" \n`}},
+		{line: `'it\'s'`, err: "unterminated single quote"},
+		{line: `"open`, err: "unterminated double quote"},
+	}
+	for _, tt := range tests {
+		got, err := SplitCommandLine(tt.line)
+		if tt.err != "" {
+			require.EqualError(t, err, tt.err, tt.line)
+			continue
+		}
+		require.NoError(t, err, tt.line)
+		assert.Equal(t, tt.want, got, tt.line)
+	}
+}
+
+func TestCompleteLine(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		SubCommands: []*Command{
+			{Name: "greet", Flags: FlagsFunc(func(f *flag.FlagSet) { f.Bool("loud", false, "shout") })},
+			{Name: "get"},
+			{Name: "fail", CompleteArgs: CompleteFiles()},
+		},
+	}
+	assert.Equal(t, []string{"greet", "get"}, CompleteLine(context.Background(), root, "g"))
+	assert.Equal(t, []string{"-loud"}, CompleteLine(context.Background(), root, "greet -l"))
+	assert.Len(t, CompleteLine(context.Background(), root, ""), 3)
+	assert.Nil(t, CompleteLine(context.Background(), root, "greet 'x"))
+	assert.Nil(t, CompleteLine(context.Background(), root, "fail "))
+
+	t.Run("bound variables unchanged", func(t *testing.T) {
+		t.Parallel()
+		var name string
+		root := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.StringVar(&name, "name", "default", "name")
+				StringSlice(f, "tag", nil, "tag")
+			}),
+			SubCommands: []*Command{{Name: "sub", Exec: func(ctx context.Context, s *State) error { return nil }}},
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, []string{"sub"}, CompleteLine(context.Background(), root, "--name polluted --tag x su"))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, "default", name)
+		require.NoError(t, Parse(root, []string{"sub"}))
+		assert.Empty(t, GetFlag[[]string](root.state, "tag"))
+	})
+}
@@ -0,0 +1,259 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Invocation is the result of parsing arguments with [ParseInvocation]: the resolved terminal
+// command, its bound flag values and the remaining arguments.
+//
+// Unlike [Parse], ParseInvocation does not store the result in the command tree. The flag values
+// of an Invocation are held in private copies of the command tree's flag sets, so a single command
+// tree can be parsed concurrently and reused across tests without state bleeding between
+// invocations.
+type Invocation struct {
+	state *State
+}
+
+// ParseInvocation is like [Parse], but returns the parse result as an [Invocation] instead of
+// storing it in the root command. The command tree, including the variables flags are bound to
+// (e.g., with [flag.FlagSet.StringVar]), is not modified. Flags therefore must be read with
+// [GetFlag] or [LookupFlag] rather than through bound variables.
+//
+// Flag values are copied from the command tree's flag sets. The standard library flag types and the
+// flag types of this package are copied and reset to their default values. A custom [flag.Value] is
+// copied if it is a pointer to a basic type, e.g., "type level int"; otherwise it is shared with
+// the command tree and its value is not isolated between invocations.
+func ParseInvocation(root *Command, args []string) (*Invocation, error) {
+	return parseInvocation(root, args, &parseConfig{lookupEnv: os.LookupEnv})
+}
+
+// ParseOnly is like [ParseInvocation], but does not require the terminal command to have an Exec
+// function. It resolves the command, flag values and remaining arguments without executing
+// anything, e.g., to explain what a command line would do, to validate arguments, or to hand the
+// parsed command to an external executor. Flag values are available from [Invocation.State].
+//
+// Running an Invocation whose command has no Exec function returns an error.
+func ParseOnly(root *Command, args []string) (*Invocation, error) {
+	return parseInvocation(root, args, &parseConfig{lookupEnv: os.LookupEnv, allowNoExec: true})
+}
+
+// Resolve parses args like [ParseOnly] and returns the terminal command along with a [State]
+// holding its flag values and remaining arguments. It is meant for tooling built on top of a
+// command tree, such as completion, documentation generators or linters, that needs to know which
+// command a command line resolves to without running it. The command tree is not modified, and the
+// streams of the returned State are nil.
+func Resolve(root *Command, args []string) (*Command, *State, error) {
+	inv, err := ParseOnly(root, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return inv.Command(), inv.State(), nil
+}
+
+func parseInvocation(root *Command, args []string, cfg *parseConfig) (*Invocation, error) {
+	s, err := parseInvocationState(root, args, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{state: s}, nil
+}
+
+// parseInvocationState parses args into a new State holding copies of the flag sets. The State is
+// returned even if parsing fails, e.g., to render the usage text of the command parsing stopped at.
+// It is nil if the command tree is invalid.
+func parseInvocationState(root *Command, args []string, cfg *parseConfig) (*State, error) {
+	if root == nil {
+		return nil, fmt.Errorf("failed to parse: root command is nil")
+	}
+	if err := validateCommands(root, nil); err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	s := &State{clones: &flagClones{}}
+	return s, parseState(root, s, args, cfg)
+}
+
+// RunArgs parses args with [ParseInvocation] and runs the terminal command with [Invocation.Run].
+// Unlike [Parse] followed by [Run], it does not store any state in the command tree, so it may be
+// called concurrently with different arguments, e.g., by a REPL or a server embedding the CLI.
+// Every call has its own arguments, I/O streams and flag values.
+//
+// The options parameter may be nil, in which case default values are used. See [RunOptions] for
+// more details.
+func RunArgs(ctx context.Context, root *Command, args []string, options *RunOptions) error {
+	_, err := runArgs(ctx, root, args, options)
+	return err
+}
+
+// runArgs is like RunArgs, and also returns the state the command ran with.
+func runArgs(ctx context.Context, root *Command, args []string, options *RunOptions) (*State, error) {
+	var trace *Trace
+	if options != nil {
+		trace = options.Trace
+	}
+	trace.parseStart(root, args)
+	inv, err := ParseInvocation(root, args)
+	if err != nil {
+		return nil, err
+	}
+	trace.commandResolved(inv.Path())
+	return inv.run(ctx, options)
+}
+
+// RunResult is like [RunArgs], but returns the result recorded by Exec with [State.SetResult], for
+// programs and tests that consume a command's result instead of parsing its output. It returns an
+// error if Exec succeeded without recording a result, or if the result is not of type T.
+//
+// The options parameter may be nil, in which case default values are used. See [RunOptions] for
+// more details.
+func RunResult[T any](ctx context.Context, root *Command, args []string, options *RunOptions) (T, error) {
+	var zero T
+	s, err := runArgs(ctx, root, args, options)
+	if err != nil {
+		return zero, err
+	}
+	result, ok := s.Result()
+	if !ok {
+		return zero, fmt.Errorf("command %q did not record a result", getCommandPath(s.path))
+	}
+	v, ok := result.(T)
+	if !ok {
+		return zero, fmt.Errorf("command %q: result is of type %T, not %T", getCommandPath(s.path), result, zero)
+	}
+	return v, nil
+}
+
+// Command returns the terminal command, i.e., the command that is executed by [Invocation.Run].
+func (inv *Invocation) Command() *Command {
+	return inv.state.path[len(inv.state.path)-1]
+}
+
+// Path returns the command chain from the root command to the terminal command.
+func (inv *Invocation) Path() []*Command {
+	return slices.Clone(inv.state.path)
+}
+
+// Args returns the positional arguments remaining after flag parsing.
+func (inv *Invocation) Args() []string {
+	return slices.Clone(inv.state.Args)
+}
+
+// State returns a [State] holding the parsed flag values and arguments, without running the
+// command. Flags are read with [GetFlag] or [LookupFlag], and [State.FlagSources] reports where
+// their values came from. The streams of the returned State are nil.
+func (inv *Invocation) State() *State {
+	return inv.newState()
+}
+
+// Run executes the terminal command, like [Run]. Every call runs with a fresh [State], so the
+// same Invocation may be run multiple times, including concurrently.
+//
+// The options parameter may be nil, in which case default values are used. See [RunOptions] for
+// more details.
+func (inv *Invocation) Run(ctx context.Context, options *RunOptions) error {
+	_, err := inv.run(ctx, options)
+	return err
+}
+
+// run is like Run, and also returns the state the command ran with.
+func (inv *Invocation) run(ctx context.Context, options *RunOptions) (*State, error) {
+	cmd := inv.Command()
+	if cmd.Exec == nil {
+		return nil, fmt.Errorf("command %q: no exec function defined", getCommandPath(inv.state.path))
+	}
+	options = checkAndSetRunOptions(options)
+	s := inv.newState()
+	s.Stdin, s.Stdout, s.Stderr = options.Stdin, options.Stdout, options.Stderr
+	bindStructFlags(s)
+	return s, runState(ctx, cmd, s, options)
+}
+
+func (inv *Invocation) newState() *State {
+	return &State{
+		Args:      slices.Clone(inv.state.Args),
+		dashArgs:  slices.Clone(inv.state.dashArgs),
+		path:      inv.state.path,
+		setFlags:  inv.state.setFlags,
+		sources:   inv.state.sources,
+		clones:    inv.state.clones,
+		confirmed: inv.state.confirmed,
+		usage:     inv.state.usage,
+		messages:  inv.state.messages,
+	}
+}
+
+// flagClones holds per-invocation copies of flag sets, keyed by the original flag set. A nil
+// *flagClones uses the original flag sets.
+type flagClones struct {
+	mu   sync.Mutex
+	sets map[*flag.FlagSet]*flag.FlagSet
+}
+
+// pathFlagSets is like the package-level pathFlagSets, but returns the copies of the flag sets.
+func (c *flagClones) pathFlagSets(path []*Command, i int) []*flag.FlagSet {
+	sets := pathFlagSets(path, i)
+	if c == nil {
+		return sets
+	}
+	for i, fset := range sets {
+		sets[i] = c.flagSet(fset)
+	}
+	return sets
+}
+
+// flagSet returns the copy of fset, creating it on first use.
+func (c *flagClones) flagSet(fset *flag.FlagSet) *flag.FlagSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sets == nil {
+		c.sets = make(map[*flag.FlagSet]*flag.FlagSet)
+	}
+	clone, ok := c.sets[fset]
+	if !ok {
+		clone = cloneFlagSet(fset)
+		c.sets[fset] = clone
+	}
+	return clone
+}
+
+func cloneFlagSet(fset *flag.FlagSet) *flag.FlagSet {
+	clone := flag.NewFlagSet(fset.Name(), flag.ContinueOnError)
+	fset.VisitAll(func(f *flag.Flag) {
+		clone.Var(cloneFlagValue(f.Value, f.DefValue), f.Name, f.Usage)
+	})
+	return clone
+}
+
+// cloneFlagValue returns a copy of v reset to its default value, or v itself if it cannot be
+// copied.
+func cloneFlagValue(v flag.Value, defValue string) flag.Value {
+	if c, ok := v.(interface{ cloneValue() flag.Value }); ok {
+		return c.cloneValue()
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return v
+	}
+	switch rv.Elem().Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return v
+	}
+	clone, ok := reflect.New(rv.Elem().Type()).Interface().(flag.Value)
+	if !ok {
+		return v
+	}
+	reflect.ValueOf(clone).Elem().Set(rv.Elem())
+	if err := clone.Set(defValue); err != nil {
+		return v
+	}
+	return clone
+}
@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvocation(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() (*Command, *string) {
+		region := new(string)
+		root := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.StringVar(region, "region", "us-east-1", "cloud region")
+				f.Bool("verbose", false, "enable verbose output")
+			}),
+			SubCommands: []*Command{
+				{
+					Name: "deploy",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.Int("replicas", 1, "number of replicas")
+						StringSlice(f, "tag", nil, "image tag")
+					}),
+					Exec: func(ctx context.Context, s *State) error {
+						fmt.Fprintf(s.Stdout, "%s %d %v %v",
+							GetFlag[string](s, "region"),
+							GetFlag[int](s, "replicas"),
+							GetFlag[[]string](s, "tag"),
+							s.Args,
+						)
+						return nil
+					},
+				},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		return root, region
+	}
+
+	t.Run("tree not modified", func(t *testing.T) {
+		t.Parallel()
+		root, region := newRoot()
+		inv, err := ParseInvocation(root, []string{"--region=eu-west-1", "deploy", "--replicas=3", "web"})
+		require.NoError(t, err)
+		require.Equal(t, "deploy", inv.Command().Name)
+		require.Equal(t, "app deploy", getCommandPath(inv.Path()))
+		require.Equal(t, []string{"web"}, inv.Args())
+		require.Nil(t, root.state)
+		require.Equal(t, "us-east-1", *region)
+
+		var out bytes.Buffer
+		require.NoError(t, inv.Run(context.Background(), &RunOptions{Stdout: &out}))
+		require.Equal(t, "eu-west-1 3 [] [web]", out.String())
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		t.Parallel()
+		root, _ := newRoot()
+		const n = 20
+		var wg sync.WaitGroup
+		outputs := make([]string, n)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				args := []string{"--region", fmt.Sprintf("r%d", i), "deploy", fmt.Sprintf("--replicas=%d", i)}
+				if i%2 == 0 {
+					args = append(args, "--tag", fmt.Sprintf("v%d", i))
+				}
+				inv, err := ParseInvocation(root, args)
+				if !assert.NoError(t, err) {
+					return
+				}
+				var out bytes.Buffer
+				assert.NoError(t, inv.Run(context.Background(), &RunOptions{Stdout: &out}))
+				outputs[i] = out.String()
+			}(i)
+		}
+		wg.Wait()
+		for i, got := range outputs {
+			want := fmt.Sprintf("r%d %d [] []", i, i)
+			if i%2 == 0 {
+				want = fmt.Sprintf("r%d %d [v%d] []", i, i, i)
+			}
+			assert.Equal(t, want, got)
+		}
+	})
+	t.Run("parse after invocation", func(t *testing.T) {
+		t.Parallel()
+		root, region := newRoot()
+		_, err := ParseInvocation(root, []string{"--region=eu-west-1", "deploy", "--tag=v1"})
+		require.NoError(t, err)
+		require.NoError(t, Parse(root, []string{"deploy"}))
+		require.Equal(t, "us-east-1", *region)
+		require.Empty(t, GetFlag[[]string](root.state, "tag"))
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		root, _ := newRoot()
+		_, err := ParseInvocation(root, []string{"deploy", "--unknown"})
+		require.ErrorContains(t, err, "flag provided but not defined")
+		_, err = ParseInvocation(nil, nil)
+		require.ErrorContains(t, err, "root command is nil")
+	})
+}
+
+func TestParseOnly(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "us-east-1", "cloud region")
+		}),
+		SubCommands: []*Command{
+			{
+				Name: "deploy",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Int("replicas", 1, "number of replicas")
+				}),
+			},
+		},
+	}
+	_, err := ParseInvocation(root, []string{"deploy"})
+	require.ErrorContains(t, err, `command "app deploy": no exec function defined`)
+
+	inv, err := ParseOnly(root, []string{"--region=eu-west-1", "deploy", "--replicas=3", "web"})
+	require.NoError(t, err)
+	require.Equal(t, "deploy", inv.Command().Name)
+	require.Equal(t, []string{"web"}, inv.Args())
+	s := inv.State()
+	require.Equal(t, "eu-west-1", GetFlag[string](s, "region"))
+	require.Equal(t, 3, GetFlag[int](s, "replicas"))
+	require.Equal(t, map[string]FlagSource{"region": FlagSourceCommandLine, "replicas": FlagSourceCommandLine}, s.FlagSources())
+	require.Nil(t, root.state)
+
+	err = inv.Run(context.Background(), nil)
+	require.EqualError(t, err, `command "app deploy": no exec function defined`)
+
+	_, err = ParseOnly(root, []string{"deploy", "--replicas=x"})
+	require.ErrorContains(t, err, `invalid value "x" for flag -replicas`)
+}
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "us-east-1", "cloud region")
+		}),
+		SubCommands: []*Command{
+			{
+				Name: "deploy",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Int("replicas", 1, "number of replicas")
+				}),
+			},
+		},
+	}
+
+	t.Run("terminal command and state", func(t *testing.T) {
+		t.Parallel()
+		cmd, s, err := Resolve(root, []string{"--region=eu-west-1", "deploy", "--replicas=3", "web"})
+		require.NoError(t, err)
+		require.Same(t, root.SubCommands[0], cmd)
+		require.Equal(t, []string{"web"}, s.Args)
+		require.Equal(t, "eu-west-1", GetFlag[string](s, "region"))
+		require.Equal(t, 3, GetFlag[int](s, "replicas"))
+		require.Nil(t, root.state)
+	})
+	t.Run("root", func(t *testing.T) {
+		t.Parallel()
+		cmd, s, err := Resolve(root, nil)
+		require.NoError(t, err)
+		require.Same(t, root, cmd)
+		require.Equal(t, "us-east-1", GetFlag[string](s, "region"))
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		cmd, s, err := Resolve(root, []string{"deploi"})
+		require.Error(t, err)
+		require.Nil(t, cmd)
+		require.Nil(t, s)
+	})
+}
+
+func TestRunArgs(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "echo",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("prefix", "", "prefix to print")
+		}),
+		Exec: func(ctx context.Context, s *State) error {
+			fmt.Fprint(s.Stdout, GetFlag[string](s, "prefix"), s.Args)
+			return nil
+		},
+	}
+	const n = 20
+	var wg sync.WaitGroup
+	outputs := make([]bytes.Buffer, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			args := []string{"--prefix", fmt.Sprint(i), fmt.Sprint("arg", i)}
+			assert.NoError(t, RunArgs(context.Background(), root, args, &RunOptions{Stdout: &outputs[i]}))
+		}(i)
+	}
+	wg.Wait()
+	for i := range outputs {
+		assert.Equal(t, fmt.Sprintf("%d[arg%d]", i, i), outputs[i].String())
+	}
+
+	err := RunArgs(context.Background(), root, []string{"--unknown"}, nil)
+	require.ErrorContains(t, err, "flag provided but not defined")
+}
+
+func TestRunResult(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		SubCommands: []*Command{
+			{
+				Name: "count",
+				Exec: func(ctx context.Context, s *State) error {
+					s.SetResult(len(s.Args))
+					return nil
+				},
+			},
+			{
+				Name: "noop",
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			},
+			{
+				Name: "fail",
+				Exec: func(ctx context.Context, s *State) error {
+					s.SetResult(1)
+					return errors.New("boom")
+				},
+			},
+		},
+	}
+	ctx := context.Background()
+
+	n, err := RunResult[int](ctx, root, []string{"count", "a", "b"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, err = RunResult[string](ctx, root, []string{"count"}, nil)
+	require.EqualError(t, err, `command "app count": result is of type int, not string`)
+
+	_, err = RunResult[int](ctx, root, []string{"noop"}, nil)
+	require.EqualError(t, err, `command "app noop" did not record a result`)
+
+	_, err = RunResult[int](ctx, root, []string{"fail"}, nil)
+	require.EqualError(t, err, "boom")
+
+	_, err = RunResult[int](ctx, root, []string{"unknown"}, nil)
+	var unknownErr *UnknownCommandError
+	require.ErrorAs(t, err, &unknownErr)
+}
@@ -0,0 +1,382 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Messages is a catalog of the text produced by the framework, such as help text headings and
+// parse errors, so a localized CLI can translate it. Empty fields use the English defaults shown
+// in their documentation. Text defined by the command tree, like usage strings, and errors
+// returned by the flag package, like "flag provided but not defined", are not covered.
+//
+// Messages that depend on a number of items receive the items, so a translation can choose the
+// grammatical number and join them the way its language does, e.g., "-a, -b y -c". A translation
+// that keeps such a default message can still set List and Alternatives to join its items, and
+// Suggestions to choose the grammatical number of the suggestions for a mistyped name.
+//
+//	options := &cli.Options{
+//	    Messages: &cli.Messages{
+//	        Usage:             "Uso:",
+//	        AvailableCommands: "Comandos disponibles:",
+//	        UnknownCommand:    func(name string) string { return fmt.Sprintf("comando desconocido %q", name) },
+//	    },
+//	}
+type Messages struct {
+	// Usage is the heading of the usage pattern in help text, "Usage:".
+	Usage string
+	// AvailableCommands is the heading of the list of subcommands, "Available Commands:".
+	AvailableCommands string
+	// Flags is the heading of the flags of the command, "Flags:".
+	Flags string
+	// GlobalFlags is the heading of the flags inherited from parent commands, "Global Flags:".
+	GlobalFlags string
+	// Environment is the heading of the documented environment variables, "Environment:".
+	Environment string
+	// SeeAlso is the heading of related commands, "See Also:".
+	SeeAlso string
+	// Destructive annotates destructive commands in the list of subcommands, "(destructive)".
+	Destructive string
+	// CommandGroups is the heading of the summary of command groups shown instead of their
+	// commands, see [UsageOptions.MaxCommands], "Command Groups:".
+	CommandGroups string
+	// AvailablePlugins is the heading of the plugins listed in the help text of the root command,
+	// see [Options.Plugins], "Available Plugins:".
+	AvailablePlugins string
+	// NoPreviousLine is the error for "!!" entered as the first line of an [Interactive] session,
+	// "no previous line".
+	NoPreviousLine string
+
+	// GroupHeading returns the heading of the commands of a [Command.Group], "GROUP Commands:".
+	GroupHeading func(group string) string
+	// GroupCount returns the number of commands in a group in the summary of command groups, "1
+	// command" or "N commands".
+	GroupCount func(n int) string
+	// GroupHelp returns the hint following a summary of command groups, given the path of the
+	// [Options.HelpCommand] for the command, e.g., `Use "app help <group>" to list the commands of
+	// a group, or "app help --all" to list all commands.`
+	GroupHelp func(helpPath string) string
+
+	// MoreHelp returns the hint closing the help text of a command with subcommands, `Use "app
+	// [command] --help" for more information about a command.`
+	MoreHelp func(cmdPath string) string
+	// FlagDefault returns the annotation of a flag's default value, "(default: VALUE)".
+	FlagDefault func(value string) string
+	// FlagEnv returns the annotation of a flag's environment variable, "(env: NAME)".
+	FlagEnv func(name string) string
+	// FlagChoices returns the annotation of the allowed values of a flag defined with [Choice],
+	// "(one of: a, b, c)".
+	FlagChoices func(choices []string) string
+	// FlagRequires returns the annotation of the flags a flag requires, see
+	// [FlagMetadata.Requires], given their names with their leading dash, "(requires: -a, -b)".
+	FlagRequires func(flags []string) string
+	// FlagOrigin returns the annotation of the command defining a flag, see
+	// [UsageOptions.ShowFlagOrigin], `(from "app db")`.
+	FlagOrigin func(command string) string
+
+	// List joins items that all apply in the default messages, e.g., the flags a flag requires,
+	// "a, b, c". A translation can set it to "a, b y c".
+	List func(items []string) string
+	// Alternatives joins items of which one applies in the default messages, e.g., the allowed
+	// values of a flag, "a, b, c". A translation can set it to "a, b o c".
+	Alternatives func(items []string) string
+
+	// UnknownCommand returns the error message for an unknown subcommand, `unknown command "NAME"`.
+	UnknownCommand func(name string) string
+	// AmbiguousCommand returns the error message for an abbreviated subcommand name matching
+	// several subcommands, see [Options.PrefixMatching], which is followed by their names,
+	// `ambiguous command "NAME", could be one of:`.
+	AmbiguousCommand func(name string) string
+	// DidYouMean introduces suggestions for a mistyped command or flag, "Did you mean one of
+	// these?".
+	DidYouMean string
+	// Suggestions returns the introduction of n suggestions for a mistyped command or flag, so a
+	// translation can choose the grammatical number. It takes precedence over DidYouMean.
+	Suggestions func(n int) string
+	// UnexpectedArgs returns the error message for positional arguments given to a command that
+	// only groups subcommands, see [Options.StrictArgs], `unexpected arguments "a b", expected a
+	// subcommand`.
+	UnexpectedArgs func(args []string) string
+	// MissingFlags returns the error message for required flags that are not set, given the path of
+	// the command and the names of the flags with their leading dash, e.g., `command "app deploy":
+	// required flags "-region, -token" not set`.
+	MissingFlags func(command string, flags []string) string
+	// MissingRequires returns the error message for a flag that is set without the flags it
+	// requires, see [FlagMetadata.Requires], given the path of the command and the names of the
+	// flags with their leading dash, e.g., `command "app serve": flag -tls-cert requires -tls-key`.
+	MissingRequires func(command, flag string, missing []string) string
+
+	// UnusedFlag returns the warning for a flag of a parent command that was set but not used by
+	// the command, see [RunOptions.UnusedParentFlags], given the name of the flag with its leading
+	// dash, e.g., `flag -verbose was set but not used by command "app deploy"`.
+	UnusedFlag func(command, flag string) string
+	// UnusedFlags returns the error message for flags of parent commands that were set but not used
+	// by the command, given the names of the flags with their leading dash, e.g., `command "app
+	// deploy": flags "-verbose", "-region" set but not used`.
+	UnusedFlags func(command string, flags []string) string
+	// ConfirmPrompt returns the question asking the user to confirm running a destructive command,
+	// see [Command.Destructive], `Command "app db drop" is destructive. Continue?`.
+	ConfirmPrompt func(command string) string
+	// ConfirmRequired returns the error message for a command that needs confirmation when the
+	// user cannot be asked, given the name of the flag confirming it with its leading dash, e.g.,
+	// `command "app db drop" is destructive: confirm with -yes` for a destructive command and
+	// `command "app deploy" requires confirmation: confirm with -yes` otherwise.
+	ConfirmRequired func(command, flag string, destructive bool) string
+	// Aborted returns the error message for a command the user declined to run, `command "app db
+	// drop": aborted`.
+	Aborted func(command string) string
+}
+
+// The methods of Messages return the message, or its default if it is not set. They may be called
+// on a nil *Messages.
+
+func (m *Messages) usage() string {
+	if m == nil || m.Usage == "" {
+		return "Usage:"
+	}
+	return m.Usage
+}
+
+func (m *Messages) availableCommands() string {
+	if m == nil || m.AvailableCommands == "" {
+		return "Available Commands:"
+	}
+	return m.AvailableCommands
+}
+
+func (m *Messages) flags() string {
+	if m == nil || m.Flags == "" {
+		return "Flags:"
+	}
+	return m.Flags
+}
+
+func (m *Messages) globalFlags() string {
+	if m == nil || m.GlobalFlags == "" {
+		return "Global Flags:"
+	}
+	return m.GlobalFlags
+}
+
+func (m *Messages) environment() string {
+	if m == nil || m.Environment == "" {
+		return "Environment:"
+	}
+	return m.Environment
+}
+
+func (m *Messages) seeAlso() string {
+	if m == nil || m.SeeAlso == "" {
+		return "See Also:"
+	}
+	return m.SeeAlso
+}
+
+func (m *Messages) destructive() string {
+	if m == nil || m.Destructive == "" {
+		return "(destructive)"
+	}
+	return m.Destructive
+}
+
+func (m *Messages) commandGroups() string {
+	if m == nil || m.CommandGroups == "" {
+		return "Command Groups:"
+	}
+	return m.CommandGroups
+}
+
+func (m *Messages) availablePlugins() string {
+	if m == nil || m.AvailablePlugins == "" {
+		return "Available Plugins:"
+	}
+	return m.AvailablePlugins
+}
+
+func (m *Messages) noPreviousLine() string {
+	if m == nil || m.NoPreviousLine == "" {
+		return "no previous line"
+	}
+	return m.NoPreviousLine
+}
+
+func (m *Messages) groupHeading(group string) string {
+	if m == nil || m.GroupHeading == nil {
+		return group + " Commands:"
+	}
+	return m.GroupHeading(group)
+}
+
+func (m *Messages) groupCount(n int) string {
+	if m == nil || m.GroupCount == nil {
+		if n == 1 {
+			return "1 command"
+		}
+		return fmt.Sprintf("%d commands", n)
+	}
+	return m.GroupCount(n)
+}
+
+func (m *Messages) groupHelp(helpPath string) string {
+	if m == nil || m.GroupHelp == nil {
+		return fmt.Sprintf("Use \"%s <group>\" to list the commands of a group, or \"%s --all\" to list all commands.", helpPath, helpPath)
+	}
+	return m.GroupHelp(helpPath)
+}
+
+func (m *Messages) moreHelp(cmdPath string) string {
+	if m == nil || m.MoreHelp == nil {
+		return fmt.Sprintf("Use \"%s [command] --help\" for more information about a command.", cmdPath)
+	}
+	return m.MoreHelp(cmdPath)
+}
+
+func (m *Messages) flagDefault(value string) string {
+	if m == nil || m.FlagDefault == nil {
+		return fmt.Sprintf("(default: %s)", value)
+	}
+	return m.FlagDefault(value)
+}
+
+func (m *Messages) flagEnv(name string) string {
+	if m == nil || m.FlagEnv == nil {
+		return fmt.Sprintf("(env: %s)", name)
+	}
+	return m.FlagEnv(name)
+}
+
+func (m *Messages) flagChoices(choices []string) string {
+	if m == nil || m.FlagChoices == nil {
+		return fmt.Sprintf("(one of: %s)", m.alternatives(choices))
+	}
+	return m.FlagChoices(choices)
+}
+
+func (m *Messages) flagRequires(flags []string) string {
+	if m == nil || m.FlagRequires == nil {
+		return fmt.Sprintf("(requires: %s)", m.list(flags))
+	}
+	return m.FlagRequires(flags)
+}
+
+func (m *Messages) flagOrigin(command string) string {
+	if m == nil || m.FlagOrigin == nil {
+		return fmt.Sprintf("(from %q)", command)
+	}
+	return m.FlagOrigin(command)
+}
+
+func (m *Messages) unknownCommand(name string) string {
+	if m == nil || m.UnknownCommand == nil {
+		return fmt.Sprintf("unknown command %q", name)
+	}
+	return m.UnknownCommand(name)
+}
+
+func (m *Messages) ambiguousCommand(name string) string {
+	if m == nil || m.AmbiguousCommand == nil {
+		return fmt.Sprintf("ambiguous command %q, could be one of:", name)
+	}
+	return m.AmbiguousCommand(name)
+}
+
+func (m *Messages) didYouMean(n int) string {
+	switch {
+	case m != nil && m.Suggestions != nil:
+		return m.Suggestions(n)
+	case m != nil && m.DidYouMean != "":
+		return m.DidYouMean
+	default:
+		return "Did you mean one of these?"
+	}
+}
+
+func (m *Messages) unexpectedArgs(args []string) string {
+	if m == nil || m.UnexpectedArgs == nil {
+		msg := "unexpected argument"
+		if len(args) > 1 {
+			msg += "s"
+		}
+		return fmt.Sprintf("%s %q, expected a subcommand", msg, strings.Join(args, " "))
+	}
+	return m.UnexpectedArgs(args)
+}
+
+func (m *Messages) missingFlags(command string, flags []string) string {
+	if m == nil || m.MissingFlags == nil {
+		msg := "required flag"
+		if len(flags) > 1 {
+			msg += "s"
+		}
+		return fmt.Sprintf("command %q: %s %q not set", command, msg, m.list(flags))
+	}
+	return m.MissingFlags(command, flags)
+}
+
+func (m *Messages) missingRequires(command, flag string, missing []string) string {
+	if m == nil || m.MissingRequires == nil {
+		return fmt.Sprintf("command %q: flag %s requires %s", command, flag, m.list(missing))
+	}
+	return m.MissingRequires(command, flag, missing)
+}
+
+func (m *Messages) unusedFlag(command, flag string) string {
+	if m == nil || m.UnusedFlag == nil {
+		return fmt.Sprintf("flag %s was set but not used by command %q", flag, command)
+	}
+	return m.UnusedFlag(command, flag)
+}
+
+func (m *Messages) unusedFlags(command string, flags []string) string {
+	if m == nil || m.UnusedFlags == nil {
+		msg := "flag"
+		if len(flags) > 1 {
+			msg += "s"
+		}
+		quoted := make([]string, len(flags))
+		for i, f := range flags {
+			quoted[i] = strconv.Quote(f)
+		}
+		return fmt.Sprintf("command %q: %s %s set but not used", command, msg, m.list(quoted))
+	}
+	return m.UnusedFlags(command, flags)
+}
+
+func (m *Messages) confirmPrompt(command string) string {
+	if m == nil || m.ConfirmPrompt == nil {
+		return fmt.Sprintf("Command %q is destructive. Continue?", command)
+	}
+	return m.ConfirmPrompt(command)
+}
+
+func (m *Messages) confirmRequired(command, flag string, destructive bool) string {
+	if m == nil || m.ConfirmRequired == nil {
+		if destructive {
+			return fmt.Sprintf("command %q is destructive: confirm with %s", command, flag)
+		}
+		return fmt.Sprintf("command %q requires confirmation: confirm with %s", command, flag)
+	}
+	return m.ConfirmRequired(command, flag, destructive)
+}
+
+func (m *Messages) aborted(command string) string {
+	if m == nil || m.Aborted == nil {
+		return fmt.Sprintf("command %q: aborted", command)
+	}
+	return m.Aborted(command)
+}
+
+func (m *Messages) list(items []string) string {
+	if m == nil || m.List == nil {
+		return strings.Join(items, ", ")
+	}
+	return m.List(items)
+}
+
+func (m *Messages) alternatives(items []string) string {
+	if m == nil || m.Alternatives == nil {
+		return strings.Join(items, ", ")
+	}
+	return m.Alternatives(items)
+}
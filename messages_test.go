@@ -0,0 +1,185 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessages(t *testing.T) {
+	t.Parallel()
+
+	spanish := &Messages{
+		Usage:             "Uso:",
+		AvailableCommands: "Comandos disponibles:",
+		Flags:             "Opciones:",
+		GlobalFlags:       "Opciones globales:",
+		Destructive:       "(destructivo)",
+		MoreHelp: func(cmdPath string) string {
+			return fmt.Sprintf("Use \"%s [comando] --help\" para más información.", cmdPath)
+		},
+		FlagDefault: func(value string) string { return "(por defecto: " + value + ")" },
+		FlagEnv:     func(name string) string { return "(entorno: " + name + ")" },
+		FlagChoices: func(choices []string) string { return "(uno de: " + joinList(choices, "o") + ")" },
+		UnknownCommand: func(name string) string {
+			return fmt.Sprintf("comando desconocido %q", name)
+		},
+		Suggestions: func(n int) string {
+			if n == 1 {
+				return "¿Quiso decir esto?"
+			}
+			return "¿Quiso decir alguno de estos?"
+		},
+		AmbiguousCommand: func(name string) string {
+			return fmt.Sprintf("comando ambiguo %q, podría ser:", name)
+		},
+		ConfirmRequired: func(command, flag string, destructive bool) string {
+			return fmt.Sprintf("el comando %q es destructivo: confirme con %s", command, flag)
+		},
+		MissingFlags: func(command string, flags []string) string {
+			if len(flags) == 1 {
+				return fmt.Sprintf("comando %q: falta la opción obligatoria %s", command, flags[0])
+			}
+			return fmt.Sprintf("comando %q: faltan las opciones obligatorias %s", command, joinList(flags, "y"))
+		},
+	}
+	newRoot := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("region", "eu", "region")
+			}),
+			FlagsMetadata: []FlagMetadata{{Name: "region", EnvVar: "APP_REGION"}},
+			SubCommands: []*Command{
+				{
+					Name: "deploy",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("token", "", "token")
+						f.String("env", "", "environment")
+						Choice(f, "mode", []string{"fast", "safe", "slow"}, "safe", "mode")
+					}),
+					FlagsMetadata: []FlagMetadata{
+						{Name: "token", Required: true},
+						{Name: "env", Required: true},
+					},
+					Exec: exec,
+				},
+				{Name: "destroy", Destructive: true, Exec: exec},
+			},
+		}
+	}
+	run := func(t *testing.T, args ...string) (stdout, stderr string, err error) {
+		t.Helper()
+		var outBuf, errBuf bytes.Buffer
+		err = ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:            args,
+			RunOptions:      RunOptions{Stdin: strings.NewReader(""), Stdout: &outBuf, Stderr: &errBuf},
+			LookupEnv:       func(string) (string, bool) { return "", false },
+			Messages:        spanish,
+			SkipHelpOnError: true,
+		})
+		return outBuf.String(), errBuf.String(), err
+	}
+
+	t.Run("usage", func(t *testing.T) {
+		t.Parallel()
+		stdout, _, err := run(t, "--help")
+		require.NoError(t, err)
+		for _, want := range []string{
+			"Uso:\n  app [flags] <command>",
+			"Comandos disponibles:",
+			"(destructivo)",
+			"Opciones:",
+			"(por defecto: eu) (entorno: APP_REGION)",
+			`Use "app [comando] --help" para más información.`,
+		} {
+			require.Contains(t, stdout, want)
+		}
+
+		stdout, _, err = run(t, "deploy", "--help")
+		require.NoError(t, err)
+		require.Contains(t, stdout, "Opciones globales:")
+		require.Contains(t, stdout, "(uno de: fast, safe o slow)")
+	})
+	t.Run("unknown command", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, "deploi")
+		require.Error(t, err)
+		require.Equal(t, "error: comando desconocido \"deploi\". ¿Quiso decir esto?\n\tdeploy\n", stderr)
+	})
+	t.Run("missing flags", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, "deploy")
+		require.Error(t, err)
+		require.Equal(t, "error: comando \"app deploy\": faltan las opciones obligatorias -token y -env\n", stderr)
+
+		_, stderr, err = run(t, "deploy", "--token=x")
+		require.Error(t, err)
+		require.Equal(t, "error: comando \"app deploy\": falta la opción obligatoria -env\n", stderr)
+	})
+	t.Run("flag suggestions", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, "deploy", "--tokn=x")
+		require.Error(t, err)
+		require.Contains(t, stderr, "¿Quiso decir esto?\n\t--token")
+	})
+	t.Run("run messages", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, "destroy")
+		require.Error(t, err)
+		require.Equal(t, "error: el comando \"app destroy\" es destructivo: confirme con -yes\n", stderr)
+
+		err = parse(newRoot(), []string{"de"}, &parseConfig{prefixMatch: true, messages: spanish})
+		require.EqualError(t, err, "comando ambiguo \"de\", podría ser:\n\tdeploy\n\tdestroy")
+	})
+	t.Run("list hooks", func(t *testing.T) {
+		t.Parallel()
+		m := &Messages{
+			List:         func(items []string) string { return joinList(items, "y") },
+			Alternatives: func(items []string) string { return joinList(items, "o") },
+		}
+		require.Equal(t, "(requires: -a, -b y -c)", m.flagRequires([]string{"-a", "-b", "-c"}))
+		require.Equal(t, "(one of: a o b)", m.flagChoices([]string{"a", "b"}))
+		require.Equal(t, `command "app": required flags "-a y -b" not set`, m.missingFlags("app", []string{"-a", "-b"}))
+	})
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		var m *Messages
+		require.Equal(t, "Usage:", m.usage())
+		require.Equal(t, `unknown command "x"`, m.unknownCommand("x"))
+		require.Equal(t, `command "app": required flags "-a, -b" not set`, m.missingFlags("app", []string{"-a", "-b"}))
+		require.Equal(t, `command "app": flag -a requires -b`, m.missingRequires("app", "-a", []string{"-b"}))
+		require.Equal(t, "(requires: -a, -b)", m.flagRequires([]string{"-a", "-b"}))
+		require.Equal(t, "(one of: a, b, c)", m.flagChoices([]string{"a", "b", "c"}))
+		require.Equal(t, "Did you mean one of these?", m.didYouMean(1))
+		require.Equal(t, "Did you mean one of these?", m.didYouMean(2))
+		require.Equal(t, "Quiso decir", (&Messages{DidYouMean: "Quiso decir"}).didYouMean(1))
+		require.Equal(t, `command "app": flags "-a", "-b" set but not used`, m.unusedFlags("app", []string{"-a", "-b"}))
+		require.Equal(t, `command "app": flags "-a" y "-b" set but not used`,
+			(&Messages{List: func(items []string) string { return joinList(items, "y") }}).unusedFlags("app", []string{"-a", "-b"}))
+		require.Equal(t, `Command "app" is destructive. Continue?`, m.confirmPrompt("app"))
+		require.Equal(t, `command "app" requires confirmation: confirm with -yes`, m.confirmRequired("app", "-yes", false))
+		require.Equal(t, `command "app": aborted`, m.aborted("app"))
+		require.Equal(t, `(from "app db")`, m.flagOrigin("app db"))
+		require.Equal(t, "Available Plugins:", m.availablePlugins())
+		require.Equal(t, "no previous line", m.noPreviousLine())
+		require.Equal(t, "Flags:", (&Messages{}).flags())
+		require.Equal(t, "Storage Commands:", m.groupHeading("Storage"))
+		require.Equal(t, "1 command", m.groupCount(1))
+		require.Equal(t, "3 commands", m.groupCount(3))
+	})
+}
+
+// joinList joins items as a list ending with the conjunction, e.g., "a, b y c".
+func joinList(items []string, conjunction string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " " + conjunction + " " + items[len(items)-1]
+}
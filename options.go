@@ -0,0 +1,512 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfridman/cli/pkg/suggest"
+)
+
+// Options configures [ParseAndRun], which handles the full lifecycle of a command invocation:
+// parsing, help, execution, error reporting and exit code mapping.
+type Options struct {
+	// RunOptions configures how the command is executed, see [RunOptions].
+	RunOptions
+
+	// Args are the arguments to parse. If nil, os.Args[1:] is used.
+	Args []string
+	// Isolated parses the arguments like [ParseInvocation] instead of [Parse]: the parse result is
+	// not stored in the command tree, so the same tree can be run again with other arguments, e.g.,
+	// by tests. Flags must then be read with [GetFlag] or [LookupFlag] rather than through the
+	// variables they are bound to.
+	Isolated bool
+	// ArgsFromStdin enables the [ArgsFromStdinFlag] sentinel argument. When present, it is replaced
+	// with newline-delimited arguments read from Stdin, for invocations generated by other tools
+	// that exceed OS argument length limits. Empty lines are ignored. The sentinel is not
+	// recognized after the "--" delimiter.
+	ArgsFromStdin bool
+	// ArgFiles enables response files: an argument of the form "@FILE" is replaced with the
+	// arguments read from FILE, one per line, for build systems that generate command lines
+	// exceeding OS argument length limits. Empty lines and lines starting with "#" are ignored, and
+	// arguments read from a file are not expanded again. Arguments after the "--" delimiter are not
+	// expanded.
+	ArgFiles bool
+	// Plugins enables external subcommands, like git and kubectl plugins: if the first argument is
+	// not a subcommand of the root command "app", but an executable "app-<arg>" is found on PATH, it
+	// is run with the remaining arguments and the standard streams instead, and its exit code is
+	// reported. Discovered plugins are listed in the root command's help text. See [ListPlugins].
+	Plugins bool
+	// ExplainFlags enables the hidden [ExplainFlagsFlag] argument. When present, the arguments are
+	// parsed as usual, but instead of running the command, a table of all flags with their final
+	// value and where it came from (see [State.FlagSources]) is written to Stdout. The flag is not
+	// shown in help text and is not recognized after the "--" delimiter.
+	ExplainFlags bool
+	// DebugFlag enables the hidden [DebugFlagName] argument, which turns on [RunOptions.Debug] for
+	// the invocation, so users can see how their command line was parsed and run, e.g., where the
+	// value of a flag came from. The flag is not shown in help text, is not recognized after the
+	// "--" delimiter, and is left to the application if any command defines a "debug" flag.
+	DebugFlag bool
+	// DocsCommand enables the hidden [DocsCommandName] subcommand of the root command, so end users
+	// and packagers can generate documentation from the installed binary, e.g., "app docs --format
+	// man --dir ./man". It writes the output of [GenerateDocs] to Stdout, or to a file named after
+	// the root command in the given directory. With "--package DIR" it writes the files of
+	// [WritePackageFiles] instead, and with "--packager NAME" the output of [GeneratePackagingHints].
+	// The subcommand is not shown in help text, and is ignored if the root command defines a
+	// subcommand with the same name.
+	DocsCommand bool
+	// HelpCommand enables the hidden [HelpCommandName] subcommand of the root command, to navigate
+	// the help text of large command trees: "app help db migrate" shows the help text of "app db
+	// migrate", "app help Storage" lists the commands of the [Command.Group] "Storage" of the root
+	// command, and "app help --all" lists all subcommands, even if [UsageOptions.MaxCommands]
+	// summarizes them by group. The subcommand is not shown in help text, and is ignored if the root
+	// command defines a subcommand with the same name.
+	HelpCommand bool
+
+	// LookupEnv looks up the environment variables flags are bound to with [FlagMetadata.EnvVar].
+	// Defaults to [os.LookupEnv].
+	LookupEnv func(key string) (string, bool)
+	// ConfigSources are consulted in order for flags that are not set on the command line or
+	// through their environment variable.
+	ConfigSources []ConfigSource
+
+	// HelpFlags are the names of the flags, without dashes, that request help. Defaults to "h" and
+	// "help". A help flag is ignored if a command in the hierarchy defines a flag with the same
+	// name, or if the terminal command sets [Command.DisableHelpFlag].
+	HelpFlags []string
+
+	// NormalizeFlag, if set, maps the name of every flag argument to the name of a defined flag
+	// before it is looked up, e.g., for case-insensitive flags or to accept "--dry_run" for
+	// "--dry-run". It receives the name without leading dashes. Arguments starting with "/" are
+	// passed including the slash, so Windows-style "/verbose" flags can be supported by trimming it.
+	// If the returned name is not a defined flag, the argument is left as is.
+	NormalizeFlag func(name string) string
+
+	// PrefixMatching resolves an abbreviated subcommand name to the only subcommand starting with
+	// it, e.g., "app stat" to "app status". If several subcommands share the prefix, parsing fails
+	// with an error listing them. An exact match always takes precedence.
+	PrefixMatching bool
+
+	// StrictArgs rejects positional arguments given to commands with subcommands, which usually
+	// only exist to group them, e.g., "app nested -- bogus", instead of passing them to Exec in
+	// [State.Args]. Words before the "--" delimiter that are not subcommands are always rejected
+	// as unknown commands.
+	StrictArgs bool
+
+	// PromptMissing prompts the user for the values of required flags that are not set, instead of
+	// failing, when Stdin is a terminal. Prompts are written to Stderr, and values of flags marked
+	// [FlagMetadata.Secret] are not echoed. An empty answer leaves the flag unset.
+	PromptMissing bool
+
+	// Tokenizer, if set, rewrites the arguments before they are parsed, to support alternate
+	// syntaxes. See [Tokenizer].
+	Tokenizer Tokenizer
+
+	// Suggest configures the "did you mean" suggestions for mistyped commands and flags. If nil, the
+	// defaults of [suggest.Config] are used.
+	Suggest *suggest.Config
+
+	// ErrorWriter is where errors are written. Defaults to Stderr.
+	ErrorWriter io.Writer
+	// ExitCode maps an error to a process exit code, which is returned as part of an [ExitError].
+	// It receives the error as returned by [ParseAndRun] with the default exit code already
+	// applied: 2 for parse errors, the code of an [ExitError] returned by Exec, 130 for errors
+	// wrapping [ErrInterrupted], and 1 otherwise.
+	ExitCode func(err error) int
+	// ErrorHandler, if set, presents errors instead of ParseAndRun writing them to ErrorWriter, so
+	// applications can format errors centrally, e.g., with color or the command path. It receives
+	// the error as an [*ExitError] holding the exit code ParseAndRun would use (see ExitCode) and
+	// returns the exit code to report. The state is nil for errors that occur before parsing.
+	ErrorHandler func(err error, s *State) int
+	// SkipHelpOnError disables writing the command's usage text to ErrorWriter after a parse error.
+	SkipHelpOnError bool
+	// UsageOptions configures how usage text is rendered, see [FormatUsage]. It applies to all
+	// usage text: the text shown for the help flags, after parse errors, for [ShowHelp] and by
+	// [State.Usage].
+	UsageOptions *UsageOptions
+	// Messages translates the text produced by the framework, such as help text headings and parse
+	// errors, see [Messages]. It is also used for usage text unless UsageOptions sets its own.
+	Messages *Messages
+
+	// OnResult is called with the result recorded by Exec with [State.SetResult], after the command
+	// ran successfully. It is not called if Exec did not record a result.
+	OnResult func(result any)
+}
+
+// ArgsFromStdinFlag is the sentinel argument replaced with arguments read from Stdin, see
+// [Options.ArgsFromStdin].
+const ArgsFromStdinFlag = "--args-stdin"
+
+// ExplainFlagsFlag is the hidden argument that prints where flag values came from instead of
+// running the command, see [Options.ExplainFlags].
+const ExplainFlagsFlag = "--explain-flags"
+
+// ConfigSource provides flag values from a source other than the command line, such as a
+// configuration file.
+type ConfigSource interface {
+	// Lookup returns the value for the named flag defined by cmd, and whether a value was found.
+	// The value is parsed the same way as a command line value.
+	Lookup(cmd *Command, name string) (string, bool)
+}
+
+// ConfigSourceFunc is an adapter to allow the use of ordinary functions as a [ConfigSource].
+type ConfigSourceFunc func(cmd *Command, name string) (string, bool)
+
+// Lookup calls f(cmd, name).
+func (f ConfigSourceFunc) Lookup(cmd *Command, name string) (string, bool) {
+	return f(cmd, name)
+}
+
+// ExitError is an error carrying a process exit code. Exec functions may return an ExitError to
+// control the exit code reported by [ParseAndRun].
+type ExitError struct {
+	// Code is the process exit code.
+	Code int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ParseAndRun parses the arguments and runs the terminal command, handling the full lifecycle of a
+// command invocation. If help is requested, the usage text is written to Stdout and nil is
+// returned. Otherwise, any error is written to the error writer and returned as an [*ExitError]
+// holding the exit code, so a main function reduces to:
+//
+//	if err := cli.ParseAndRun(ctx, root, nil); err != nil {
+//	    os.Exit(err.(*cli.ExitError).Code)
+//	}
+//
+// The options parameter may be nil, in which case default values are used. See [Options] for more
+// details.
+func ParseAndRun(ctx context.Context, root *Command, options *Options) error {
+	if options == nil {
+		options = &Options{}
+	}
+	runOptions := checkAndSetRunOptions(&options.RunOptions)
+	args := options.Args
+	if args == nil {
+		args = os.Args[1:]
+	}
+	errWriter := options.ErrorWriter
+	if errWriter == nil {
+		errWriter = runOptions.Stderr
+	}
+	if options.ArgsFromStdin {
+		var err error
+		if args, err = expandArgsFromStdin(args, runOptions.Stdin); err != nil {
+			return reportError(errWriter, err, 2, nil, options)
+		}
+	}
+	if options.ArgFiles {
+		var err error
+		if args, err = expandArgFiles(args); err != nil {
+			return reportError(errWriter, err, 2, nil, options)
+		}
+	}
+	if options.Plugins && root != nil {
+		if path, ok := lookupPlugin(root, args); ok {
+			if err := runPlugin(ctx, path, args[1:], runOptions); err != nil {
+				var exitErr *ExitError
+				if errors.As(err, &exitErr) {
+					// The plugin reported the error itself.
+					return exitError(err, exitErr.Code, options)
+				}
+				return reportError(errWriter, err, 1, nil, options)
+			}
+			return nil
+		}
+	}
+	if options.DocsCommand && root != nil && len(args) > 0 && args[0] == DocsCommandName &&
+		root.findSubCommand(DocsCommandName) == nil {
+		docsOptions := *options
+		docsOptions.Args = args
+		docsOptions.DocsCommand = false
+		docsOptions.Plugins = false
+		return ParseAndRun(ctx, newDocsCommand(root), &docsOptions)
+	}
+	if options.HelpCommand && root != nil && len(args) > 0 && args[0] == HelpCommandName &&
+		root.findSubCommand(HelpCommandName) == nil {
+		helpOptions := *options
+		helpOptions.Args = args
+		helpOptions.HelpCommand = false
+		helpOptions.DocsCommand = false
+		helpOptions.Plugins = false
+		return ParseAndRun(ctx, newHelpCommand(root, options), &helpOptions)
+	}
+	var explain bool
+	if options.ExplainFlags {
+		args, explain = removeArg(args, ExplainFlagsFlag)
+	}
+	var debug bool
+	if options.DebugFlag && root != nil && lookupTreeFlag(root, strings.TrimLeft(DebugFlagName, "-")) == nil {
+		args, debug = removeArg(args, DebugFlagName)
+	}
+	if debug || runOptions.Debug {
+		// Copy the options, which are shared with the caller.
+		debugOptions := *runOptions
+		debugOptions.Debug = true
+		debugOptions.debugLog = newDebugLog(debugOptions.Stderr)
+		runOptions = &debugOptions
+		if root != nil {
+			runOptions.debugLog.printf("arguments: %s", strings.Join(maskSecretArgs(args, treeSecretFlags(root, nil)), " "))
+		}
+	}
+	cfg := &parseConfig{
+		lookupEnv:     options.LookupEnv,
+		configSources: options.ConfigSources,
+		suggest:       options.Suggest,
+		tokenizer:     options.Tokenizer,
+		helpFlags:     options.HelpFlags,
+		normalizeFlag: options.NormalizeFlag,
+		prefixMatch:   options.PrefixMatching,
+		strictArgs:    options.StrictArgs,
+		messages:      options.Messages,
+		usage:         usageOptions(options, nil),
+	}
+	if cfg.lookupEnv == nil {
+		cfg.lookupEnv = os.LookupEnv
+	}
+	if f, ok := runOptions.Stdin.(*os.File); ok && options.PromptMissing && isTerminal(f) {
+		cfg.prompt = terminalPrompt(f, runOptions.Stderr)
+	}
+
+	runOptions.Trace.parseStart(root, args)
+	parseStart := time.Now()
+	var s *State
+	var err error
+	if options.Isolated {
+		s, err = parseInvocationState(root, args, cfg)
+	} else if err = parse(root, args, cfg); root != nil {
+		s = root.state
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			usage := formatUsage(root, s.path, usageOptions(options, runOptions.Stdout))
+			if options.Plugins && len(s.path) == 1 {
+				if plugins := ListPlugins(root); len(plugins) > 0 {
+					usage += "\n\n" + options.Messages.availablePlugins() + "\n  " + strings.Join(plugins, "\n  ")
+				}
+			}
+			writeHelp(runOptions.Stdout, usage, runOptions.HelpPager)
+			return nil
+		}
+		exitErr := reportError(errWriter, err, 2, s, options)
+		if !options.SkipHelpOnError && s != nil {
+			fmt.Fprintf(errWriter, "\n%s\n", formatUsage(root, s.path, usageOptions(options, errWriter)))
+		}
+		return exitErr
+	}
+	runOptions.Trace.commandResolved(s.path)
+	runOptions.debugLog.parsed(s, time.Since(parseStart))
+	if explain {
+		writeFlagSources(runOptions.Stdout, s)
+		return nil
+	}
+	if options.Isolated {
+		s, err = (&Invocation{state: s}).run(ctx, runOptions)
+	} else {
+		err = Run(ctx, root, runOptions)
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			// Run already wrote the usage text requested by Exec.
+			return exitError(err, 2, options)
+		}
+		return reportError(errWriter, err, 1, s, options)
+	}
+	if options.OnResult != nil {
+		if result, ok := s.Result(); ok {
+			options.OnResult(result)
+		}
+	}
+	return nil
+}
+
+// Main runs [ParseAndRun] and returns the process exit code: 0 on success and for help requests, 2
+// for usage and parse errors, the code of an [ExitError] returned by Exec, 130 for interrupted
+// commands and 1 for other errors (see [Options.ExitCode]). Errors are written by ParseAndRun, so
+// a main function reduces to:
+//
+//	func main() {
+//	    os.Exit(cli.Main(context.Background(), root, nil))
+//	}
+//
+// The options parameter may be nil, in which case default values are used.
+func Main(ctx context.Context, root *Command, options *Options) int {
+	err := ParseAndRun(ctx, root, options)
+	if err == nil {
+		return 0
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return 1
+}
+
+// expandArgsFromStdin replaces the first [ArgsFromStdinFlag] in args, before any "--" delimiter,
+// with the newline-delimited arguments read from r.
+func expandArgsFromStdin(args []string, r io.Reader) ([]string, error) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg != ArgsFromStdinFlag {
+			continue
+		}
+		var stdinArgs []string
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			if line := strings.TrimSuffix(scanner.Text(), "\r"); line != "" {
+				stdinArgs = append(stdinArgs, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read arguments from stdin: %w", err)
+		}
+		expanded := append(slices.Clone(args[:i]), stdinArgs...)
+		return append(expanded, args[i+1:]...), nil
+	}
+	return args, nil
+}
+
+// expandArgFiles replaces each "@FILE" argument before any "--" delimiter with the arguments read
+// from FILE, see [Options.ArgFiles].
+func expandArgFiles(args []string) ([]string, error) {
+	var expanded []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+		path, ok := strings.CutPrefix(arg, "@")
+		if !ok || path == "" {
+			expanded = append(expanded, arg)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read argument file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			expanded = append(expanded, line)
+		}
+	}
+	return expanded, nil
+}
+
+// removeArg removes all occurrences of arg before any "--" delimiter from args, and reports
+// whether it was found.
+func removeArg(args []string, arg string) ([]string, bool) {
+	end := slices.Index(args, "--")
+	if end < 0 {
+		end = len(args)
+	}
+	if !slices.Contains(args[:end], arg) {
+		return args, false
+	}
+	removed := slices.DeleteFunc(slices.Clone(args[:end]), func(a string) bool { return a == arg })
+	return append(removed, args[end:]...), true
+}
+
+// writeFlagSources writes a table of the flags in the command hierarchy, their final values and
+// where the values came from.
+func writeFlagSources(w io.Writer, s *State) {
+	flags := make(map[string]*flag.Flag)
+	metadata := make(map[string]FlagMetadata)
+	for i := range s.path {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			fset.VisitAll(func(f *flag.Flag) { flags[f.Name] = f })
+		}
+		for _, m := range pathFlagsMetadata(s.path, i) {
+			metadata[m.Name] = m
+		}
+	}
+	sources := s.FlagSources()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tVALUE\tSOURCE")
+	for _, name := range names {
+		source := sources[name].String()
+		if sources[name] == FlagSourceEnv {
+			source += " (" + metadata[name].EnvVar + ")"
+		}
+		value := flags[name].Value.String()
+		if metadata[name].Secret {
+			value = maskedValue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", formatFlagName(name), value, source)
+	}
+	tw.Flush()
+}
+
+// usageOptions returns the usage options for rendering usage text to w.
+func usageOptions(options *Options, w io.Writer) *UsageOptions {
+	var opt UsageOptions
+	if options.UsageOptions != nil {
+		opt = *options.UsageOptions
+	}
+	if opt.Output == nil {
+		opt.Output = w
+	}
+	if opt.Messages == nil {
+		opt.Messages = options.Messages
+	}
+	opt.helpCommand = options.HelpCommand
+	return &opt
+}
+
+// reportError presents err with the error handler of the options, or writes it to w, and returns
+// it as an [ExitError], see exitError.
+func reportError(w io.Writer, err error, code int, s *State, options *Options) *ExitError {
+	exitErr := exitError(err, code, options)
+	if options.ErrorHandler != nil {
+		exitErr.Code = options.ErrorHandler(exitErr, s)
+		return exitErr
+	}
+	fmt.Fprintf(w, "error: %v\n", err)
+	return exitErr
+}
+
+// exitError wraps err in an [ExitError] with the given default code, unless err already carries
+// one, and applies the exit code mapping of the options.
+func exitError(err error, code int, options *Options) *ExitError {
+	exitErr := &ExitError{Code: code, Err: err}
+	var existing *ExitError
+	if errors.As(err, &existing) {
+		exitErr.Code = existing.Code
+	} else if errors.Is(err, ErrInterrupted) {
+		exitErr.Code = 130
+	}
+	if options.ExitCode != nil {
+		exitErr.Code = options.ExitCode(exitErr)
+	}
+	return exitErr
+}
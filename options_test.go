@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfridman/cli/pkg/suggest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAndRun(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("region", "us-east-1", "cloud region")
+				f.Int("retries", 0, "number of retries")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "region", EnvVar: "APP_REGION"},
+				{Name: "retries", EnvVar: "APP_RETRIES"},
+			},
+			SubCommands: []*Command{
+				{
+					Name: "deploy",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("token", "", "API token")
+					}),
+					FlagsMetadata: []FlagMetadata{
+						{Name: "token", Required: true, EnvVar: "APP_TOKEN"},
+					},
+					Exec: func(ctx context.Context, s *State) error {
+						fmt.Fprintf(s.Stdout, "%s %s %d\n",
+							GetFlag[string](s, "token"),
+							GetFlag[string](s, "region"),
+							GetFlag[int](s, "retries"),
+						)
+						return nil
+					},
+				},
+				{
+					Name: "count",
+					Exec: func(ctx context.Context, s *State) error {
+						s.SetResult(len(s.Args))
+						return nil
+					},
+				},
+				{
+					Name: "fail",
+					Exec: func(ctx context.Context, s *State) error {
+						if len(s.Args) > 0 {
+							return &ExitError{Code: 42, Err: errors.New("custom failure")}
+						}
+						return errors.New("failure")
+					},
+				},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+	env := func(vars map[string]string) func(string) (string, bool) {
+		return func(key string) (string, bool) {
+			v, ok := vars[key]
+			return v, ok
+		}
+	}
+	run := func(t *testing.T, opt *Options) (stdout, stderr string, err error) {
+		t.Helper()
+		var outBuf, errBuf bytes.Buffer
+		opt.Stdout = &outBuf
+		opt.Stderr = &errBuf
+		if opt.LookupEnv == nil {
+			opt.LookupEnv = env(nil)
+		}
+		err = ParseAndRun(context.Background(), newRoot(), opt)
+		return outBuf.String(), errBuf.String(), err
+	}
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(t, &Options{Args: []string{"deploy", "--token=abc"}})
+		require.NoError(t, err)
+		require.Equal(t, "abc us-east-1 0\n", stdout)
+		require.Empty(t, stderr)
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		stdout, stderr, err := run(t, &Options{Args: []string{"deploy", "--help"}})
+		require.NoError(t, err)
+		require.Contains(t, stdout, "Usage:\n  app deploy [flags]")
+		require.Contains(t, stdout, "API token (env: APP_TOKEN)")
+		require.Empty(t, stderr)
+
+		// The pager is only used when Stdout is a terminal.
+		paged, _, err := run(t, &Options{Args: []string{"deploy", "--help"}, RunOptions: RunOptions{HelpPager: true}})
+		require.NoError(t, err)
+		require.Equal(t, stdout, paged)
+	})
+	t.Run("parse error", func(t *testing.T) {
+		t.Parallel()
+		_, stderr, err := run(t, &Options{Args: []string{"deploy"}})
+		var exitErr *ExitError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, 2, exitErr.Code)
+		require.ErrorContains(t, err, `required flag "-token" not set`)
+		require.Contains(t, stderr, "error: command \"app deploy\": required flag \"-token\" not set\n\nUsage:")
+
+		_, stderr, err = run(t, &Options{Args: []string{"deploy"}, SkipHelpOnError: true})
+		require.Error(t, err)
+		require.NotContains(t, stderr, "Usage:")
+	})
+	t.Run("exec error", func(t *testing.T) {
+		t.Parallel()
+		var errBuf bytes.Buffer
+		_, stderr, err := run(t, &Options{Args: []string{"fail"}, ErrorWriter: &errBuf})
+		require.Equal(t, 1, err.(*ExitError).Code)
+		require.Empty(t, stderr)
+		require.Equal(t, "error: failure\n", errBuf.String())
+
+		_, _, err = run(t, &Options{Args: []string{"fail", "custom"}})
+		require.Equal(t, 42, err.(*ExitError).Code)
+		require.ErrorContains(t, err, "custom failure")
+	})
+	t.Run("exit code mapping", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := run(t, &Options{
+			Args: []string{"fail", "custom"},
+			ExitCode: func(err error) int {
+				return err.(*ExitError).Code + 1
+			},
+		})
+		require.Equal(t, 43, err.(*ExitError).Code)
+	})
+	t.Run("env", func(t *testing.T) {
+		t.Parallel()
+		stdout, _, err := run(t, &Options{
+			Args:      []string{"deploy", "--region", "eu-west-1"},
+			LookupEnv: env(map[string]string{"APP_TOKEN": "from-env", "APP_REGION": "ignored", "APP_RETRIES": "3"}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "from-env eu-west-1 3\n", stdout)
+
+		_, _, err = run(t, &Options{
+			Args:      []string{"deploy", "--token=abc"},
+			LookupEnv: env(map[string]string{"APP_RETRIES": "three"}),
+		})
+		require.ErrorContains(t, err, `invalid value "three" for flag -retries from environment variable APP_RETRIES`)
+	})
+	t.Run("args from stdin", func(t *testing.T) {
+		t.Parallel()
+		stdin := "--token\nabc\r\n\n--region=eu-west-1\n"
+		stdout, _, err := run(t, &Options{
+			Args:          []string{"deploy", ArgsFromStdinFlag, "--retries=2"},
+			ArgsFromStdin: true,
+			RunOptions:    RunOptions{Stdin: strings.NewReader(stdin)},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "abc eu-west-1 2\n", stdout)
+
+		_, _, err = run(t, &Options{
+			Args:       []string{"deploy", ArgsFromStdinFlag},
+			RunOptions: RunOptions{Stdin: strings.NewReader(stdin)},
+		})
+		require.ErrorContains(t, err, "flag provided but not defined: -args-stdin")
+
+		args, err := expandArgsFromStdin([]string{"a", "--", ArgsFromStdinFlag}, strings.NewReader(stdin))
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "--", ArgsFromStdinFlag}, args)
+	})
+	t.Run("error handler", func(t *testing.T) {
+		t.Parallel()
+		var paths []string
+		handler := func(err error, s *State) int {
+			var exitErr *ExitError
+			require.ErrorAs(t, err, &exitErr)
+			paths = append(paths, getCommandPath(s.path))
+			return exitErr.Code + 100
+		}
+		_, stderr, err := run(t, &Options{Args: []string{"fail", "x"}, ErrorHandler: handler})
+		require.EqualError(t, err, "custom failure")
+		require.Equal(t, 142, err.(*ExitError).Code)
+		require.Empty(t, stderr)
+
+		_, stderr, err = run(t, &Options{Args: []string{"deploy"}, ErrorHandler: handler, SkipHelpOnError: true})
+		require.Equal(t, 102, err.(*ExitError).Code)
+		require.Empty(t, stderr)
+		require.Equal(t, []string{"app fail", "app deploy"}, paths)
+	})
+	t.Run("tokenizer", func(t *testing.T) {
+		t.Parallel()
+		// Rewrites "key=value" pairs into flags.
+		tokenizer := TokenizerFunc(func(root *Command, args []string) ([]string, error) {
+			tokens := make([]string, 0, len(args))
+			for _, arg := range args {
+				if key, value, ok := strings.Cut(arg, "="); ok && !strings.HasPrefix(key, "-") {
+					if key == "" {
+						return nil, errors.New("missing key")
+					}
+					arg = "--" + key + "=" + value
+				}
+				tokens = append(tokens, arg)
+			}
+			return tokens, nil
+		})
+		stdout, _, err := run(t, &Options{Args: []string{"deploy", "token=abc", "retries=3"}, Tokenizer: tokenizer})
+		require.NoError(t, err)
+		require.Equal(t, "abc us-east-1 3\n", stdout)
+
+		_, _, err = run(t, &Options{Args: []string{"deploy", "=abc"}, Tokenizer: tokenizer})
+		require.EqualError(t, err, "failed to tokenize arguments: missing key")
+	})
+	t.Run("arg files", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "args.txt")
+		require.NoError(t, os.WriteFile(path, []byte("# generated\n--token=abc\n\n  --region=eu-west-1  \n"), 0o644))
+		stdout, _, err := run(t, &Options{
+			Args:     []string{"deploy", "@" + path, "--retries=2"},
+			ArgFiles: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "abc eu-west-1 2\n", stdout)
+
+		_, _, err = run(t, &Options{Args: []string{"deploy", "@" + path}})
+		require.ErrorContains(t, err, `required flag "-token" not set`)
+
+		_, _, err = run(t, &Options{Args: []string{"deploy", "@missing.txt"}, ArgFiles: true})
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		args, err := expandArgFiles([]string{"a", "@", "--", "@" + path})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "@", "--", "@" + path}, args)
+	})
+	t.Run("suggestions", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := run(t, &Options{Args: []string{"deplyo"}})
+		require.ErrorContains(t, err, "Did you mean one of these?\n\tdeploy")
+
+		_, _, err = run(t, &Options{Args: []string{"deplyo"}, Suggest: &suggest.Config{MaxResults: -1}})
+		require.EqualError(t, err, `unknown command "deplyo"`)
+
+		_, _, err = run(t, &Options{Args: []string{"deploy", "--tokn=abc"}, Suggest: &suggest.Config{Threshold: 0.9}})
+		require.NotContains(t, err.Error(), "Did you mean")
+	})
+	t.Run("config sources", func(t *testing.T) {
+		t.Parallel()
+		var owners []string
+		src := ConfigSourceFunc(func(cmd *Command, name string) (string, bool) {
+			owners = append(owners, cmd.Name+":"+name)
+			if name == "retries" || name == "token" {
+				return "5", true
+			}
+			return "", false
+		})
+		stdout, _, err := run(t, &Options{
+			Args:          []string{"deploy"},
+			LookupEnv:     env(map[string]string{"APP_TOKEN": "from-env"}),
+			ConfigSources: []ConfigSource{src},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "from-env us-east-1 5\n", stdout)
+		require.ElementsMatch(t, []string{"app:region", "app:retries"}, owners)
+	})
+	t.Run("result", func(t *testing.T) {
+		t.Parallel()
+		var got []any
+		onResult := func(result any) { got = append(got, result) }
+		_, _, err := run(t, &Options{Args: []string{"count", "a", "b"}, OnResult: onResult})
+		require.NoError(t, err)
+		_, _, err = run(t, &Options{Args: []string{"deploy", "--token=abc"}, OnResult: onResult})
+		require.NoError(t, err)
+		require.Equal(t, []any{2}, got)
+	})
+	t.Run("explain flags", func(t *testing.T) {
+		t.Parallel()
+		src := ConfigSourceFunc(func(cmd *Command, name string) (string, bool) {
+			return "3", name == "retries"
+		})
+		stdout, _, err := run(t, &Options{
+			Args:          []string{"deploy", ExplainFlagsFlag, "--token=abc"},
+			ExplainFlags:  true,
+			LookupEnv:     env(map[string]string{"APP_REGION": "eu-west-1"}),
+			ConfigSources: []ConfigSource{src},
+		})
+		require.NoError(t, err)
+		require.Equal(t, `FLAG      VALUE      SOURCE
+-region   eu-west-1  env (APP_REGION)
+-retries  3          config
+-token    abc        command line
+`, stdout)
+
+		_, _, err = run(t, &Options{Args: []string{"deploy", ExplainFlagsFlag, "--token=abc"}})
+		require.ErrorContains(t, err, "flag provided but not defined: -explain-flags")
+
+		args, found := removeArg([]string{"a", "--", ExplainFlagsFlag}, ExplainFlagsFlag)
+		require.False(t, found)
+		require.Equal(t, []string{"a", "--", ExplainFlagsFlag}, args)
+	})
+}
+
+func TestMainExitCode(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{Name: "ok", Exec: func(ctx context.Context, s *State) error { return nil }},
+				{Name: "fail", Exec: func(ctx context.Context, s *State) error { return errors.New("boom") }},
+				{Name: "exit", Exec: func(ctx context.Context, s *State) error { return &ExitError{Code: 3} }},
+				{Name: "usage", Exec: func(ctx context.Context, s *State) error { return ShowHelp() }},
+			},
+		}
+	}
+	tests := []struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{args: []string{"ok"}, code: 0},
+		{args: []string{"--help"}, code: 0, stdout: "Usage:"},
+		{args: []string{"unknown"}, code: 2, stderr: `unknown command "unknown"`},
+		{args: []string{"ok", "--nope"}, code: 2, stderr: "flag provided but not defined"},
+		{args: []string{"usage"}, code: 2, stderr: "Usage:"},
+		{args: []string{"fail"}, code: 1, stderr: "boom"},
+		{args: []string{"exit"}, code: 3},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := Main(context.Background(), newRoot(), &Options{
+			Args:       tt.args,
+			RunOptions: RunOptions{Stdout: &stdout, Stderr: &stderr},
+		})
+		require.Equal(t, tt.code, code, tt.args)
+		require.Contains(t, stdout.String(), tt.stdout, tt.args)
+		require.Contains(t, stderr.String(), tt.stderr, tt.args)
+	}
+}
@@ -0,0 +1,132 @@
+// Package output renders structured command output in the format selected by the user, in the
+// style of kubectl's -output flag: as an aligned table, JSON, YAML, or with a Go template.
+//
+//	format, err := output.Parse("json")
+//	if err != nil {
+//	    return err
+//	}
+//	return output.Write(os.Stdout, format, users)
+//
+// Commands built with the cli package typically define the flag with cli.OutputFormatFlag and
+// render their result with State.Emit.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// Format names accepted by [Parse].
+const (
+	FormatTable      = "table"
+	FormatJSON       = "json"
+	FormatYAML       = "yaml"
+	FormatGoTemplate = "go-template"
+)
+
+// Names lists the values accepted by [Parse], for help text and shell completion.
+var Names = []string{FormatTable, FormatJSON, FormatYAML, FormatGoTemplate + "="}
+
+// Format is an output format, see [Parse]. The zero value renders tables.
+type Format struct {
+	// Name is one of FormatTable, FormatJSON, FormatYAML or FormatGoTemplate.
+	Name string
+	// Template is the template text of the FormatGoTemplate format.
+	Template string
+
+	tmpl *template.Template
+}
+
+// Parse parses an output format: "table", "json", "yaml", or "go-template=TEMPLATE", where
+// TEMPLATE is a [text/template] executed with the rendered value, e.g.,
+// "go-template={{range .}}{{.Name}}{{println}}{{end}}".
+func Parse(s string) (Format, error) {
+	name, text, hasTemplate := strings.Cut(s, "=")
+	switch {
+	case name == FormatGoTemplate && hasTemplate:
+		tmpl, err := template.New("output").Parse(text)
+		if err != nil {
+			return Format{}, fmt.Errorf("invalid template: %w", err)
+		}
+		return Format{Name: name, Template: text, tmpl: tmpl}, nil
+	case name == FormatGoTemplate:
+		return Format{}, fmt.Errorf("format %q requires a template, e.g., go-template={{.}}", name)
+	case !hasTemplate && (name == FormatTable || name == FormatJSON || name == FormatYAML):
+		return Format{Name: name}, nil
+	default:
+		return Format{}, fmt.Errorf("unknown format %q, must be one of: table, json, yaml, go-template=TEMPLATE", s)
+	}
+}
+
+// String returns the format in the syntax accepted by [Parse].
+func (f Format) String() string {
+	switch f.Name {
+	case "":
+		return FormatTable
+	case FormatGoTemplate:
+		return f.Name + "=" + f.Template
+	default:
+		return f.Name
+	}
+}
+
+// Write renders v to w in format f.
+//
+// Tables have one row per element if v is a slice or array, and a single row otherwise. Struct
+// elements have a column per exported field, named after its json tag if any, and map elements
+// have a column per key. Other elements are rendered in a single VALUE column. JSON and YAML
+// encode v with [encoding/json], so json tags and custom marshalers apply to both.
+func Write(w io.Writer, f Format, v any) error {
+	switch f.Name {
+	case "", FormatTable:
+		headers, rows := tableRows(v)
+		return Table(w, headers, rows)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case FormatYAML:
+		data, err := encodeYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatGoTemplate:
+		tmpl := f.tmpl
+		if tmpl == nil {
+			var err error
+			if tmpl, err = template.New("output").Parse(f.Template); err != nil {
+				return fmt.Errorf("invalid template: %w", err)
+			}
+		}
+		return tmpl.Execute(w, v)
+	default:
+		return fmt.Errorf("unknown format %q", f.Name)
+	}
+}
+
+// encodeYAML encodes v as a YAML document, by way of its JSON encoding.
+func encodeYAML(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	for _, line := range yamlLines(generic) {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type user struct {
+	Name      string            `json:"name"`
+	UserID    int               `json:"-"`
+	Admin     bool              `json:"admin"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Groups    []string          `json:"groups"`
+	password  string
+}
+
+var users = []user{
+	{
+		Name:      "alice",
+		Admin:     true,
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Labels:    map[string]string{"team": "infra", "on-call": "yes"},
+		Groups:    []string{"wheel", "dev"},
+	},
+	{
+		Name:      "李明",
+		CreatedAt: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		password:  "secret",
+	},
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"table", "json", "yaml", "go-template={{.}}"} {
+		f, err := Parse(s)
+		require.NoError(t, err)
+		require.Equal(t, s, f.String())
+	}
+	require.Equal(t, "table", Format{}.String())
+
+	_, err := Parse("xml")
+	require.EqualError(t, err, `unknown format "xml", must be one of: table, json, yaml, go-template=TEMPLATE`)
+	_, err = Parse("json=x")
+	require.ErrorContains(t, err, "unknown format")
+	_, err = Parse("go-template")
+	require.EqualError(t, err, `format "go-template" requires a template, e.g., go-template={{.}}`)
+	_, err = Parse("go-template={{.Name")
+	require.ErrorContains(t, err, "invalid template")
+}
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	write := func(t *testing.T, format string, v any) string {
+		t.Helper()
+		f, err := Parse(format)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, f, v))
+		return buf.String()
+	}
+
+	t.Run("table", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `NAME    ADMIN   CREATED AT                      LABELS                             GROUPS
+alice   true    2024-01-02 03:04:05 +0000 UTC   {"on-call":"yes","team":"infra"}   ["wheel","dev"]
+李明    false   2024-06-01 00:00:00 +0000 UTC
+`, write(t, "table", users))
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, Format{}, &users[1]))
+		require.Equal(t, `NAME   ADMIN   CREATED AT                      LABELS   GROUPS
+李明   false   2024-06-01 00:00:00 +0000 UTC
+`, buf.String())
+		require.Equal(t, "NAME   ADMIN   CREATED AT   LABELS   GROUPS\n", write(t, "table", []*user{}))
+		require.Equal(t, `REGION   ZONES
+eu       3
+us       2
+`, write(t, "table", []map[string]any{{"region": "eu", "zones": 3}, {"region": "us", "zones": 2}}))
+		require.Equal(t, "VALUE\na\nb\n", write(t, "table", []string{"a", "b"}))
+		require.Equal(t, "", write(t, "table", nil))
+	})
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `{
+  "name": "李明",
+  "admin": false,
+  "createdAt": "2024-06-01T00:00:00Z",
+  "groups": null
+}
+`, write(t, "json", users[1]))
+	})
+	t.Run("yaml", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `- admin: true
+  createdAt: "2024-01-02T03:04:05Z"
+  groups:
+    - wheel
+    - dev
+  labels:
+    on-call: "yes"
+    team: infra
+  name: alice
+- admin: false
+  createdAt: "2024-06-01T00:00:00Z"
+  groups: null
+  name: 李明
+`, write(t, "yaml", users))
+		require.Equal(t, `empty: {}
+none: []
+numbers:
+  - 1
+  - 2.5
+quoted:
+  - ""
+  - " padded"
+  - "true"
+  - "42"
+  - "a: b"
+  - "line\nbreak"
+`, write(t, "yaml", map[string]any{
+			"empty":   map[string]int{},
+			"none":    []int{},
+			"numbers": []float64{1, 2.5},
+			"quoted":  []string{"", " padded", "true", "42", "a: b", "line\nbreak"},
+		}))
+	})
+	t.Run("go-template", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "alice,李明,", write(t, "go-template={{range .}}{{.Name}},{{end}}", users))
+		var buf bytes.Buffer
+		err := Write(&buf, Format{Name: FormatGoTemplate, Template: "{{.Missing}}"}, users[0])
+		require.ErrorContains(t, err, "can't evaluate field Missing")
+	})
+}
+
+func TestColumnHeader(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]string{
+		"name":       "NAME",
+		"CreatedAt":  "CREATED AT",
+		"created_at": "CREATED AT",
+		"user-id":    "USER ID",
+		"UserID":     "USER ID",
+		"HTTPServer": "HTTP SERVER",
+	} {
+		require.Equal(t, want, columnHeader(name), name)
+	}
+}
@@ -0,0 +1,354 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/mfridman/cli/pkg/textutil"
+)
+
+// tableGutter is the number of spaces between table columns without borders.
+const tableGutter = 3
+
+// minTruncatedWidth is the width below which [WithMaxWidth] does not shrink columns.
+const minTruncatedWidth = 4
+
+// TableOption configures [Table].
+type TableOption func(*tableOptions)
+
+type tableOptions struct {
+	maxWidth       int
+	maxColumnWidth int
+	borders        bool
+}
+
+// WithMaxWidth fits the table in width terminal columns, e.g., the width of the terminal, by
+// shrinking the widest columns and truncating their cells. Columns are not shrunk below 4 columns,
+// so a table with many columns may still be wider.
+func WithMaxWidth(width int) TableOption {
+	return func(o *tableOptions) { o.maxWidth = width }
+}
+
+// WithMaxColumnWidth truncates cells wider than width terminal columns.
+func WithMaxColumnWidth(width int) TableOption {
+	return func(o *tableOptions) { o.maxColumnWidth = width }
+}
+
+// WithBorders draws ASCII borders around the table, between its columns, and below the headers.
+func WithBorders() TableOption {
+	return func(o *tableOptions) { o.borders = true }
+}
+
+// Table writes headers and rows to w as a table with columns sized to their widest cell, as
+// measured by [textutil.Width], so cells may contain wide characters or be colorized. Headers are
+// written as is, and may be nil to omit the header line. Rows may have fewer cells than there are
+// columns. Newlines and tabs in cells are replaced by spaces, and cells that do not fit are
+// truncated with an ellipsis, see [WithMaxWidth] and [WithMaxColumnWidth].
+//
+// Without borders, columns are separated by three spaces and lines have no trailing spaces.
+//
+//	output.Table(os.Stdout, []string{"NAME", "STATUS"}, [][]string{
+//	    {"web", "running"},
+//	    {"db", "stopped"},
+//	}, output.WithBorders())
+func Table(w io.Writer, headers []string, rows [][]string, opts ...TableOption) error {
+	var o tableOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	all := rows
+	if headers != nil {
+		all = append([][]string{headers}, rows...)
+	}
+	columns := 0
+	for _, row := range all {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return nil
+	}
+	cells := make([][]string, len(all))
+	widths := make([]int, columns)
+	for i, row := range all {
+		cells[i] = make([]string, columns)
+		for j, cell := range row {
+			cell = strings.Map(func(r rune) rune {
+				if r == '\n' || r == '\r' || r == '\t' {
+					return ' '
+				}
+				return r
+			}, cell)
+			cells[i][j] = cell
+			widths[j] = max(widths[j], textutil.Width(cell))
+		}
+	}
+	if o.maxColumnWidth > 0 {
+		for j := range widths {
+			widths[j] = min(widths[j], max(o.maxColumnWidth, 1))
+		}
+	}
+	if o.maxWidth > 0 {
+		shrinkColumns(widths, o.maxWidth-tableOverhead(columns, o.borders))
+	}
+
+	var b strings.Builder
+	border := func() {
+		if !o.borders {
+			return
+		}
+		b.WriteString("+")
+		for _, width := range widths {
+			b.WriteString(strings.Repeat("-", width+2) + "+")
+		}
+		b.WriteString("\n")
+	}
+	border()
+	for i, row := range cells {
+		var line strings.Builder
+		if o.borders {
+			line.WriteString("| ")
+		}
+		for j, cell := range row {
+			cell = textutil.Truncate(cell, widths[j])
+			line.WriteString(cell)
+			padding := widths[j] - textutil.Width(cell)
+			switch {
+			case o.borders && j < columns-1:
+				line.WriteString(strings.Repeat(" ", padding) + " | ")
+			case o.borders:
+				line.WriteString(strings.Repeat(" ", padding) + " |")
+			case j < columns-1:
+				line.WriteString(strings.Repeat(" ", padding+tableGutter))
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteString("\n")
+		if i == 0 && headers != nil {
+			border()
+		}
+	}
+	if len(rows) > 0 {
+		border()
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tableOverhead returns the number of columns taken by separators and borders in a table with the
+// given number of columns.
+func tableOverhead(columns int, borders bool) int {
+	if borders {
+		return 3*(columns-1) + 4
+	}
+	return tableGutter * (columns - 1)
+}
+
+// shrinkColumns narrows the widest of widths, one column at a time, until their sum is at most
+// total or no column is wider than minTruncatedWidth.
+func shrinkColumns(widths []int, total int) {
+	sum := 0
+	for _, width := range widths {
+		sum += width
+	}
+	for sum > total {
+		widest := 0
+		for j, width := range widths {
+			if width > widths[widest] {
+				widest = j
+			}
+		}
+		if widths[widest] <= minTruncatedWidth {
+			return
+		}
+		widths[widest]--
+		sum--
+	}
+}
+
+// tableRows returns the headers and rows of the table representation of v, see [Write].
+func tableRows(v any) ([]string, [][]string) {
+	rv := indirect(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	items := []reflect.Value{rv}
+	elemType := rv.Type()
+	if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Type().Elem().Kind() != reflect.Uint8 {
+		items = items[:0]
+		for i := 0; i < rv.Len(); i++ {
+			if item := indirect(rv.Index(i)); item.IsValid() {
+				items = append(items, item)
+			}
+		}
+		elemType = rv.Type().Elem()
+		for elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Interface && len(items) > 0 {
+			elemType = items[0].Type()
+		}
+	}
+
+	switch {
+	case elemType.Kind() == reflect.Struct:
+		fields := tableFields(elemType)
+		headers := make([]string, len(fields))
+		for i, f := range fields {
+			headers[i] = columnHeader(f.name)
+		}
+		rows := make([][]string, 0, len(items))
+		for _, item := range items {
+			row := make([]string, len(fields))
+			if item.Type() == elemType {
+				for i, f := range fields {
+					if fv, err := item.FieldByIndexErr(f.index); err == nil {
+						row[i] = formatCell(fv)
+					}
+				}
+			}
+			rows = append(rows, row)
+		}
+		return headers, rows
+	case elemType.Kind() == reflect.Map && elemType.Key().Kind() == reflect.String:
+		var keys []string
+		for _, item := range items {
+			if item.Kind() != reflect.Map {
+				continue
+			}
+			for _, k := range item.MapKeys() {
+				if !slices.Contains(keys, k.String()) {
+					keys = append(keys, k.String())
+				}
+			}
+		}
+		slices.Sort(keys)
+		headers := make([]string, len(keys))
+		for i, k := range keys {
+			headers[i] = columnHeader(k)
+		}
+		rows := make([][]string, 0, len(items))
+		for _, item := range items {
+			row := make([]string, len(keys))
+			if item.Kind() == reflect.Map {
+				for i, k := range keys {
+					row[i] = formatCell(item.MapIndex(reflect.ValueOf(k).Convert(item.Type().Key())))
+				}
+			}
+			rows = append(rows, row)
+		}
+		return headers, rows
+	default:
+		rows := make([][]string, 0, len(items))
+		for _, item := range items {
+			rows = append(rows, []string{formatCell(item)})
+		}
+		return []string{"VALUE"}, rows
+	}
+}
+
+// columnHeader returns the header of the column for a struct field or map key: name in upper case,
+// with words separated by spaces, e.g., "CREATED AT" for "CreatedAt" or "created_at".
+func columnHeader(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			r = ' '
+		} else if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+type tableField struct {
+	name  string
+	index []int
+}
+
+// tableFields returns the columns of a table of structs of type t: its exported fields, including
+// those promoted from embedded structs, named after their json tag if any. Fields tagged with
+// json:"-" are skipped.
+func tableFields(t reflect.Type) []tableField {
+	var fields []tableField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct) {
+			continue
+		}
+		name := f.Name
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields = append(fields, tableField{name: name, index: f.Index})
+	}
+	return fields
+}
+
+// formatCell returns the text of a table cell. Values implementing [fmt.Stringer] or error are
+// formatted with their method, composite values are encoded as JSON, and nil is rendered as an
+// empty cell.
+func formatCell(v reflect.Value) string {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	if v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case fmt.Stringer:
+			return x.String()
+		case error:
+			return x.Error()
+		}
+	}
+	v = indirect(v)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return ""
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// indirect dereferences pointers and interfaces, and returns the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+
+	headers := []string{"NAME", "STATUS", "DESCRIPTION"}
+	rows := [][]string{
+		{"web", "\x1b[32mrunning\x1b[0m", "serves the public website\nand the API"},
+		{"数据库", "stopped"},
+	}
+	table := func(t *testing.T, headers []string, rows [][]string, opts ...TableOption) string {
+		t.Helper()
+		var buf bytes.Buffer
+		require.NoError(t, Table(&buf, headers, rows, opts...))
+		return buf.String()
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "NAME     STATUS    DESCRIPTION\n"+
+			"web      \x1b[32mrunning\x1b[0m   serves the public website and the API\n"+
+			"数据库   stopped\n", table(t, headers, rows))
+		require.Equal(t, "a     b\nccc\n", table(t, nil, [][]string{{"a", "b"}, {"ccc"}}))
+		require.Empty(t, table(t, nil, nil))
+	})
+	t.Run("borders", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `+--------+---------+---------------------------------------+
+| NAME   | STATUS  | DESCRIPTION                           |
++--------+---------+---------------------------------------+
+| web    | `+"\x1b[32mrunning\x1b[0m"+` | serves the public website and the API |
+| 数据库 | stopped |                                       |
++--------+---------+---------------------------------------+
+`, table(t, headers, rows, WithBorders()))
+		require.Equal(t, `+------+
+| NAME |
++------+
+`, table(t, []string{"NAME"}, nil, WithBorders()))
+	})
+	t.Run("max column width", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, "NAME     STATUS    DESCRIPTION\n"+
+			"web      \x1b[32mrunning\x1b[0m   serves the…\n"+
+			"数据库   stopped\n", table(t, headers, rows, WithMaxColumnWidth(11)))
+	})
+	t.Run("max width", func(t *testing.T) {
+		t.Parallel()
+		got := table(t, headers, rows, WithMaxWidth(40))
+		require.Equal(t, "NAME     STATUS    DESCRIPTION\n"+
+			"web      \x1b[32mrunning\x1b[0m   serves the public we…\n"+
+			"数据库   stopped\n", got)
+		got = table(t, headers, rows, WithMaxWidth(10))
+		require.Equal(t, "NAME   STA…   DES…\n"+
+			"web    \x1b[32mrun…\x1b[0m   ser…\n"+
+			"数…    sto…\n", got)
+	})
+}
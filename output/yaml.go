@@ -0,0 +1,116 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// yamlLines returns the lines of the YAML block representation of v, a value decoded from JSON
+// with [json.Decoder.UseNumber]. Mapping keys are sorted.
+func yamlLines(v any) []string {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			return []string{"{}"}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var lines []string
+		for _, k := range keys {
+			child := yamlLines(v[k])
+			if !isYAMLBlock(v[k]) {
+				lines = append(lines, yamlString(k)+": "+child[0])
+				continue
+			}
+			lines = append(lines, yamlString(k)+":")
+			for _, line := range child {
+				lines = append(lines, "  "+line)
+			}
+		}
+		return lines
+	case []any:
+		if len(v) == 0 {
+			return []string{"[]"}
+		}
+		var lines []string
+		for _, item := range v {
+			child := yamlLines(item)
+			lines = append(lines, "- "+child[0])
+			for _, line := range child[1:] {
+				lines = append(lines, "  "+line)
+			}
+		}
+		return lines
+	default:
+		return []string{yamlScalar(v)}
+	}
+}
+
+// isYAMLBlock reports whether v is a non-empty mapping or sequence, which starts on its own line.
+func isYAMLBlock(v any) bool {
+	switch v := v.(type) {
+	case map[string]any:
+		return len(v) > 0
+	case []any:
+		return len(v) > 0
+	default:
+		return false
+	}
+}
+
+func yamlScalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	default:
+		return yamlString(fmt.Sprint(v))
+	}
+}
+
+// yamlString returns s as a plain scalar if it cannot be mistaken for another type or syntax, and
+// as a double-quoted scalar otherwise.
+func yamlString(s string) string {
+	if isPlainYAML(s) {
+		return s
+	}
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func isPlainYAML(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "~", "null", "true", "false", "yes", "no", "y", "n", "on", "off":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "-.0123456789") {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+		case strings.ContainsRune("_-./ ", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
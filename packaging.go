@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PackageFile is a file generated by [WritePackageFiles] for distribution alongside the binary.
+type PackageFile struct {
+	// Path is the file path relative to the output directory, e.g., "completions/todo.bash".
+	Path string
+	// InstallDir is the conventional install directory, relative to the installation prefix, e.g.,
+	// "share/bash-completion/completions".
+	InstallDir string
+	// InstallName is the file name to install as, which may differ from the generated file name.
+	InstallName string
+}
+
+// PackageFiles returns the layout of the files generated by [WritePackageFiles]: a completion
+// script for each supported shell and a section 1 man page.
+func PackageFiles(root *Command) []PackageFile {
+	if root == nil {
+		return nil
+	}
+	name := root.Name
+	return []PackageFile{
+		{Path: "completions/" + name + ".bash", InstallDir: "share/bash-completion/completions", InstallName: name},
+		{Path: "completions/_" + name, InstallDir: "share/zsh/site-functions", InstallName: "_" + name},
+		{Path: "completions/" + name + ".fish", InstallDir: "share/fish/vendor_completions.d", InstallName: name + ".fish"},
+		{Path: "man/" + name + ".1", InstallDir: "share/man/man1", InstallName: name + ".1"},
+	}
+}
+
+// WritePackageFiles generates the shell completion scripts and man page for the root command into
+// dir, using the layout described by [PackageFiles], so release pipelines can publish them as
+// artifacts. Directories are created as needed and existing files are overwritten.
+func WritePackageFiles(dir string, root *Command) ([]PackageFile, error) {
+	if root == nil {
+		return nil, fmt.Errorf("failed to write package files: root command is nil")
+	}
+	files := PackageFiles(root)
+	for _, f := range files {
+		var buf bytes.Buffer
+		var err error
+		switch {
+		case strings.HasPrefix(f.Path, "man/"):
+			err = GenerateDocs(&buf, root, "man")
+		case strings.HasSuffix(f.Path, ".bash"):
+			err = GenerateCompletion(&buf, root, "bash")
+		case strings.HasSuffix(f.Path, ".fish"):
+			err = GenerateCompletion(&buf, root, "fish")
+		default:
+			err = GenerateCompletion(&buf, root, "zsh")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write package files: %w", err)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to write package files: %w", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write package files: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// GeneratePackagingHints writes the snippet a package manifest needs to install the files
+// generated by [WritePackageFiles], assuming they are shipped in the release archive next to the
+// binary. Supported packagers are "homebrew", which emits the body of a formula's install method,
+// and "scoop", which emits manifest fields. Scoop has no convention for completions or man pages,
+// so they are only mentioned in the manifest notes.
+func GeneratePackagingHints(w io.Writer, root *Command, packager string) error {
+	if root == nil {
+		return fmt.Errorf("failed to generate packaging hints: root command is nil")
+	}
+	if err := validateName(root); err != nil {
+		return fmt.Errorf("failed to generate packaging hints: %w", err)
+	}
+	name := root.Name
+	var b strings.Builder
+	switch packager {
+	case "homebrew":
+		files := PackageFiles(root)
+		fmt.Fprintf(&b, "bin.install %q\n", name)
+		fmt.Fprintf(&b, "bash_completion.install %q => %q\n", files[0].Path, files[0].InstallName)
+		fmt.Fprintf(&b, "zsh_completion.install %q\n", files[1].Path)
+		fmt.Fprintf(&b, "fish_completion.install %q\n", files[2].Path)
+		fmt.Fprintf(&b, "man1.install %q\n", files[3].Path)
+	case "scoop":
+		fmt.Fprintf(&b, "\"bin\": %q,\n", name+".exe")
+		b.WriteString("\"notes\": [\n")
+		fmt.Fprintf(&b, "    %q\n", fmt.Sprintf(
+			"Shell completion scripts and the man page for %s are in $dir\\completions and $dir\\man.", name))
+		b.WriteString("]\n")
+	default:
+		return fmt.Errorf("failed to generate packaging hints: unsupported packager %q, must be one of: homebrew, scoop", packager)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
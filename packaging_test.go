@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackaging(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name:      "app",
+			ShortHelp: "An example app",
+			Metadata:  &AppMetadata{Version: "v1.0.0"},
+			Exec:      func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	t.Run("write package files", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		files, err := WritePackageFiles(dir, newRoot())
+		require.NoError(t, err)
+		require.Equal(t, PackageFiles(newRoot()), files)
+		for _, f := range files {
+			data, err := os.ReadFile(filepath.Join(dir, f.Path))
+			require.NoError(t, err)
+			require.NotEmpty(t, data)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, "completions", "_app"))
+		require.NoError(t, err)
+		require.Contains(t, string(data), "#compdef app")
+		data, err = os.ReadFile(filepath.Join(dir, "man", "app.1"))
+		require.NoError(t, err)
+		require.Contains(t, string(data), `.TH "APP" 1`)
+	})
+	t.Run("homebrew", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, GeneratePackagingHints(&buf, newRoot(), "homebrew"))
+		require.Equal(t, `bin.install "app"
+bash_completion.install "completions/app.bash" => "app"
+zsh_completion.install "completions/_app"
+fish_completion.install "completions/app.fish"
+man1.install "man/app.1"
+`, buf.String())
+	})
+	t.Run("scoop", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, GeneratePackagingHints(&buf, newRoot(), "scoop"))
+		require.Contains(t, buf.String(), `"bin": "app.exe",`)
+	})
+	t.Run("unsupported packager", func(t *testing.T) {
+		t.Parallel()
+		err := GeneratePackagingHints(&bytes.Buffer{}, newRoot(), "apt")
+		require.ErrorContains(t, err, `unsupported packager "apt"`)
+	})
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mfridman/cli/pkg/style"
+)
+
+// defaultPager is the pager used when the PAGER environment variable is not set. The flags make
+// less exit if the text fits on one screen, pass through color escape sequences, and leave the
+// text on screen after exiting.
+var defaultPager = []string{"less", "-FRX"}
+
+// writeHelp writes help text to w, through a pager if paging is enabled, w is a terminal and the
+// text does not fit on the screen. See [RunOptions.HelpPager].
+func writeHelp(w io.Writer, text string, pager bool) {
+	if pager && pageText(w, text) {
+		return
+	}
+	fmt.Fprintln(w, text)
+}
+
+// pageText pipes text through the pager and reports whether it did so.
+func pageText(w io.Writer, text string) bool {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return false
+	}
+	height, ok := style.TerminalHeight(f)
+	if !ok || strings.Count(text, "\n")+1 < height {
+		return false
+	}
+	args := strings.Fields(os.Getenv("PAGER"))
+	if len(args) == 0 {
+		args = defaultPager
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text + "\n")
+	cmd.Stdout = f
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+	// The pager has taken over the terminal, so its exit status is not meaningful.
+	_ = cmd.Wait()
+	return true
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
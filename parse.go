@@ -5,11 +5,13 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"regexp"
+	"maps"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/mfridman/cli/pkg/suggest"
 	"github.com/mfridman/xflag"
 )
 
@@ -20,6 +22,48 @@ import (
 // with the root command and the arguments to parse, typically os.Args[1:]. Once parsing is
 // complete, the root command is ready to be executed with the [Run] function.
 func Parse(root *Command, args []string) error {
+	return parse(root, args, &parseConfig{lookupEnv: os.LookupEnv})
+}
+
+// parseConfig holds settings that customize parsing, see [Options].
+type parseConfig struct {
+	lookupEnv     func(key string) (string, bool)
+	configSources []ConfigSource
+	suggest       *suggest.Config
+	tokenizer     Tokenizer
+	helpFlags     []string
+	prompt        promptFunc
+	normalizeFlag func(name string) string
+	prefixMatch   bool
+	strictArgs    bool
+	messages      *Messages
+	// usage configures the usage text written when Exec requests it, see [State.Usage].
+	usage *UsageOptions
+	// allowNoExec accepts a terminal command without an Exec function, see [ParseOnly].
+	allowNoExec bool
+}
+
+// defaultHelpFlags are the names of the flags that request help, see [Options.HelpFlags].
+var defaultHelpFlags = []string{"h", "help"}
+
+// Tokenizer rewrites raw arguments into the syntax parsed by the framework, so a CLI can support
+// alternate syntaxes, such as "+verbose" toggles or "key=value" positional pairs, while reusing
+// command resolution, validation, help and execution. See [Options.Tokenizer].
+type Tokenizer interface {
+	// Tokenize returns the arguments to parse in place of args. The root command is given so the
+	// tokenizer can tell command names apart from other arguments.
+	Tokenize(root *Command, args []string) ([]string, error)
+}
+
+// TokenizerFunc is an adapter to allow the use of ordinary functions as a [Tokenizer].
+type TokenizerFunc func(root *Command, args []string) ([]string, error)
+
+// Tokenize calls f(root, args).
+func (f TokenizerFunc) Tokenize(root *Command, args []string) ([]string, error) {
+	return f(root, args)
+}
+
+func parse(root *Command, args []string, cfg *parseConfig) error {
 	if root == nil {
 		return fmt.Errorf("failed to parse: root command is nil")
 	}
@@ -29,37 +73,62 @@ func Parse(root *Command, args []string) error {
 
 	// Initialize or update root state
 	if root.state == nil {
-		root.state = &State{
-			path: []*Command{root},
+		root.state = &State{}
+	}
+	return parseState(root, root.state, args, cfg)
+}
+
+// parseState parses args into s. If s holds per-invocation flag set copies (see
+// [ParseInvocation]), neither the command tree nor its flag values are modified.
+func parseState(root *Command, s *State, args []string, cfg *parseConfig) error {
+	// Reset command path but preserve other state
+	s.path = []*Command{root}
+	s.usage = cfg.usage
+	s.messages = cfg.messages
+	if len(args) > 0 && args[0] == CompleteCommandName {
+		s.path = []*Command{root, newCompleteCommand(root, args[1:])}
+		s.Args = nil
+		s.dashArgs = nil
+		s.setFlags = nil
+		s.sources = nil
+		return nil
+	}
+	if cfg.tokenizer != nil {
+		var err error
+		if args, err = cfg.tokenizer.Tokenize(root, args); err != nil {
+			return fmt.Errorf("failed to tokenize arguments: %w", err)
 		}
-	} else {
-		// Reset command path but preserve other state
-		root.state.path = []*Command{root}
 	}
 	// First split args at the -- delimiter if present
 	var argsToParse []string
 	var remainingArgs []string
+	s.dashArgs = nil
 	for i, arg := range args {
 		if arg == "--" {
 			argsToParse = args[:i]
 			remainingArgs = args[i+1:]
+			s.dashArgs = append([]string{}, remainingArgs...)
 			break
 		}
 	}
 	if argsToParse == nil {
 		argsToParse = args
 	}
+	if cfg.normalizeFlag != nil {
+		argsToParse = normalizeFlagArgs(argsToParse, cfg.normalizeFlag, func(name string) *flag.Flag {
+			return lookupTreeFlag(root, name)
+		})
+	}
 
 	current := root
-	if current.Flags == nil {
+	if current.Flags == nil && s.clones == nil {
 		current.Flags = flag.NewFlagSet(root.Name, flag.ContinueOnError)
 	}
 	var commandChain []*Command
 	commandChain = append(commandChain, root)
-
-	// Create combined flags with all parent flags
-	combinedFlags := flag.NewFlagSet(root.Name, flag.ContinueOnError)
-	combinedFlags.SetOutput(io.Discard)
+	// commandWords is the number of arguments naming subcommands, which precede the positional
+	// arguments once flags are parsed.
+	commandWords := 0
 
 	// First pass: process commands and build the flag set
 	i := 0
@@ -76,7 +145,7 @@ func Parse(root *Command, args []string) error {
 
 			// Check if this flag expects a value
 			name := strings.TrimLeft(arg, "-")
-			if f := current.Flags.Lookup(name); f != nil {
+			if f := lookupTraversalFlag(commandChain, name); f != nil {
 				if _, isBool := f.Value.(interface{ IsBoolFlag() bool }); !isBool {
 					// Skip both flag and its value
 					i += 2
@@ -89,106 +158,124 @@ func Parse(root *Command, args []string) error {
 
 		// Try to traverse to subcommand
 		if len(current.SubCommands) > 0 {
-			if sub := current.findSubCommand(arg); sub != nil {
-				root.state.path = append(slices.Clone(root.state.path), sub)
-				if sub.Flags == nil {
+			sub, err := current.matchSubCommand(arg, cfg.prefixMatch, cfg.messages)
+			if err != nil {
+				return err
+			}
+			if sub != nil {
+				s.path = append(slices.Clone(s.path), sub)
+				if sub.Flags == nil && s.clones == nil {
 					sub.Flags = flag.NewFlagSet(sub.Name, flag.ContinueOnError)
 				}
 				current = sub
 				commandChain = append(commandChain, sub)
+				commandWords++
 				i++
 				continue
 			}
-			return current.formatUnknownCommandError(arg)
+			return current.newUnknownCommandError(arg, cfg.suggest, cfg.messages)
 		}
 		break
 	}
-	current.Flags.Usage = func() { /* suppress default usage */ }
+	if s.clones == nil {
+		current.Flags.Usage = func() { /* suppress default usage */ }
+	}
 
-	// Add the help check here, after we've found the correct command
-	hasHelp := false
-	for _, arg := range argsToParse {
-		if arg == "-h" || arg == "--h" || arg == "-help" || arg == "--help" {
-			hasHelp = true
-			break
+	// Create combined flags with all parent flags
+	combinedFlags := combineFlags(commandChain, s.clones)
+	if s.clones == nil {
+		resetFlagValues(commandChain)
+	}
+	if current.needsConfirmation() && combinedFlags.Lookup(confirmFlagName) == nil {
+		combinedFlags.Bool(confirmFlagName, false, current.confirmFlagUsage())
+	}
+	secrets := pathSecretFlags(commandChain)
+	var secretFiles []string
+	for name := range secrets {
+		if f := secretFileFlag(name); combinedFlags.Lookup(f.Name) == nil {
+			combinedFlags.String(f.Name, "", f.Usage)
+			secretFiles = append(secretFiles, name)
 		}
 	}
+	slices.Sort(secretFiles)
 
-	// Add flags in reverse order for proper precedence
-	for i := len(commandChain) - 1; i >= 0; i-- {
-		cmd := commandChain[i]
-		if cmd.Flags != nil {
-			cmd.Flags.VisitAll(func(f *flag.Flag) {
-				if combinedFlags.Lookup(f.Name) == nil {
-					combinedFlags.Var(f.Value, f.Name, f.Usage)
-				}
-			})
-		}
+	// Check for help only after combining all flags, this way we get the full list of flags in the
+	// help message, and flags defined by the application take precedence over help flags.
+	helpFlags := cfg.helpFlags
+	if helpFlags == nil {
+		helpFlags = defaultHelpFlags
 	}
-	// Make sure to return help only after combining all flags, this way we get the full list of
-	// flags in the help message!
-	if hasHelp {
-		return flag.ErrHelp
+	if !current.DisableHelpFlag {
+		for _, arg := range argsToParse {
+			if name, ok := flagArgName(arg); ok && slices.Contains(helpFlags, name) && combinedFlags.Lookup(name) == nil {
+				return flag.ErrHelp
+			}
+		}
 	}
 
 	// Let ParseToEnd handle the flag parsing
+	argsToParse = expandCountFlags(combinedFlags, argsToParse)
 	if err := xflag.ParseToEnd(combinedFlags, argsToParse); err != nil {
-		return fmt.Errorf("command %q: %w", getCommandPath(root.state.path), err)
+		if errors.Is(err, flag.ErrHelp) {
+			// The flag package treats undefined -h and -help flags as a help request, but they are
+			// disabled or renamed.
+			err = fmt.Errorf("flag provided but not defined: %s", undefinedHelpArg(argsToParse))
+		}
+		err = maskFlagError(withFlagSuggestions(err, combinedFlags, argsToParse, cfg.suggest, cfg.messages), secrets)
+		return fmt.Errorf("command %q: %w", getCommandPath(s.path), err)
+	}
+
+	// Record which flags were explicitly set on the command line, as opposed to left at their
+	// default values.
+	s.setFlags = make(map[string]bool)
+	combinedFlags.Visit(func(f *flag.Flag) {
+		s.setFlags[f.Name] = true
+	})
+
+	s.confirmed = false
+	if f := combinedFlags.Lookup(confirmFlagName); current.needsConfirmation() && f != nil {
+		s.confirmed = f.Value.String() == "true"
+	}
+
+	// Resolve flags not set on the command line from environment variables and config sources
+	if err := resolveFlagValues(s, commandChain, combinedFlags, cfg); err != nil {
+		return fmt.Errorf("command %q: %w", getCommandPath(s.path), err)
+	}
+	if err := readSecretFiles(s, combinedFlags, secretFiles); err != nil {
+		return fmt.Errorf("command %q: %w", getCommandPath(s.path), err)
 	}
 
 	// Check required flags
 	var missingFlags []string
-	for _, cmd := range commandChain {
-		if len(cmd.FlagsMetadata) > 0 {
-			for _, flagMetadata := range cmd.FlagsMetadata {
-				if !flagMetadata.Required {
-					continue
-				}
-				flag := combinedFlags.Lookup(flagMetadata.Name)
-				if flag == nil {
-					return fmt.Errorf("command %q: internal error: required flag %s not found in flag set", getCommandPath(root.state.path), formatFlagName(flagMetadata.Name))
-				}
-				if _, isBool := flag.Value.(interface{ IsBoolFlag() bool }); isBool {
-					isSet := false
-					for _, arg := range argsToParse {
-						if strings.HasPrefix(arg, "-"+flagMetadata.Name) || strings.HasPrefix(arg, "--"+flagMetadata.Name) {
-							isSet = true
-							break
-						}
-					}
-					if !isSet {
-						missingFlags = append(missingFlags, formatFlagName(flagMetadata.Name))
-					}
-				} else if flag.Value.String() == flag.DefValue {
-					missingFlags = append(missingFlags, formatFlagName(flagMetadata.Name))
+	for i := range commandChain {
+		for _, flagMetadata := range pathFlagsMetadata(commandChain, i) {
+			if !flagMetadata.Required {
+				continue
+			}
+			if combinedFlags.Lookup(flagMetadata.Name) == nil {
+				return fmt.Errorf("command %q: internal error: required flag %s not found in flag set", getCommandPath(s.path), formatFlagName(flagMetadata.Name))
+			}
+			if _, ok := s.sources[flagMetadata.Name]; !ok && cfg.prompt != nil {
+				if err := promptFlag(s, combinedFlags.Lookup(flagMetadata.Name), flagMetadata, cfg.prompt); err != nil {
+					return fmt.Errorf("command %q: %w", getCommandPath(s.path), err)
 				}
 			}
+			if _, ok := s.sources[flagMetadata.Name]; !ok {
+				missingFlags = append(missingFlags, flagMetadata.Name)
+			}
 		}
 	}
 	if len(missingFlags) > 0 {
-		msg := "required flag"
-		if len(missingFlags) > 1 {
-			msg += "s"
-		}
-		return fmt.Errorf("command %q: %s %q not set", getCommandPath(root.state.path), msg, strings.Join(missingFlags, ", "))
+		return &MissingFlagsError{Command: getCommandPath(s.path), Flags: missingFlags, messages: cfg.messages}
+	}
+	if err := checkRequiredWith(s, commandChain, combinedFlags, cfg.messages); err != nil {
+		return err
 	}
 
-	// Skip past command names in remaining args
+	// Skip past command names in remaining args. They are counted rather than matched by name, so
+	// positional arguments equal to a command name are kept, and abbreviated names are skipped.
 	parsed := combinedFlags.Args()
-	startIdx := 0
-	for _, arg := range parsed {
-		isCommand := false
-		for _, cmd := range commandChain {
-			if arg == cmd.Name {
-				startIdx++
-				isCommand = true
-				break
-			}
-		}
-		if !isCommand {
-			break
-		}
-	}
+	startIdx := min(commandWords, len(parsed))
 
 	// Combine remaining parsed args and everything after delimiter
 	var finalArgs []string
@@ -198,18 +285,87 @@ func Parse(root *Command, args []string) error {
 	if len(remainingArgs) > 0 {
 		finalArgs = append(finalArgs, remainingArgs...)
 	}
-	root.state.Args = finalArgs
+	s.Args = finalArgs
 
-	if current.Exec == nil {
-		return fmt.Errorf("command %q: no exec function defined", getCommandPath(root.state.path))
+	if cfg.strictArgs && len(current.SubCommands) > 0 && len(finalArgs) > 0 {
+		return fmt.Errorf("command %q: %s", getCommandPath(s.path), cfg.messages.unexpectedArgs(finalArgs))
+	}
+
+	if current.Exec == nil && !cfg.allowNoExec {
+		return fmt.Errorf("command %q: no exec function defined", getCommandPath(s.path))
 	}
 	return nil
 }
 
-var validNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+// checkRequiredWith checks that every flag of chain that is set, by any source, is accompanied by
+// the flags it requires, see [FlagMetadata.Requires].
+func checkRequiredWith(s *State, chain []*Command, combined *flag.FlagSet, messages *Messages) error {
+	for i := range chain {
+		for _, m := range pathFlagsMetadata(chain, i) {
+			if len(m.Requires) == 0 {
+				continue
+			}
+			for _, name := range m.Requires {
+				if combined.Lookup(name) == nil {
+					return fmt.Errorf("command %q: internal error: flag %s requires undefined flag %s",
+						getCommandPath(s.path), formatFlagName(m.Name), formatFlagName(name))
+				}
+			}
+			if _, ok := s.sources[m.Name]; !ok {
+				continue
+			}
+			var missing []string
+			for _, name := range m.Requires {
+				if _, ok := s.sources[name]; !ok {
+					missing = append(missing, formatFlagName(name))
+				}
+			}
+			if len(missing) > 0 {
+				return errors.New(messages.missingRequires(getCommandPath(s.path), formatFlagName(m.Name), missing))
+			}
+		}
+	}
+	return nil
+}
+
+// MissingFlagsError is returned when parsing arguments that do not set all required flags of the
+// command, see [FlagMetadata]. Callers can use [errors.As] to render their own message.
+type MissingFlagsError struct {
+	// Command is the full path of the command, e.g., "app deploy".
+	Command string
+	// Flags are the names of the missing flags, without leading dashes, in the order they are
+	// declared from the root command to the terminal command.
+	Flags []string
+
+	messages *Messages
+}
+
+func (e *MissingFlagsError) Error() string {
+	names := make([]string, 0, len(e.Flags))
+	for _, name := range e.Flags {
+		names = append(names, formatFlagName(name))
+	}
+	return e.messages.missingFlags(e.Command, names)
+}
+
+// isValidName reports whether name starts with an ASCII letter and contains only ASCII letters,
+// digits, dashes and underscores. It is called for every command on every parse, so it avoids the
+// cost of a regular expression.
+func isValidName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '-' || c == '_'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
 
 func validateName(root *Command) error {
-	if !validNameRegex.MatchString(root.Name) {
+	if !isValidName(root.Name) {
 		return fmt.Errorf("name must start with a letter and contain only letters, numbers, dashes (-) or underscores (_)")
 	}
 	return nil
@@ -223,6 +379,14 @@ func validateCommands(root *Command, path []string) error {
 		return fmt.Errorf("subcommand in path [%s] has no name", strings.Join(path, ", "))
 	}
 
+	if path == nil {
+		if err := validatePersistentFlags(root, make([]*Command, 0, 8), nil); err != nil {
+			return err
+		}
+		// Reserve room for the path of a typical command tree, so that recursive calls append to
+		// it in place instead of allocating a slice for every command.
+		path = make([]string, 0, 8)
+	}
 	currentPath := append(path, root.Name)
 	if err := validateName(root); err != nil {
 		quoted := make([]string, len(currentPath))
@@ -232,6 +396,16 @@ func validateCommands(root *Command, path []string) error {
 		return fmt.Errorf("command [%s]: %w", strings.Join(quoted, ", "), err)
 	}
 
+	for i, g := range root.FlagGroups {
+		if err := validateFlagGroup(root, g); err != nil {
+			quoted := make([]string, len(currentPath))
+			for i, p := range currentPath {
+				quoted[i] = strconv.Quote(p)
+			}
+			return fmt.Errorf("command [%s]: flag group %d: %w", strings.Join(quoted, ", "), i, err)
+		}
+	}
+
 	for _, sub := range root.SubCommands {
 		if err := validateCommands(sub, currentPath); err != nil {
 			return err
@@ -239,3 +413,365 @@ func validateCommands(root *Command, path []string) error {
 	}
 	return nil
 }
+
+// validatePersistentFlags checks that no command redefines a persistent flag of one of its
+// ancestors, given in persistent along with the path of the command that defines it. The path of
+// cmd's parent is appended to in place, like the path of validateCommands.
+func validatePersistentFlags(cmd *Command, path []*Command, persistent map[string][]*Command) error {
+	path = append(path, cmd)
+	for name, owner := range persistent {
+		if flagDefined(cmd, name) {
+			return fmt.Errorf("command %s: flag %s shadows the persistent flag of command %q",
+				quotedPath(path), formatFlagName(name), getCommandPath(owner))
+		}
+	}
+	cloned := false
+	for _, m := range cmd.FlagsMetadata {
+		if !m.Persistent {
+			continue
+		}
+		if cmd.Flags == nil || cmd.Flags.Lookup(m.Name) == nil {
+			return fmt.Errorf("command %s: persistent flag %s is not defined", quotedPath(path), formatFlagName(m.Name))
+		}
+		if !cloned {
+			// The map is shared with the siblings of cmd, so it is copied before it is extended.
+			next := make(map[string][]*Command, len(persistent)+1)
+			maps.Copy(next, persistent)
+			persistent, cloned = next, true
+		}
+		persistent[m.Name] = slices.Clone(path)
+	}
+	for _, sub := range cmd.SubCommands {
+		if err := validatePersistentFlags(sub, path, persistent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quotedPath formats the names of the commands of path as a list of quoted strings, e.g.,
+// ["app", "db"], like the errors of validateCommands.
+func quotedPath(path []*Command) string {
+	quoted := make([]string, len(path))
+	for i, c := range path {
+		quoted[i] = strconv.Quote(c.Name)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// flagDefined reports whether cmd defines the named flag, in its own flags or in a flag group.
+func flagDefined(cmd *Command, name string) bool {
+	if cmd.Flags != nil && cmd.Flags.Lookup(name) != nil {
+		return true
+	}
+	for _, g := range cmd.FlagGroups {
+		if g.Flags != nil && g.Flags.Lookup(name) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isPersistentFlag reports whether the named flag of cmd is marked [FlagMetadata.Persistent].
+func isPersistentFlag(cmd *Command, name string) bool {
+	for _, m := range cmd.FlagsMetadata {
+		if m.Name == name && m.Persistent {
+			return true
+		}
+	}
+	return false
+}
+
+func validateFlagGroup(cmd *Command, g *FlagGroup) error {
+	if g == nil {
+		return errors.New("flag group is nil")
+	}
+	for _, p := range append(slices.Clone(g.Include), g.Exclude...) {
+		names := strings.Fields(p)
+		if len(names) == 0 {
+			return errors.New("empty command path")
+		}
+		current := cmd
+		for _, name := range names {
+			if current = current.findSubCommand(name); current == nil {
+				return fmt.Errorf("command path %q does not match a subcommand", p)
+			}
+		}
+	}
+	return nil
+}
+
+// flagArgName returns the name of the flag in arg, if arg is a flag without a value, such as "-h" or
+// "--help".
+func flagArgName(arg string) (string, bool) {
+	name, ok := strings.CutPrefix(arg, "-")
+	if !ok {
+		return "", false
+	}
+	name = strings.TrimPrefix(name, "-")
+	if name == "" || strings.HasPrefix(name, "-") || strings.Contains(name, "=") {
+		return "", false
+	}
+	return name, true
+}
+
+// undefinedHelpArg returns the first "-h" or "-help" flag in args, in any of its forms.
+func undefinedHelpArg(args []string) string {
+	for _, arg := range args {
+		if name, ok := flagArgName(arg); ok && (name == "h" || name == "help") {
+			return arg
+		}
+	}
+	return "-h"
+}
+
+// resolveFlagValues sets flags that were not set on the command line from their environment
+// variable (see [FlagMetadata.EnvVar]), from the first config source that has a value for them or,
+// failing that, from their [FlagMetadata.DefaultFunc]. The source of every flag value other than
+// the default is recorded in the state.
+func resolveFlagValues(s *State, chain []*Command, combined *flag.FlagSet, cfg *parseConfig) error {
+	s.sources = make(map[string]FlagSource)
+	for name := range s.setFlags {
+		s.sources[name] = FlagSourceCommandLine
+	}
+	// Metadata of deeper commands takes precedence, like their flags do.
+	metadata := make(map[string]FlagMetadata)
+	for i := range chain {
+		for _, m := range pathFlagsMetadata(chain, i) {
+			metadata[m.Name] = m
+		}
+	}
+	// Config sources are given the command that defines a flag.
+	var owners map[string]*Command
+	if len(cfg.configSources) > 0 {
+		owners = make(map[string]*Command)
+		for i := len(chain) - 1; i >= 0; i-- {
+			for _, fset := range pathFlagSets(chain, i) {
+				fset.VisitAll(func(f *flag.Flag) {
+					if _, ok := owners[f.Name]; !ok {
+						owners[f.Name] = chain[i]
+					}
+				})
+			}
+		}
+	}
+
+	var err error
+	combined.VisitAll(func(f *flag.Flag) {
+		if err != nil || s.setFlags[f.Name] {
+			return
+		}
+		if envVar := metadata[f.Name].EnvVar; envVar != "" && cfg.lookupEnv != nil {
+			if value, ok := cfg.lookupEnv(envVar); ok {
+				if setErr := f.Value.Set(value); setErr != nil {
+					if metadata[f.Name].Secret {
+						value = maskedValue
+					}
+					err = fmt.Errorf("invalid value %q for flag %s from environment variable %s: %w",
+						value, formatFlagName(f.Name), envVar, setErr)
+					return
+				}
+				s.sources[f.Name] = FlagSourceEnv
+				return
+			}
+		}
+		for _, src := range cfg.configSources {
+			value, ok := src.Lookup(owners[f.Name], f.Name)
+			if !ok {
+				continue
+			}
+			if setErr := f.Value.Set(value); setErr != nil {
+				if metadata[f.Name].Secret {
+					value = maskedValue
+				}
+				err = fmt.Errorf("invalid value %q for flag %s from config: %w",
+					value, formatFlagName(f.Name), setErr)
+				return
+			}
+			s.sources[f.Name] = FlagSourceConfig
+			return
+		}
+		if fn := metadata[f.Name].DefaultFunc; fn != nil {
+			value, fnErr := fn()
+			if fnErr != nil {
+				err = fmt.Errorf("failed to compute default value for flag %s: %w", formatFlagName(f.Name), fnErr)
+				return
+			}
+			if value == "" {
+				return
+			}
+			if setErr := f.Value.Set(value); setErr != nil {
+				if metadata[f.Name].Secret {
+					value = maskedValue
+				}
+				err = fmt.Errorf("invalid default value %q for flag %s: %w", value, formatFlagName(f.Name), setErr)
+			}
+		}
+	})
+	return err
+}
+
+// promptFlag asks the user for the value of the missing required flag f and sets it, unless the
+// user enters an empty value.
+func promptFlag(s *State, f *flag.Flag, m FlagMetadata, prompt promptFunc) error {
+	value, err := prompt(f, m)
+	if err != nil {
+		return fmt.Errorf("failed to read flag %s: %w", formatFlagName(f.Name), err)
+	}
+	if value == "" {
+		return nil
+	}
+	if err := f.Value.Set(value); err != nil {
+		return fmt.Errorf("invalid value for flag %s: %w", formatFlagName(f.Name), err)
+	}
+	s.sources[f.Name] = FlagSourcePrompt
+	return nil
+}
+
+const undefinedFlagPrefix = "flag provided but not defined: -"
+
+// withFlagSuggestions adds suggestions for similarly named flags to an error about an undefined
+// flag. The suggestions use the dash prefix the user typed, taken from the matching argument.
+func withFlagSuggestions(err error, fset *flag.FlagSet, args []string, cfg *suggest.Config, messages *Messages) error {
+	name, ok := strings.CutPrefix(err.Error(), undefinedFlagPrefix)
+	if !ok {
+		return err
+	}
+	target := "-" + name
+	for _, arg := range args {
+		if n, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "="); n == name && strings.HasPrefix(arg, "-") {
+			target = arg
+			break
+		}
+	}
+	suggestions := cfg.FindFlags(target, fset)
+	if len(suggestions) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w. %s\n\t%s", err, messages.didYouMean(len(suggestions)), strings.Join(suggestions, "\n\t"))
+}
+
+// combineFlags returns a flag set combining the flags of every command in chain. Flags are added
+// in reverse order for proper precedence, so a subcommand's flag shadows a parent flag with the same
+// name. The flags of the path are cached by the root command, see flagCache.
+func combineFlags(chain []*Command, clones *flagClones) *flag.FlagSet {
+	combined := flag.NewFlagSet(chain[0].Name, flag.ContinueOnError)
+	combined.SetOutput(io.Discard)
+	for _, pf := range chain[0].pathFlagCache().pathFlags(chain) {
+		f := pf.flag
+		if clones != nil {
+			f = clones.flagSet(pf.set).Lookup(f.Name)
+		}
+		combined.Var(f.Value, f.Name, f.Usage)
+	}
+	return combined
+}
+
+// resetFlagValues resets the values of repeatable flags of chain to their defaults, so values
+// accumulated by a previous parse of the same command tree are not carried over. Copies of flag
+// sets (see [ParseInvocation]) start out with their defaults.
+func resetFlagValues(chain []*Command) {
+	for _, pf := range chain[0].pathFlagCache().pathFlags(chain) {
+		if v, ok := pf.flag.Value.(interface{ resetValue() }); ok {
+			v.resetValue()
+		}
+	}
+}
+
+// normalizeFlagArgs rewrites flag arguments whose name, normalized with normalize, names a flag
+// found by lookup to the "-name" form the flag package expects, keeping any "=value" suffix. The
+// name is passed to normalize without leading dashes, e.g., "dry_run" for "--dry_run". Arguments
+// starting with "/" are passed as is, including the slash, e.g., "/verbose". Other arguments and
+// the values of flags are left untouched. See [Options.NormalizeFlag].
+func normalizeFlagArgs(args []string, normalize func(string) string, lookup func(name string) *flag.Flag) []string {
+	normalized := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		flagPart, value, hasValue := strings.Cut(arg, "=")
+		name := strings.TrimLeft(flagPart, "-")
+		if name == flagPart && !strings.HasPrefix(arg, "/") || name == "" {
+			normalized = append(normalized, arg)
+			continue
+		}
+		f := lookup(normalize(name))
+		if f == nil {
+			normalized = append(normalized, arg)
+			continue
+		}
+		if hasValue {
+			normalized = append(normalized, "-"+f.Name+"="+value)
+			continue
+		}
+		normalized = append(normalized, "-"+f.Name)
+		if !isBoolFlag(f) && i+1 < len(args) {
+			// Keep the flag's value as is
+			i++
+			normalized = append(normalized, args[i])
+		}
+	}
+	return normalized
+}
+
+// lookupTreeFlag returns the named flag defined by any command or flag group in the command tree,
+// or nil if there is none.
+func lookupTreeFlag(cmd *Command, name string) *flag.Flag {
+	if cmd.Flags != nil {
+		if f := cmd.Flags.Lookup(name); f != nil {
+			return f
+		}
+	}
+	for _, g := range cmd.FlagGroups {
+		if g.Flags != nil {
+			if f := g.Flags.Lookup(name); f != nil {
+				return f
+			}
+		}
+	}
+	for _, sub := range cmd.SubCommands {
+		if f := lookupTreeFlag(sub, name); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// lookupPathFlag looks up a flag by name like the flag set returned by combineFlags for chain, but
+// without building it.
+func lookupPathFlag(chain []*Command, name string) *flag.Flag {
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, fset := range pathFlagSets(chain, i) {
+			if f := fset.Lookup(name); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// lookupTraversalFlag looks up a flag by name in the flags of the last command in chain, in the
+// persistent flags of any command in chain and in the flag groups attached to any command in chain.
+// It is used while traversing subcommands to determine whether a flag consumes the next argument as
+// its value.
+func lookupTraversalFlag(chain []*Command, name string) *flag.Flag {
+	if fset := chain[len(chain)-1].Flags; fset != nil {
+		if f := fset.Lookup(name); f != nil {
+			return f
+		}
+	}
+	for _, cmd := range chain {
+		if cmd.Flags != nil && isPersistentFlag(cmd, name) {
+			if f := cmd.Flags.Lookup(name); f != nil {
+				return f
+			}
+		}
+		for _, g := range cmd.FlagGroups {
+			if g.Flags == nil {
+				continue
+			}
+			if f := g.Flags.Lookup(name); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -163,6 +165,19 @@ func TestParse(t *testing.T) {
 		err := Parse(s.root, []string{"add", "--unknown", "item1"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `command "todo add": flag provided but not defined: -unknown`)
+		require.NotContains(t, err.Error(), "Did you mean")
+	})
+	t.Run("unknown flag with suggestion", func(t *testing.T) {
+		t.Parallel()
+		s := newTestState()
+
+		err := Parse(s.root, []string{"add", "--verbos", "item1"})
+		require.Error(t, err)
+		require.Equal(t, "command \"todo add\": flag provided but not defined: -verbos. Did you mean one of these?\n\t--verbose\n\t--version", err.Error())
+
+		err = Parse(s.root, []string{"add", "-dry-rn=true", "item1"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Did you mean one of these?\n\t-dry-run")
 	})
 	t.Run("with subcommand flags", func(t *testing.T) {
 		t.Parallel()
@@ -214,6 +229,31 @@ func TestParse(t *testing.T) {
 		err := Parse(s.root, []string{"unknown"})
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "unknown command")
+
+		s = newTestState()
+		err = Parse(s.root, []string{"nested", "helo"})
+		var unknownErr *UnknownCommandError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, "helo", unknownErr.Name)
+		assert.Same(t, s.nested, unknownErr.Parent)
+		assert.Equal(t, []string{"hello"}, unknownErr.Suggestions)
+	})
+	t.Run("unknown subcommand with nested suggestion", func(t *testing.T) {
+		t.Parallel()
+		s := newTestState()
+
+		err := Parse(s.root, []string{"subb", "--echo", "hi"})
+		require.EqualError(t, err, "unknown command \"subb\". Did you mean one of these?\n\tnested sub")
+		var unknownErr *UnknownCommandError
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Same(t, s.root, unknownErr.Parent)
+		assert.Equal(t, []string{"nested sub"}, unknownErr.Suggestions)
+
+		// Direct subcommands are suggested first.
+		s = newTestState()
+		err = Parse(s.root, []string{"nest"})
+		require.ErrorAs(t, err, &unknownErr)
+		assert.Equal(t, []string{"nested"}, unknownErr.Suggestions)
 	})
 	t.Run("flags at multiple levels", func(t *testing.T) {
 		t.Parallel()
@@ -330,6 +370,10 @@ func TestParse(t *testing.T) {
 			err := Parse(s.root, []string{"nested", "hello"})
 			require.Error(t, err)
 			require.ErrorContains(t, err, `command "todo nested hello": required flags "-mandatory-flag, -another-mandatory-flag" not set`)
+			var missingErr *MissingFlagsError
+			require.ErrorAs(t, err, &missingErr)
+			assert.Equal(t, "todo nested hello", missingErr.Command)
+			assert.Equal(t, []string{"mandatory-flag", "another-mandatory-flag"}, missingErr.Flags)
 		}
 		{
 			// Correct type - true
@@ -612,6 +656,88 @@ func TestParse(t *testing.T) {
 		require.Equal(t, "val2", GetFlag[string](cmd.state, "flag2"))
 		require.Equal(t, []string{"arg1", "arg2", "arg3"}, cmd.state.Args)
 	})
+	t.Run("required flag set to default value", func(t *testing.T) {
+		t.Parallel()
+		cmd := &Command{
+			Name: "root",
+			Flags: FlagsFunc(func(fset *flag.FlagSet) {
+				fset.Int("count", 0, "count")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "count", Required: true},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		err := Parse(cmd, []string{"--count=0"})
+		require.NoError(t, err)
+		require.True(t, cmd.state.IsSet("count"))
+		require.True(t, cmd.state.Changed("count"))
+	})
+	t.Run("required bool flag set to false", func(t *testing.T) {
+		t.Parallel()
+		newRoot := func() *Command {
+			return &Command{
+				Name: "root",
+				Flags: FlagsFunc(func(fset *flag.FlagSet) {
+					fset.Bool("force", false, "force")
+				}),
+				FlagsMetadata: []FlagMetadata{
+					{Name: "force", Required: true, EnvVar: "ROOT_FORCE"},
+				},
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			}
+		}
+		noEnv := func(string) (string, bool) { return "", false }
+
+		inv, err := parseInvocation(newRoot(), []string{"--force=false"}, &parseConfig{lookupEnv: noEnv})
+		require.NoError(t, err)
+		require.False(t, GetFlag[bool](inv.State(), "force"))
+		require.Equal(t, map[string]FlagSource{"force": FlagSourceCommandLine}, inv.State().FlagSources())
+
+		_, err = parseInvocation(newRoot(), nil, &parseConfig{lookupEnv: func(key string) (string, bool) {
+			return "false", key == "ROOT_FORCE"
+		}})
+		require.NoError(t, err)
+
+		// A value set by a previous parse of the same tree does not count.
+		root := newRoot()
+		require.NoError(t, parse(root, []string{"--force"}, &parseConfig{lookupEnv: noEnv}))
+		err = parse(root, nil, &parseConfig{lookupEnv: noEnv})
+		require.EqualError(t, err, `command "root": required flag "-force" not set`)
+		require.NoError(t, parse(root, []string{"--force=false"}, &parseConfig{lookupEnv: noEnv}))
+		require.False(t, GetFlag[bool](root.state, "force"))
+	})
+	t.Run("required flag set before parsing", func(t *testing.T) {
+		t.Parallel()
+		cmd := &Command{
+			Name: "root",
+			Flags: FlagsFunc(func(fset *flag.FlagSet) {
+				fset.String("name", "", "name")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "name", Required: true},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		require.NoError(t, cmd.Flags.Set("name", "preset"))
+		err := Parse(cmd, nil)
+		require.EqualError(t, err, `command "root": required flag "-name" not set`)
+	})
+	t.Run("is set", func(t *testing.T) {
+		t.Parallel()
+		s := newTestState()
+		err := Parse(s.root, []string{"nested", "--verbose", "sub", "--echo="})
+		require.NoError(t, err)
+		require.True(t, s.root.state.IsSet("verbose"))
+		require.True(t, s.root.state.IsSet("echo"))
+		require.False(t, s.root.state.IsSet("force"))
+		require.False(t, s.root.state.IsSet("version"))
+		require.False(t, s.root.state.IsSet("unknown"))
+		// Reparsing resets which flags are considered set
+		err = Parse(s.root, []string{"nested", "sub"})
+		require.NoError(t, err)
+		require.False(t, s.root.state.IsSet("verbose"))
+	})
 }
 
 func getCommand(t *testing.T, c *Command) *Command {
@@ -622,3 +748,596 @@ func getCommand(t *testing.T, c *Command) *Command {
 	require.NotNil(t, terminal)
 	return terminal
 }
+
+func TestFlagGroups(t *testing.T) {
+	t.Parallel()
+
+	// newDBTree returns the following command tree, with a database flag group attached to "db"
+	// that is excluded from "db version":
+	//
+	//	app
+	//	├── db (--dsn, required)
+	//	│   ├── migrate
+	//	│   │   └── up
+	//	│   ├── status
+	//	│   └── version
+	//	└── serve
+	newDBTree := func(group *FlagGroup) *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		return &Command{
+			Name: "app",
+			Exec: exec,
+			SubCommands: []*Command{
+				{
+					Name:       "db",
+					Exec:       exec,
+					FlagGroups: []*FlagGroup{group},
+					SubCommands: []*Command{
+						{
+							Name:        "migrate",
+							Exec:        exec,
+							SubCommands: []*Command{{Name: "up", Exec: exec}},
+						},
+						{Name: "status", Exec: exec},
+						{Name: "version", Exec: exec},
+					},
+				},
+				{Name: "serve", Exec: exec},
+			},
+		}
+	}
+	newGroup := func() *FlagGroup {
+		return &FlagGroup{
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("dsn", "", "database connection string")
+			}),
+			FlagsMetadata: []FlagMetadata{{Name: "dsn", Required: true}},
+			Exclude:       []string{"version"},
+		}
+	}
+
+	t.Run("inherited by subtree", func(t *testing.T) {
+		t.Parallel()
+		root := newDBTree(newGroup())
+		err := Parse(root, []string{"db", "--dsn", "postgres://", "migrate", "up"})
+		require.NoError(t, err)
+		require.Equal(t, "up", root.terminal().Name)
+		require.Equal(t, "postgres://", GetFlag[string](root.state, "dsn"))
+		require.Empty(t, root.state.Args)
+
+		root = newDBTree(newGroup())
+		err = Parse(root, []string{"db", "--dsn=postgres://"})
+		require.NoError(t, err)
+		require.Equal(t, "postgres://", GetFlag[string](root.state, "dsn"))
+	})
+	t.Run("required in subtree", func(t *testing.T) {
+		t.Parallel()
+		root := newDBTree(newGroup())
+		err := Parse(root, []string{"db", "status"})
+		require.ErrorContains(t, err, `command "app db status": required flag "-dsn" not set`)
+	})
+	t.Run("excluded", func(t *testing.T) {
+		t.Parallel()
+		root := newDBTree(newGroup())
+		err := Parse(root, []string{"db", "version"})
+		require.NoError(t, err)
+		_, err = LookupFlag[string](root.state, "dsn")
+		require.ErrorContains(t, err, `flag "-dsn" not found`)
+
+		err = Parse(root, []string{"db", "version", "--dsn=x"})
+		require.ErrorContains(t, err, "flag provided but not defined: -dsn")
+	})
+	t.Run("not leaked outside subtree", func(t *testing.T) {
+		t.Parallel()
+		root := newDBTree(newGroup())
+		err := Parse(root, []string{"serve", "--dsn=x"})
+		require.ErrorContains(t, err, "flag provided but not defined: -dsn")
+	})
+	t.Run("include", func(t *testing.T) {
+		t.Parallel()
+		group := newGroup()
+		group.FlagsMetadata = nil
+		group.Exclude = nil
+		group.Include = []string{"migrate"}
+		root := newDBTree(group)
+		err := Parse(root, []string{"db", "migrate", "up", "--dsn=x"})
+		require.NoError(t, err)
+		require.Equal(t, "x", GetFlag[string](root.state, "dsn"))
+
+		root = newDBTree(group)
+		err = Parse(root, []string{"db", "status", "--dsn=x"})
+		require.ErrorContains(t, err, "flag provided but not defined: -dsn")
+	})
+	t.Run("usage", func(t *testing.T) {
+		t.Parallel()
+		root := newDBTree(newGroup())
+		err := Parse(root, []string{"db", "migrate", "--help"})
+		require.ErrorIs(t, err, flag.ErrHelp)
+		usage := DefaultUsage(root)
+		require.Contains(t, usage, "Global Flags:\n  -dsn")
+	})
+	t.Run("invalid path", func(t *testing.T) {
+		t.Parallel()
+		group := newGroup()
+		group.Exclude = []string{"migrate down"}
+		root := newDBTree(group)
+		err := Parse(root, []string{"db"})
+		require.ErrorContains(t, err, `command ["app", "db"]: flag group 0: command path "migrate down" does not match a subcommand`)
+	})
+}
+
+func TestHelpFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name: "connect",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("h", "localhost", "host to connect to")
+					}),
+					Exec: exec,
+				},
+				{Name: "raw", DisableHelpFlag: true, Exec: exec},
+			},
+			Exec: exec,
+		}
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+		require.ErrorIs(t, Parse(newRoot(), []string{"-h"}), flag.ErrHelp)
+		require.ErrorIs(t, Parse(newRoot(), []string{"--help"}), flag.ErrHelp)
+	})
+	t.Run("application flag takes precedence", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"connect", "-h", "example.com"}))
+		require.Equal(t, "example.com", GetFlag[string](root.state, "h"))
+		require.ErrorIs(t, Parse(root, []string{"connect", "--help"}), flag.ErrHelp)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		err := Parse(newRoot(), []string{"raw", "--help"})
+		require.EqualError(t, err, `command "app raw": flag provided but not defined: --help`)
+	})
+	t.Run("renamed", func(t *testing.T) {
+		t.Parallel()
+		cfg := &parseConfig{helpFlags: []string{"?"}}
+		require.ErrorIs(t, parse(newRoot(), []string{"-?"}, cfg), flag.ErrHelp)
+		err := parse(newRoot(), []string{"-h"}, cfg)
+		require.EqualError(t, err, `command "app": flag provided but not defined: -h`)
+	})
+}
+
+func TestNormalizeFlag(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("dry-run", false, "print actions only")
+			}),
+			SubCommands: []*Command{
+				{
+					Name: "copy",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("output", "", "output path")
+					}),
+					Exec: func(ctx context.Context, s *State) error { return nil },
+				},
+			},
+		}
+	}
+	normalize := func(name string) string {
+		name = strings.TrimPrefix(name, "/")
+		return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}
+
+	t.Run("rewrites flag names", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		err := parse(root, []string{"--DRY_RUN", "copy", "/Output", "/tmp/x", "/src"}, &parseConfig{normalizeFlag: normalize})
+		require.NoError(t, err)
+		assert.True(t, GetFlag[bool](root.state, "dry-run"))
+		assert.Equal(t, "/tmp/x", GetFlag[string](root.state, "output"))
+		assert.Equal(t, []string{"/src"}, root.state.Args)
+	})
+	t.Run("keeps value", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, parse(root, []string{"copy", "--OUTPUT=A_B"}, &parseConfig{normalizeFlag: normalize}))
+		assert.Equal(t, "A_B", GetFlag[string](root.state, "output"))
+	})
+	t.Run("unknown flag", func(t *testing.T) {
+		t.Parallel()
+		err := parse(newRoot(), []string{"copy", "--Missing_Flag"}, &parseConfig{normalizeFlag: normalize})
+		require.ErrorContains(t, err, "flag provided but not defined: -Missing_Flag")
+	})
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		err := Parse(newRoot(), []string{"copy", "--dry_run"})
+		require.ErrorContains(t, err, "flag provided but not defined: -dry_run")
+	})
+}
+
+func TestPrefixMatching(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{Name: "status", Exec: exec},
+				{Name: "start", Exec: exec},
+				{Name: "stop", Exec: exec},
+				{Name: "log", Exec: exec, SubCommands: []*Command{
+					{Name: "log-tail", Exec: exec},
+				}},
+			},
+		}
+	}
+	cfg := &parseConfig{prefixMatch: true}
+
+	t.Run("unambiguous prefix", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, parse(root, []string{"stat"}, cfg))
+		assert.Equal(t, "status", root.terminal().Name)
+		require.NoError(t, parse(root, []string{"STO"}, cfg))
+		assert.Equal(t, "stop", root.terminal().Name)
+	})
+	t.Run("exact match wins", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, parse(root, []string{"log"}, cfg))
+		assert.Equal(t, "log", root.terminal().Name)
+		require.NoError(t, parse(root, []string{"log", "log-t"}, cfg))
+		assert.Equal(t, "log-tail", root.terminal().Name)
+	})
+	t.Run("ambiguous prefix", func(t *testing.T) {
+		t.Parallel()
+		err := parse(newRoot(), []string{"sta"}, cfg)
+		require.EqualError(t, err, "ambiguous command \"sta\", could be one of:\n\tstatus\n\tstart")
+	})
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+		err := Parse(newRoot(), []string{"stat"})
+		require.ErrorContains(t, err, `unknown command "stat"`)
+	})
+}
+
+func TestDefaultFunc(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	branch := "main"
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("branch", "master", "branch to deploy")
+			}),
+			FlagsMetadata: []FlagMetadata{{
+				Name:   "branch",
+				EnvVar: "APP_BRANCH",
+				DefaultFunc: func() (string, error) {
+					calls++
+					if branch == "error" {
+						return "", errors.New("not a git repository")
+					}
+					return branch, nil
+				},
+				DefaultText: "current branch",
+			}},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+	noEnv := &parseConfig{lookupEnv: func(string) (string, bool) { return "", false }}
+
+	root := newRoot()
+	require.NoError(t, parse(root, nil, noEnv))
+	require.Equal(t, "main", GetFlag[string](root.state, "branch"))
+	require.False(t, root.state.Changed("branch"))
+	require.Equal(t, 1, calls)
+
+	root = newRoot()
+	require.NoError(t, parse(root, []string{"-branch", "dev"}, noEnv))
+	require.Equal(t, "dev", GetFlag[string](root.state, "branch"))
+	root = newRoot()
+	env := &parseConfig{lookupEnv: func(key string) (string, bool) { return "staging", key == "APP_BRANCH" }}
+	require.NoError(t, parse(root, nil, env))
+	require.Equal(t, "staging", GetFlag[string](root.state, "branch"))
+	require.Equal(t, 1, calls)
+
+	branch = ""
+	root = newRoot()
+	require.NoError(t, parse(root, nil, noEnv))
+	require.Equal(t, "master", GetFlag[string](root.state, "branch"))
+
+	branch = "error"
+	err := parse(newRoot(), nil, noEnv)
+	require.EqualError(t, err, `command "app": failed to compute default value for flag -branch: not a git repository`)
+
+	branch = "main"
+	root = newRoot()
+	require.NoError(t, parse(root, nil, noEnv))
+	require.Contains(t, DefaultUsage(root), "branch to deploy (default: current branch)")
+}
+
+func TestParseCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{Name: "status", Exec: exec},
+				{
+					Name:        "nested",
+					Exec:        exec,
+					SubCommands: []*Command{{Name: "hello", Exec: exec}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		args   []string
+		cfg    parseConfig
+		want   []string
+		errMsg string
+	}{
+		{name: "arg named like command", args: []string{"status", "status", "app"}, want: []string{"status", "app"}},
+		{name: "abbreviated command", args: []string{"stat", "x"}, cfg: parseConfig{prefixMatch: true}, want: []string{"x"}},
+		{name: "args after delimiter", args: []string{"nested", "--", "bogus"}, want: []string{"bogus"}},
+		{
+			name:   "strict args after delimiter",
+			args:   []string{"nested", "--", "bogus", "extra"},
+			cfg:    parseConfig{strictArgs: true},
+			errMsg: `command "app nested": unexpected arguments "bogus extra", expected a subcommand`,
+		},
+		{name: "strict leaf args", args: []string{"nested", "hello", "x"}, cfg: parseConfig{strictArgs: true}, want: []string{"x"}},
+		{name: "strict no args", args: []string{"nested"}, cfg: parseConfig{strictArgs: true}},
+		{
+			name:   "strict unknown command",
+			args:   []string{"nested", "bogus", "extra"},
+			cfg:    parseConfig{strictArgs: true},
+			errMsg: `unknown command "bogus"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newRoot()
+			cfg := tt.cfg
+			cfg.lookupEnv = func(string) (string, bool) { return "", false }
+			err := parse(root, tt.args, &cfg)
+			if tt.errMsg != "" {
+				require.ErrorContains(t, err, tt.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, root.state.Args)
+		})
+	}
+}
+
+// newBenchmarkTree returns a command tree with 10 subcommands, each with 10 subcommands, and 5
+// flags on every command.
+func newBenchmarkTree() *Command {
+	exec := func(ctx context.Context, s *State) error { return nil }
+	newFlags := func(prefix string) *flag.FlagSet {
+		return FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool(prefix+"-verbose", false, "verbose")
+			f.String(prefix+"-output", "", "output")
+			f.Int(prefix+"-count", 0, "count")
+			f.Duration(prefix+"-timeout", 0, "timeout")
+			StringSlice(f, prefix+"-tag", nil, "tags")
+		})
+	}
+	root := &Command{Name: "app", Flags: newFlags("root"), Exec: exec}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("group%d", i)
+		group := &Command{Name: name, Flags: newFlags(name), Exec: exec}
+		for j := 0; j < 10; j++ {
+			name := fmt.Sprintf("cmd%d", j)
+			group.SubCommands = append(group.SubCommands, &Command{Name: name, Flags: newFlags(name), Exec: exec})
+		}
+		root.SubCommands = append(root.SubCommands, group)
+	}
+	return root
+}
+
+func BenchmarkParse(b *testing.B) {
+	root := newBenchmarkTree()
+	// Repeatable flags are not set, since their values accumulate across calls to Parse.
+	args := []string{"group5", "cmd5", "-root-verbose", "-group5-output=x", "-cmd5-count", "3", "arg"}
+	cfg := &parseConfig{lookupEnv: func(string) (string, bool) { return "", false }}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := parse(root, args, cfg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInvocation(b *testing.B) {
+	root := newBenchmarkTree()
+	args := []string{"group5", "cmd5", "-root-verbose", "-group5-output=x", "-cmd5-count", "3", "-cmd5-tag=a", "arg"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseInvocation(root, args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseUnknownCommand(b *testing.B) {
+	root := newBenchmarkTree()
+	cfg := &parseConfig{lookupEnv: func(string) (string, bool) { return "", false }}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := parse(root, []string{"group5", "cmd55"}, cfg); err == nil {
+			b.Fatal("expected error")
+		}
+	}
+}
+
+func TestIsValidName(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"a", "app", "App2", "dry-run", "dry_run", "x-1_y"} {
+		require.True(t, isValidName(name), name)
+	}
+	for _, name := range []string{"", "1app", "-app", "_app", "app name", "app.name", "café", "app/"} {
+		require.False(t, isValidName(name), name)
+	}
+}
+
+func TestPersistentFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("region", "us", "cloud region")
+				f.String("profile", "", "credentials profile")
+			}),
+			FlagsMetadata: []FlagMetadata{{Name: "region", Persistent: true}},
+			SubCommands: []*Command{
+				{
+					Name: "db",
+					SubCommands: []*Command{
+						{Name: "migrate", Exec: exec},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("recognized between subcommands", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"db", "--region", "eu", "migrate"}))
+		require.Equal(t, "migrate", root.terminal().Name)
+		require.Equal(t, "eu", GetFlag[string](root.state, "region"))
+		require.Empty(t, root.state.Args)
+
+		// Inherited flags that are not persistent are not known past the command defining them.
+		err := Parse(newRoot(), []string{"db", "--profile", "dev", "migrate"})
+		require.ErrorContains(t, err, `unknown command "dev"`)
+	})
+	t.Run("subcommand added later", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		db := root.SubCommands[0]
+		db.SubCommands = append(db.SubCommands, &Command{
+			Name: "seed",
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		})
+		inv, err := ParseInvocation(root, []string{"db", "--region=eu", "seed"})
+		require.NoError(t, err)
+		require.Equal(t, "eu", GetFlag[string](inv.State(), "region"))
+
+		require.NoError(t, Parse(root, []string{"db", "seed"}))
+		require.Contains(t, DefaultUsage(root), "Global Flags:\n  -profile    credentials profile\n  -region     cloud region (default: us)")
+	})
+	t.Run("shadowed", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		root.SubCommands[0].SubCommands[0].Flags = FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "", "region")
+		})
+		err := Parse(root, []string{"db", "migrate"})
+		require.EqualError(t, err, `failed to parse: command ["app", "db", "migrate"]: flag -region shadows the persistent flag of command "app"`)
+	})
+	t.Run("not defined", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		root.FlagsMetadata = append(root.FlagsMetadata, FlagMetadata{Name: "zone", Persistent: true})
+		err := Parse(root, nil)
+		require.EqualError(t, err, `failed to parse: command ["app"]: persistent flag -zone is not defined`)
+	})
+	t.Run("docs", func(t *testing.T) {
+		t.Parallel()
+		var b strings.Builder
+		require.NoError(t, GenerateDocs(&b, newRoot(), "markdown"))
+		require.Contains(t, b.String(), "cloud region (default: us) (applies to all subcommands)")
+		require.NotContains(t, b.String(), "credentials profile (applies")
+	})
+}
+
+func TestFlagRequires(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name: "serve",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("tls-cert", "", "TLS certificate file")
+						f.String("tls-key", "", "TLS key file")
+						f.String("tls-ca", "", "TLS CA file")
+					}),
+					FlagsMetadata: []FlagMetadata{
+						{Name: "tls-cert", Requires: []string{"tls-key", "tls-ca"}, EnvVar: "APP_TLS_CERT"},
+					},
+					Exec: func(ctx context.Context, s *State) error { return nil },
+				},
+			},
+		}
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+	parseArgs := func(args []string, lookupEnv func(string) (string, bool)) error {
+		return parse(newRoot(), args, &parseConfig{lookupEnv: lookupEnv})
+	}
+
+	t.Run("satisfied", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, parseArgs([]string{"serve"}, noEnv))
+		require.NoError(t, parseArgs([]string{"serve", "--tls-key=k"}, noEnv))
+		require.NoError(t, parseArgs([]string{"serve", "--tls-cert=c", "--tls-key=k", "--tls-ca="}, noEnv))
+	})
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+		err := parseArgs([]string{"serve", "--tls-cert=c"}, noEnv)
+		require.EqualError(t, err, `command "app serve": flag -tls-cert requires -tls-key, -tls-ca`)
+		err = parseArgs([]string{"serve", "--tls-cert=c", "--tls-key=k"}, noEnv)
+		require.EqualError(t, err, `command "app serve": flag -tls-cert requires -tls-ca`)
+	})
+	t.Run("set from environment", func(t *testing.T) {
+		t.Parallel()
+		err := parseArgs([]string{"serve", "--tls-key=k"}, func(key string) (string, bool) {
+			return "c", key == "APP_TLS_CERT"
+		})
+		require.EqualError(t, err, `command "app serve": flag -tls-cert requires -tls-ca`)
+	})
+	t.Run("undefined", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		root.SubCommands[0].FlagsMetadata[0].Requires = []string{"tls-pass"}
+		err := parse(root, []string{"serve"}, &parseConfig{lookupEnv: noEnv})
+		require.EqualError(t, err, `command "app serve": internal error: flag -tls-cert requires undefined flag -tls-pass`)
+	})
+	t.Run("help and docs", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, parse(root, []string{"serve"}, &parseConfig{lookupEnv: noEnv}))
+		require.Contains(t, FormatUsage(root, &UsageOptions{Width: 120}), "TLS certificate file (env: APP_TLS_CERT) (requires: -tls-key, -tls-ca)")
+
+		var b strings.Builder
+		require.NoError(t, GenerateDocs(&b, newRoot(), "markdown"))
+		require.Contains(t, b.String(), "TLS certificate file (env: APP_TLS_CERT) (requires: -tls-key, -tls-ca)")
+	})
+}
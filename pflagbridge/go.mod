@@ -0,0 +1,18 @@
+module github.com/mfridman/cli/pflagbridge
+
+go 1.21.0
+
+require (
+	github.com/mfridman/cli v0.0.0-00010101000000-000000000000
+	github.com/spf13/pflag v1.0.9
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mfridman/xflag v0.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/mfridman/cli => ../
@@ -0,0 +1,156 @@
+// Package pflagbridge adapts flags defined with github.com/spf13/pflag for commands built with the
+// cli package, so libraries exporting pflag-based registration functions can be reused without
+// copying their flags by hand.
+//
+//	cmd := &cli.Command{
+//	    Name:          "serve",
+//	    Flags:         pflagbridge.Flags(server.RegisterFlags),
+//	    FlagsMetadata: pflagbridge.Metadata(server.RegisterFlags),
+//	}
+//
+// It is a separate module, so the cli package itself does not depend on pflag.
+package pflagbridge
+
+import (
+	"flag"
+
+	"github.com/mfridman/cli"
+	"github.com/spf13/pflag"
+)
+
+// Annotations set by cobra's MarkFlagRequired and MarkFlagFilename, which [Metadata] translates.
+const (
+	requiredAnnotation = "cobra_annotation_bash_completion_one_required_flag"
+	filenameAnnotation = "cobra_annotation_bash_completion_filename"
+)
+
+// Flags returns a standard library flag set with the flags that register defines on a pflag flag
+// set, for use as [cli.Command.Flags]. See [FlagSet].
+func Flags(register func(*pflag.FlagSet)) *flag.FlagSet {
+	pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	register(pfs)
+	return FlagSet(pfs)
+}
+
+// FlagSet returns a standard library flag set with the flags of pfs. The flags share their values
+// with pfs, so variables bound with pflag's XxxVar functions receive the parsed values, and
+// [cli.GetFlag] returns values of the same type as pflag's GetXxx methods, e.g., []string for a
+// string slice flag.
+//
+// A flag with a shorthand is also defined under its shorthand, e.g., -v for -verbose, unless
+// pfs defines a flag with that name. Flags with a NoOptDefVal, such as count flags, can be set
+// without a value like boolean flags. Deprecated flags have the deprecation notice appended to
+// their usage.
+func FlagSet(pfs *pflag.FlagSet) *flag.FlagSet {
+	fset := flag.NewFlagSet(pfs.Name(), flag.ContinueOnError)
+	pfs.VisitAll(func(f *pflag.Flag) {
+		usage := f.Usage
+		if f.Deprecated != "" {
+			usage += " (DEPRECATED: " + f.Deprecated + ")"
+		}
+		v := &value{Value: f.Value, pfs: pfs, name: f.Name, noOptDefVal: f.NoOptDefVal}
+		fset.Var(v, f.Name, usage)
+	})
+	pfs.VisitAll(func(f *pflag.Flag) {
+		if f.Shorthand == "" || fset.Lookup(f.Shorthand) != nil {
+			return
+		}
+		fset.Var(fset.Lookup(f.Name).Value, f.Shorthand, "shorthand for -"+f.Name)
+	})
+	return fset
+}
+
+// Metadata returns the [cli.FlagMetadata] of the flags that register defines on a pflag flag set,
+// taken from the annotations set by cobra: flags marked with MarkFlagRequired are required, and
+// flags marked with MarkFlagFilename complete file names with the given extensions. Flags without
+// such annotations are omitted.
+func Metadata(register func(*pflag.FlagSet)) []cli.FlagMetadata {
+	pfs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	register(pfs)
+	var metadata []cli.FlagMetadata
+	pfs.VisitAll(func(f *pflag.Flag) {
+		m := cli.FlagMetadata{Name: f.Name}
+		if values, ok := f.Annotations[requiredAnnotation]; ok && len(values) > 0 && values[0] == "true" {
+			m.Required = true
+		}
+		if exts, ok := f.Annotations[filenameAnnotation]; ok {
+			patterns := make([]string, len(exts))
+			for i, ext := range exts {
+				patterns[i] = "*." + ext
+			}
+			m.Complete = cli.CompleteFiles(patterns...)
+		}
+		if m.Required || m.Complete != nil {
+			metadata = append(metadata, m)
+		}
+	})
+	return metadata
+}
+
+// value adapts a pflag value to [flag.Getter].
+type value struct {
+	pflag.Value
+	pfs         *pflag.FlagSet
+	name        string
+	noOptDefVal string
+}
+
+// IsBoolFlag reports whether the flag can be set without a value, see [flag.Value].
+func (v *value) IsBoolFlag() bool {
+	return v.noOptDefVal != ""
+}
+
+func (v *value) Set(s string) error {
+	// The flag package sets flags without a value to "true", which pflag spells as NoOptDefVal.
+	if s == "true" && v.noOptDefVal != "" && v.Type() != "bool" {
+		s = v.noOptDefVal
+	}
+	return v.Value.Set(s)
+}
+
+// Get returns the value of the flag with the type returned by the matching GetXxx method of the
+// pflag flag set, or its string representation if there is none.
+func (v *value) Get() any {
+	var (
+		x   any
+		err error
+	)
+	switch v.Type() {
+	case "bool":
+		x, err = v.pfs.GetBool(v.name)
+	case "int":
+		x, err = v.pfs.GetInt(v.name)
+	case "int32":
+		x, err = v.pfs.GetInt32(v.name)
+	case "int64":
+		x, err = v.pfs.GetInt64(v.name)
+	case "uint":
+		x, err = v.pfs.GetUint(v.name)
+	case "uint64":
+		x, err = v.pfs.GetUint64(v.name)
+	case "float32":
+		x, err = v.pfs.GetFloat32(v.name)
+	case "float64":
+		x, err = v.pfs.GetFloat64(v.name)
+	case "string":
+		x, err = v.pfs.GetString(v.name)
+	case "duration":
+		x, err = v.pfs.GetDuration(v.name)
+	case "count":
+		x, err = v.pfs.GetCount(v.name)
+	case "stringSlice":
+		x, err = v.pfs.GetStringSlice(v.name)
+	case "stringArray":
+		x, err = v.pfs.GetStringArray(v.name)
+	case "intSlice":
+		x, err = v.pfs.GetIntSlice(v.name)
+	case "stringToString":
+		x, err = v.pfs.GetStringToString(v.name)
+	default:
+		return v.String()
+	}
+	if err != nil {
+		return v.String()
+	}
+	return x
+}
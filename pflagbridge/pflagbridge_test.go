@@ -0,0 +1,127 @@
+package pflagbridge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mfridman/cli"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+type serverOptions struct {
+	addr    string
+	verbose int
+	debug   bool
+	tags    []string
+	timeout time.Duration
+}
+
+func (o *serverOptions) RegisterFlags(f *pflag.FlagSet) {
+	f.StringVarP(&o.addr, "addr", "a", ":8080", "listen address")
+	f.CountVarP(&o.verbose, "verbose", "v", "increase verbosity")
+	f.BoolVar(&o.debug, "debug", false, "enable debug mode")
+	f.StringSliceVar(&o.tags, "tags", nil, "tags to apply")
+	f.DurationVar(&o.timeout, "timeout", 5*time.Second, "request timeout")
+	f.String("config", "", "config file")
+	f.String("legacy", "", "legacy option")
+	_ = f.MarkDeprecated("legacy", "use -config instead")
+	f.SetAnnotation("addr", requiredAnnotation, []string{"true"})
+	f.SetAnnotation("config", filenameAnnotation, []string{"yaml", "yml"})
+}
+
+func TestFlags(t *testing.T) {
+	t.Parallel()
+
+	t.Run("values", func(t *testing.T) {
+		t.Parallel()
+		var opts serverOptions
+		var got map[string]any
+		root := &cli.Command{
+			Name:  "serve",
+			Flags: Flags(opts.RegisterFlags),
+			Exec: func(ctx context.Context, s *cli.State) error {
+				got = map[string]any{
+					"addr":    cli.GetFlag[string](s, "addr"),
+					"verbose": cli.GetFlag[int](s, "verbose"),
+					"debug":   cli.GetFlag[bool](s, "debug"),
+					"tags":    cli.GetFlag[[]string](s, "tags"),
+					"timeout": cli.GetFlag[time.Duration](s, "timeout"),
+				}
+				return nil
+			},
+		}
+		args := []string{"-a", ":9090", "-v", "-verbose", "-debug", "-tags", "a,b", "-tags=c", "-timeout", "1m"}
+		require.NoError(t, cli.RunArgs(context.Background(), root, args, nil))
+		want := map[string]any{
+			"addr":    ":9090",
+			"verbose": 2,
+			"debug":   true,
+			"tags":    []string{"a", "b", "c"},
+			"timeout": time.Minute,
+		}
+		require.Equal(t, want, got)
+		require.Equal(t, serverOptions{
+			addr:    ":9090",
+			verbose: 2,
+			debug:   true,
+			tags:    []string{"a", "b", "c"},
+			timeout: time.Minute,
+		}, opts)
+	})
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		var opts serverOptions
+		fset := Flags(opts.RegisterFlags)
+		require.NoError(t, fset.Parse(nil))
+		require.Equal(t, ":8080", fset.Lookup("addr").DefValue)
+		require.Equal(t, ":8080", fset.Lookup("a").DefValue)
+		require.Equal(t, 5*time.Second, opts.timeout)
+	})
+	t.Run("usage", func(t *testing.T) {
+		t.Parallel()
+		var opts serverOptions
+		fset := Flags(opts.RegisterFlags)
+		require.Equal(t, "shorthand for -verbose", fset.Lookup("v").Usage)
+		require.Equal(t, "legacy option (DEPRECATED: use -config instead)", fset.Lookup("legacy").Usage)
+	})
+	t.Run("shorthand conflict", func(t *testing.T) {
+		t.Parallel()
+		fset := Flags(func(f *pflag.FlagSet) {
+			f.BoolP("all", "a", false, "all")
+			f.Bool("a", false, "a")
+		})
+		require.Equal(t, "a", fset.Lookup("a").Usage)
+	})
+	t.Run("invalid value", func(t *testing.T) {
+		t.Parallel()
+		var opts serverOptions
+		fset := Flags(opts.RegisterFlags)
+		require.Error(t, fset.Parse([]string{"-timeout", "soon"}))
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+
+	var opts serverOptions
+	metadata := Metadata(opts.RegisterFlags)
+	require.Len(t, metadata, 2)
+	require.Equal(t, "addr", metadata[0].Name)
+	require.True(t, metadata[0].Required)
+	require.Nil(t, metadata[0].Complete)
+	require.Equal(t, "config", metadata[1].Name)
+	require.False(t, metadata[1].Required)
+	require.NotNil(t, metadata[1].Complete)
+
+	root := &cli.Command{
+		Name:          "serve",
+		Flags:         Flags(opts.RegisterFlags),
+		FlagsMetadata: metadata,
+		Exec:          func(ctx context.Context, s *cli.State) error { return nil },
+	}
+	err := cli.RunArgs(context.Background(), root, nil, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "addr")
+}
@@ -0,0 +1,105 @@
+// Package style provides minimal, terminal-aware text styling using ANSI escape sequences. It is
+// used by the cli package to colorize help output and is exposed so applications can style their
+// own output consistently, including support for the NO_COLOR convention (https://no-color.org).
+package style
+
+import (
+	"io"
+	"os"
+)
+
+// Style is an ANSI SGR escape sequence, such as "\x1b[1m" for bold.
+type Style string
+
+// Common styles.
+const (
+	Bold      Style = "\x1b[1m"
+	Dim       Style = "\x1b[2m"
+	Underline Style = "\x1b[4m"
+	Red       Style = "\x1b[31m"
+	Green     Style = "\x1b[32m"
+	Yellow    Style = "\x1b[33m"
+	Blue      Style = "\x1b[34m"
+	Cyan      Style = "\x1b[36m"
+)
+
+const reset = "\x1b[0m"
+
+// Apply wraps text in the style, resetting it afterwards. Empty styles and empty text are returned
+// as is.
+func (s Style) Apply(text string) string {
+	if s == "" || text == "" {
+		return text
+	}
+	return string(s) + text + reset
+}
+
+// Theme is a set of styles for the elements of help output.
+type Theme struct {
+	// Heading styles section headings, such as "Usage:" and "Flags:".
+	Heading Style
+	// Command styles command names.
+	Command Style
+	// Flag styles flag names.
+	Flag Style
+}
+
+// DefaultTheme returns the default theme: bold headings, cyan command names and yellow flag names.
+func DefaultTheme() *Theme {
+	return &Theme{
+		Heading: Bold,
+		Command: Cyan,
+		Flag:    Yellow,
+	}
+}
+
+// Enabled reports whether styled output should be written to w. It returns false if the NO_COLOR
+// environment variable is set or if w is not a terminal.
+func Enabled(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Styler applies styles to text destined for a specific writer. If styling is disabled for the
+// writer, text is returned unchanged.
+//
+//	st := style.NewStyler(os.Stdout)
+//	fmt.Println(st.Bold("done"), st.Dim("(3 files)"))
+type Styler struct {
+	enabled bool
+}
+
+// NewStyler returns a Styler for w. Styling is enabled if [Enabled] reports true for w.
+func NewStyler(w io.Writer) Styler {
+	return Styler{enabled: Enabled(w)}
+}
+
+// Enabled reports whether the Styler applies styles.
+func (s Styler) Enabled() bool { return s.enabled }
+
+// Apply applies the style to text if styling is enabled.
+func (s Styler) Apply(st Style, text string) string {
+	if !s.enabled {
+		return text
+	}
+	return st.Apply(text)
+}
+
+// Bold returns text in bold if styling is enabled.
+func (s Styler) Bold(text string) string { return s.Apply(Bold, text) }
+
+// Dim returns text dimmed if styling is enabled.
+func (s Styler) Dim(text string) string { return s.Apply(Dim, text) }
+
+// Underline returns text underlined if styling is enabled.
+func (s Styler) Underline(text string) string { return s.Apply(Underline, text) }
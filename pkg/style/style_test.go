@@ -0,0 +1,63 @@
+package style
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "\x1b[1mbold\x1b[0m", Bold.Apply("bold"))
+	assert.Equal(t, "plain", Style("").Apply("plain"))
+	assert.Equal(t, "", Bold.Apply(""))
+}
+
+func TestStyler(t *testing.T) {
+	t.Parallel()
+
+	disabled := NewStyler(&bytes.Buffer{})
+	assert.False(t, disabled.Enabled())
+	assert.Equal(t, "text", disabled.Bold("text"))
+	assert.Equal(t, "text", disabled.Apply(Red, "text"))
+
+	enabled := Styler{enabled: true}
+	assert.Equal(t, "\x1b[2mtext\x1b[0m", enabled.Dim("text"))
+	assert.Equal(t, "\x1b[4mtext\x1b[0m", enabled.Underline("text"))
+	assert.Equal(t, "\x1b[31mtext\x1b[0m", enabled.Apply(Red, "text"))
+}
+
+func TestEnabled(t *testing.T) {
+	assert.False(t, Enabled(&bytes.Buffer{}))
+	t.Setenv("NO_COLOR", "")
+	assert.False(t, Enabled(os.Stdout))
+}
+
+func TestTerminalWidth(t *testing.T) {
+	t.Setenv("COLUMNS", "")
+	_, ok := TerminalWidth(&bytes.Buffer{})
+	assert.False(t, ok)
+
+	t.Setenv("COLUMNS", "132")
+	width, ok := TerminalWidth(&bytes.Buffer{})
+	assert.True(t, ok)
+	assert.Equal(t, 132, width)
+
+	t.Setenv("COLUMNS", "invalid")
+	_, ok = TerminalWidth(&bytes.Buffer{})
+	assert.False(t, ok)
+}
+
+func TestTerminalHeight(t *testing.T) {
+	t.Setenv("LINES", "")
+	_, ok := TerminalHeight(&bytes.Buffer{})
+	assert.False(t, ok)
+
+	t.Setenv("LINES", "40")
+	height, ok := TerminalHeight(&bytes.Buffer{})
+	assert.True(t, ok)
+	assert.Equal(t, 40, height)
+}
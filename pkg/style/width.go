@@ -0,0 +1,37 @@
+package style
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// TerminalWidth returns the width, in columns, of the terminal w is attached to. The COLUMNS
+// environment variable takes precedence if set to a positive integer. It returns false if the width
+// cannot be determined, e.g., because w is not a terminal.
+func TerminalWidth(w io.Writer) (int, bool) {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n, true
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	cols, _, ok := terminalSize(f)
+	return cols, ok
+}
+
+// TerminalHeight returns the height, in rows, of the terminal w is attached to. The LINES
+// environment variable takes precedence if set to a positive integer. It returns false if the
+// height cannot be determined, e.g., because w is not a terminal.
+func TerminalHeight(w io.Writer) (int, bool) {
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		return n, true
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0, false
+	}
+	_, rows, ok := terminalSize(f)
+	return rows, ok
+}
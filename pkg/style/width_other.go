@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package style
+
+import "os"
+
+func terminalSize(*os.File) (cols, rows int, ok bool) {
+	return 0, 0, false
+}
@@ -1,15 +1,53 @@
 package suggest
 
 import (
+	"flag"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
-// threshold is the minimum similarity score required for a string to be considered similar.
-const threshold = 0.5
+const (
+	// DefaultThreshold is the minimum similarity score a candidate must exceed to be suggested, when
+	// no threshold is configured.
+	DefaultThreshold = 0.5
+	// DefaultMaxResults is the maximum number of suggestions returned by [Config.Find], when no
+	// maximum is configured.
+	DefaultMaxResults = 3
+)
 
-// FindSimilar returns a list of similar strings to the target string from a list of candidates.
-func FindSimilar(target string, candidates []string, maxResults int) []string {
+// Scorer returns a similarity score between 0 and 1 for a target and a candidate, where 1 is an
+// exact match.
+type Scorer func(target, candidate string) float64
+
+// Config configures how suggestions are found. The zero value, or a nil *Config, uses the default
+// threshold, maximum number of results and scorer.
+type Config struct {
+	// Threshold is the minimum similarity score a candidate must exceed to be suggested. Defaults to
+	// [DefaultThreshold].
+	Threshold float64
+	// MaxResults is the maximum number of suggestions returned. Defaults to [DefaultMaxResults]. A
+	// negative value disables suggestions.
+	MaxResults int
+	// Scorer replaces the default scorer, [Similarity], e.g., with a Jaro-Winkler implementation.
+	Scorer Scorer
+}
+
+// Find returns the candidates that are similar to the target, most similar first. Candidates with
+// the same score are sorted by name.
+func (c *Config) Find(target string, candidates []string) []string {
+	threshold, maxResults, scorer := DefaultThreshold, DefaultMaxResults, Scorer(Similarity)
+	if c != nil {
+		if c.Threshold != 0 {
+			threshold = c.Threshold
+		}
+		if c.MaxResults != 0 {
+			maxResults = c.MaxResults
+		}
+		if c.Scorer != nil {
+			scorer = c.Scorer
+		}
+	}
 	// Early returns for invalid inputs
 	if target == "" || maxResults <= 0 {
 		return []string{}
@@ -22,7 +60,7 @@ func FindSimilar(target string, candidates []string, maxResults int) []string {
 
 	// Calculate similarity scores
 	for _, name := range candidates {
-		score := calculateSimilarity(target, name)
+		score := scorer(target, name)
 		if score > threshold { // Only include reasonably similar commands
 			suggestions = append(suggestions, struct {
 				name  string
@@ -47,6 +85,63 @@ func FindSimilar(target string, candidates []string, maxResults int) []string {
 	return result
 }
 
+// FindFlags returns the flag names from the flag set that are similar to the target flag. The
+// target may include leading dashes and a value (e.g., "--verbos" or "-outptu=file"), which are
+// ignored for matching. Suggestions are returned with the same dash prefix as the target, or a
+// single dash if the target has none.
+//
+// Single-character (short) flag names are only suggested for single-character targets, where they
+// match case-insensitively, e.g., "-V" suggests "-v".
+func (c *Config) FindFlags(target string, fset *flag.FlagSet) []string {
+	if fset == nil {
+		return []string{}
+	}
+	name := strings.TrimLeft(target, "-")
+	dashes := target[:len(target)-len(name)]
+	if dashes == "" {
+		dashes = "-"
+	}
+	name, _, _ = strings.Cut(name, "=")
+	isShort := len(name) == 1
+
+	var candidates []string
+	fset.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 && !isShort {
+			return
+		}
+		candidates = append(candidates, f.Name)
+	})
+	suggestions := c.Find(name, candidates)
+	for i, s := range suggestions {
+		suggestions[i] = dashes + s
+	}
+	return suggestions
+}
+
+// FindSimilar returns a list of similar strings to the target string from a list of candidates,
+// using the default threshold and scorer. See [Config.Find].
+func FindSimilar(target string, candidates []string, maxResults int) []string {
+	if maxResults <= 0 {
+		return []string{}
+	}
+	return (&Config{MaxResults: maxResults}).Find(target, candidates)
+}
+
+// FindSimilarFlags returns a list of flag names from the flag set that are similar to the target
+// flag, using the default threshold and scorer. See [Config.FindFlags].
+func FindSimilarFlags(target string, fset *flag.FlagSet, maxResults int) []string {
+	if maxResults <= 0 {
+		return []string{}
+	}
+	return (&Config{MaxResults: maxResults}).FindFlags(target, fset)
+}
+
+// Similarity is the default [Scorer]. It is case-insensitive, scores a candidate the target is a
+// prefix of 0.9, and otherwise scores by Levenshtein distance relative to the longer string.
+func Similarity(a, b string) float64 {
+	return calculateSimilarity(a, b)
+}
+
 func calculateSimilarity(a, b string) float64 {
 	a = strings.ToLower(a)
 	b = strings.ToLower(b)
@@ -61,7 +156,7 @@ func calculateSimilarity(a, b string) float64 {
 	}
 	// Calculate Levenshtein distance
 	distance := levenshteinDistance(a, b)
-	maxLen := float64(max(len(a), len(b)))
+	maxLen := float64(max(utf8.RuneCountInString(a), utf8.RuneCountInString(b)))
 
 	// Convert distance to similarity score (0 to 1)
 	similarity := 1.0 - float64(distance)/maxLen
@@ -69,38 +164,37 @@ func calculateSimilarity(a, b string) float64 {
 	return similarity
 }
 
+// levenshteinDistance returns the number of single-rune insertions, deletions and substitutions
+// needed to turn a into b. It keeps only two rows of the distance matrix, sized by the shorter
+// string.
 func levenshteinDistance(a, b string) int {
-	if len(a) == 0 {
-		return len(b)
-	}
-	if len(b) == 0 {
-		return len(a)
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
 	}
-
-	matrix := make([][]int, len(a)+1)
-	for i := range matrix {
-		matrix[i] = make([]int, len(b)+1)
+	if len(rb) == 0 {
+		return len(ra)
 	}
 
-	for i := 0; i <= len(a); i++ {
-		matrix[i][0] = i
-	}
-	for j := 0; j <= len(b); j++ {
-		matrix[0][j] = j
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
 	}
-
-	for i := 1; i <= len(a); i++ {
-		for j := 1; j <= len(b); j++ {
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
 			cost := 1
-			if a[i-1] == b[j-1] {
+			if ra[i-1] == rb[j-1] {
 				cost = 0
 			}
-			matrix[i][j] = min(
-				matrix[i-1][j]+1, // deletion
-				min(matrix[i][j-1]+1, // insertion
-					matrix[i-1][j-1]+cost)) // substitution
+			curr[j] = min(
+				prev[j]+1,      // deletion
+				curr[j-1]+1,    // insertion
+				prev[j-1]+cost, // substitution
+			)
 		}
+		prev, curr = curr, prev
 	}
-
-	return matrix[len(a)][len(b)]
+	return prev[len(rb)]
 }
@@ -1,6 +1,8 @@
 package suggest
 
 import (
+	"flag"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -103,6 +105,12 @@ func TestCalculateSimilarity(t *testing.T) {
 			b:        "",
 			expected: 0.0,
 		},
+		{
+			name:     "non-ASCII strings",
+			a:        "überprüfen",
+			b:        "uberprüfen",
+			expected: 0.9, // one substitution out of 10 runes, not 12 bytes
+		},
 	}
 
 	for _, tt := range tests {
@@ -170,6 +178,30 @@ func TestLevenshteinDistance(t *testing.T) {
 			b:        "world",
 			expected: 4,
 		},
+		{
+			name:     "shorter first string",
+			a:        "sit",
+			b:        "kitten",
+			expected: 4,
+		},
+		{
+			name:     "non-ASCII substitution",
+			a:        "café",
+			b:        "cafe",
+			expected: 1,
+		},
+		{
+			name:     "non-ASCII strings",
+			a:        "über",
+			b:        "übel",
+			expected: 1,
+		},
+		{
+			name:     "multi-byte insertion",
+			a:        "日本",
+			b:        "日本語",
+			expected: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -179,3 +211,142 @@ func TestLevenshteinDistance(t *testing.T) {
 		})
 	}
 }
+
+func TestFindSimilarFlags(t *testing.T) {
+	t.Parallel()
+
+	fset := flag.NewFlagSet("test", flag.ContinueOnError)
+	fset.Bool("verbose", false, "")
+	fset.Bool("version", false, "")
+	fset.String("output", "", "")
+	fset.Bool("v", false, "")
+	fset.Bool("o", false, "")
+
+	tests := []struct {
+		name       string
+		target     string
+		maxResults int
+		expected   []string
+	}{
+		{
+			name:       "double dash",
+			target:     "--verbos",
+			maxResults: 3,
+			expected:   []string{"--verbose", "--version"},
+		},
+		{
+			name:       "single dash",
+			target:     "-outptu",
+			maxResults: 3,
+			expected:   []string{"-output"},
+		},
+		{
+			name:       "no dash",
+			target:     "outptu",
+			maxResults: 3,
+			expected:   []string{"-output"},
+		},
+		{
+			name:       "with value",
+			target:     "--outptu=file.txt",
+			maxResults: 3,
+			expected:   []string{"--output"},
+		},
+		{
+			name:       "short name",
+			target:     "-V",
+			maxResults: 3,
+			expected:   []string{"-v", "-verbose", "-version"},
+		},
+		{
+			name:       "short names not suggested for long targets",
+			target:     "-ou",
+			maxResults: 3,
+			expected:   []string{"-output"},
+		},
+		{
+			name:       "no match",
+			target:     "--zzz",
+			maxResults: 3,
+			expected:   []string{},
+		},
+		{
+			name:       "max results",
+			target:     "--verbos",
+			maxResults: 1,
+			expected:   []string{"--verbose"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FindSimilarFlags(tt.target, fset, tt.maxResults)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+	assert.Equal(t, []string{}, FindSimilarFlags("--verbose", nil, 3))
+}
+
+func TestConfig(t *testing.T) {
+	candidates := []string{"status", "start", "stop", "stash"}
+
+	tests := []struct {
+		name     string
+		config   *Config
+		target   string
+		expected []string
+	}{
+		{
+			name:     "nil config uses defaults",
+			config:   nil,
+			target:   "stat",
+			expected: []string{"status", "start", "stash"},
+		},
+		{
+			name:     "max results",
+			config:   &Config{MaxResults: 1},
+			target:   "stat",
+			expected: []string{"status"},
+		},
+		{
+			name:     "negative max results disables suggestions",
+			config:   &Config{MaxResults: -1},
+			target:   "stat",
+			expected: []string{},
+		},
+		{
+			name:     "threshold",
+			config:   &Config{Threshold: 0.85},
+			target:   "stat",
+			expected: []string{"status"},
+		},
+		{
+			name: "custom scorer",
+			config: &Config{Scorer: func(target, candidate string) float64 {
+				if candidate[0] == target[0] && candidate[len(candidate)-1] == target[len(target)-1] {
+					return 1
+				}
+				return 0
+			}},
+			target:   "sp",
+			expected: []string{"stop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.config.Find(tt.target, candidates))
+		})
+	}
+}
+
+func BenchmarkFind(b *testing.B) {
+	candidates := make([]string, 0, 200)
+	for i := 0; i < 200; i++ {
+		candidates = append(candidates, fmt.Sprintf("command-name-%03d", i))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		FindSimilar("comand-nme-042", candidates, 3)
+	}
+}
@@ -2,33 +2,118 @@ package textutil
 
 import "strings"
 
+// Wrap splits text into lines of at most width terminal columns, breaking at whitespace. Runs of
+// whitespace are collapsed into single spaces. Widths are measured with [Width], so wide characters,
+// such as CJK ideographs, count as two columns. Words wider than width are broken into pieces.
 func Wrap(text string, width int) []string {
-	words := strings.Fields(text)
+	width = max(width, 1)
 	var (
-		lines         []string
-		currentLine   []string
-		currentLength int
+		lines       []string
+		line        strings.Builder
+		lineWidth   int
+		lineStarted bool
 	)
-	for _, word := range words {
-		if currentLength+len(word)+1 > width {
-			if len(currentLine) > 0 {
-				lines = append(lines, strings.Join(currentLine, " "))
-				currentLine = []string{word}
-				currentLength = len(word)
-			} else {
-				lines = append(lines, word)
-			}
-		} else {
-			currentLine = append(currentLine, word)
-			if currentLength == 0 {
-				currentLength = len(word)
-			} else {
-				currentLength += len(word) + 1
-			}
+	flush := func() {
+		if lineStarted {
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth, lineStarted = 0, false
 		}
 	}
-	if len(currentLine) > 0 {
-		lines = append(lines, strings.Join(currentLine, " "))
+	for _, word := range strings.Fields(text) {
+		w := Width(word)
+		if lineStarted && lineWidth+1+w <= width {
+			line.WriteString(" " + word)
+			lineWidth += 1 + w
+			continue
+		}
+		flush()
+		for w > width {
+			var head string
+			head, word = breakWord(word, width)
+			lines = append(lines, head)
+			w = Width(word)
+		}
+		line.WriteString(word)
+		lineWidth, lineStarted = w, true
 	}
+	flush()
 	return lines
 }
+
+// minColumnWidth is the minimum width of the second column of [Columns], so text remains readable
+// next to long first column cells on narrow terminals.
+const minColumnWidth = 20
+
+// Columns lays out rows as two aligned columns. The first column is padded to its widest cell plus
+// gutter spaces, and the second column is wrapped with [Wrap] to the remaining width, but to no less
+// than 20 columns, with continuation lines indented to the second column. A row with an empty
+// second cell is rendered as its first cell only. Every line ends with a newline.
+//
+// Widths are measured with [Width], so cells may contain wide characters or be colorized.
+func Columns(rows [][2]string, width, gutter int) string {
+	leftWidth := 0
+	for _, row := range rows {
+		leftWidth = max(leftWidth, Width(row[0]))
+	}
+	leftWidth += gutter
+	wrapWidth := max(width-leftWidth, minColumnWidth)
+	indent := strings.Repeat(" ", leftWidth)
+
+	var b strings.Builder
+	for _, row := range rows {
+		lines := Wrap(row[1], wrapWidth)
+		if len(lines) == 0 {
+			b.WriteString(strings.TrimRight(row[0], " "))
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(row[0])
+		b.WriteString(strings.Repeat(" ", leftWidth-Width(row[0])))
+		b.WriteString(lines[0])
+		b.WriteString("\n")
+		for _, line := range lines[1:] {
+			b.WriteString(indent + line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// WrapParagraphs wraps each paragraph of text, separated by blank lines, with [Wrap] and joins them
+// with blank lines. Paragraphs with an indented line, such as code examples or lists, are kept
+// as is, apart from trailing whitespace.
+func WrapParagraphs(text string, width int) string {
+	var paragraphs []string
+	for _, p := range splitParagraphs(text) {
+		if strings.Contains("\n"+p, "\n ") || strings.Contains("\n"+p, "\n\t") {
+			paragraphs = append(paragraphs, p)
+			continue
+		}
+		paragraphs = append(paragraphs, strings.Join(Wrap(p, width), "\n"))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// splitParagraphs returns the paragraphs of text, separated by lines that are empty or contain
+// only whitespace, with trailing whitespace removed from every line.
+func splitParagraphs(text string) []string {
+	var (
+		paragraphs []string
+		current    []string
+	)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line != "" {
+			current = append(current, line)
+			continue
+		}
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, "\n"))
+	}
+	return paragraphs
+}
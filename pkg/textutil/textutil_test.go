@@ -41,7 +41,31 @@ func TestWrapText(t *testing.T) {
 			name:     "single word longer than width",
 			text:     "supercalifragilistic",
 			width:    10,
-			expected: []string{"supercalifragilistic"},
+			expected: []string{"supercalif", "ragilistic"},
+		},
+		{
+			name:     "long word after short words",
+			text:     "see https://example.com/docs/flags",
+			width:    15,
+			expected: []string{"see", "https://example", ".com/docs/flags"},
+		},
+		{
+			name:     "wide characters",
+			text:     "部署 应用 到 生产 环境",
+			width:    10,
+			expected: []string{"部署 应用", "到 生产", "环境"},
+		},
+		{
+			name:     "wide word longer than width",
+			text:     "部署应用到生产环境",
+			width:    7,
+			expected: []string{"部署应", "用到生", "产环境"},
+		},
+		{
+			name:     "combining marks",
+			text:     "cafe\u0301 cafe\u0301",
+			width:    9,
+			expected: []string{"cafe\u0301 cafe\u0301"},
 		},
 		{
 			name:     "multiple spaces",
@@ -58,3 +82,140 @@ func TestWrapText(t *testing.T) {
 		})
 	}
 }
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     [][2]string
+		width    int
+		gutter   int
+		expected string
+	}{
+		{
+			name:     "aligned",
+			rows:     [][2]string{{"add", "add an item"}, {"remove", "remove an item"}},
+			width:    80,
+			gutter:   2,
+			expected: "add     add an item\nremove  remove an item\n",
+		},
+		{
+			name:     "wrapped with continuation indent",
+			rows:     [][2]string{{"-name", "this is a long text that needs wrapping"}},
+			width:    27,
+			gutter:   2,
+			expected: "-name  this is a long text\n       that needs wrapping\n",
+		},
+		{
+			name:     "empty second column",
+			rows:     [][2]string{{"add", ""}, {"remove", "remove an item"}},
+			width:    80,
+			gutter:   4,
+			expected: "add\nremove    remove an item\n",
+		},
+		{
+			name:     "minimum second column width",
+			rows:     [][2]string{{"-a-very-long-flag-name", "this is a long text that needs wrapping"}},
+			width:    10,
+			gutter:   1,
+			expected: "-a-very-long-flag-name this is a long text\n                       that needs wrapping\n",
+		},
+		{
+			name:     "colored first column",
+			rows:     [][2]string{{"\x1b[1madd\x1b[0m", "add an item"}, {"remove", "remove an item"}},
+			width:    80,
+			gutter:   2,
+			expected: "\x1b[1madd\x1b[0m     add an item\nremove  remove an item\n",
+		},
+		{
+			name:     "no rows",
+			width:    80,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Columns(tt.rows, tt.width, tt.gutter)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"héllo", 5},
+		{"cafe\u0301", 4},
+		{"部署", 4},
+		{"ｈｉ", 4},
+		{"👍", 2},
+		{"👩\u200d💻", 2},
+		{"\x1b[1mbold\x1b[0m", 4},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Width(tt.text), "width of %q", tt.text)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected string
+	}{
+		{"hello", 5, "hello"},
+		{"hello", 4, "hel…"},
+		{"hello", 1, "…"},
+		{"hello", 0, ""},
+		{"部署完成", 5, "部署…"},
+		{"部署完成", 4, "部…"},
+		{"\x1b[1mbold\x1b[0m", 3, "\x1b[1mbo…\x1b[0m"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Truncate(tt.text, tt.width), "truncate %q to %d", tt.text, tt.width)
+	}
+}
+
+func TestWrapParagraphs(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected string
+	}{
+		{
+			name:     "single paragraph",
+			text:     "this is a long text\nthat needs wrapping",
+			width:    10,
+			expected: "this is a\nlong text\nthat needs\nwrapping",
+		},
+		{
+			name:     "blank lines kept",
+			text:     "\nfirst paragraph\n\n  \n\nsecond paragraph\n",
+			width:    10,
+			expected: "first\nparagraph\n\nsecond\nparagraph",
+		},
+		{
+			name:     "indented paragraph kept",
+			text:     "example:\n\n  app deploy --replicas=3   \n  app status",
+			width:    10,
+			expected: "example:\n\n  app deploy --replicas=3\n  app status",
+		},
+		{
+			name:     "empty",
+			text:     "",
+			width:    10,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, WrapParagraphs(tt.text, tt.width))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Width returns the number of columns s occupies in a terminal. Wide characters, such as CJK
+// ideographs and most emoji, count as two columns, combining marks and other zero-width characters,
+// including those joined to the previous character by a zero-width joiner, count as none, and ANSI
+// escape sequences are ignored.
+func Width(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		end, w := nextCell(s, i)
+		n += w
+		i = end
+	}
+	return n
+}
+
+// breakWord splits word after as many characters as fit in width columns, but at least one, so
+// the head is never empty. Zero-width characters stay with the character before them.
+func breakWord(word string, width int) (head, tail string) {
+	n := 0
+	for i := 0; i < len(word); {
+		end, w := nextCell(word, i)
+		if n+w > width && n > 0 {
+			return word[:i], word[i:]
+		}
+		n += w
+		i = end
+	}
+	return word, ""
+}
+
+// nextCell returns the end of the character or ANSI escape sequence starting at s[i], and the
+// number of columns it occupies. A character after a zero-width joiner is part of the same
+// grapheme cluster and occupies no columns of its own.
+func nextCell(s string, i int) (end, width int) {
+	if end := ansiSequenceEnd(s, i); end > i {
+		return end, 0
+	}
+	r, size := utf8.DecodeRuneInString(s[i:])
+	if r == zeroWidthJoiner {
+		if _, next := utf8.DecodeRuneInString(s[i+size:]); next > 0 {
+			size += next
+		}
+		return i + size, 0
+	}
+	return i + size, runeWidth(r)
+}
+
+const zeroWidthJoiner = '\u200d'
+
+// runeWidth returns the number of columns r occupies in a terminal.
+func runeWidth(r rune) int {
+	switch {
+	case r == 0 || r == '\u200b' || r == zeroWidthJoiner:
+		return 0
+	case r >= 0xfe00 && r <= 0xfe0f, r >= 0xe0100 && r <= 0xe01ef:
+		// Variation selectors.
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWide(r):
+		return 2
+	}
+	return 1
+}
+
+// wideRanges are the ranges of characters with East Asian Width W (wide) or F (fullwidth), as
+// defined by Unicode Standard Annex #11, sorted by their start.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec}, {0x23f0, 0x23f0},
+	{0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267f, 0x267f},
+	{0x2693, 0x2693}, {0x26a1, 0x26a1}, {0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5},
+	{0x26ce, 0x26ce}, {0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b}, {0x2728, 0x2728},
+	{0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27b0, 0x27b0}, {0x27bf, 0x27bf}, {0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55},
+	{0x2e80, 0x303e}, {0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19}, {0xfe30, 0xfe6f},
+	{0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4}, {0x17000, 0x18cff}, {0x1b000, 0x1b2ff},
+	{0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf}, {0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a},
+	{0x1f200, 0x1f251}, {0x1f300, 0x1f320}, {0x1f32d, 0x1f335}, {0x1f337, 0x1f37c},
+	{0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca}, {0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0},
+	{0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e}, {0x1f440, 0x1f440}, {0x1f442, 0x1f4fc},
+	{0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e}, {0x1f550, 0x1f567}, {0x1f57a, 0x1f57a},
+	{0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4}, {0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5},
+	{0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2}, {0x1f6d5, 0x1f6d7}, {0x1f6eb, 0x1f6ec},
+	{0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb}, {0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945},
+	{0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff}, {0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+func isWide(r rune) bool {
+	lo, hi := 0, len(wideRanges)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < wideRanges[mid][0]:
+			hi = mid
+		case r > wideRanges[mid][1]:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// ansiSequenceEnd returns the index after the ANSI CSI escape sequence starting at s[i], such as
+// "\x1b[1;34m", or i if there is none.
+func ansiSequenceEnd(s string, i int) int {
+	if !strings.HasPrefix(s[i:], "\x1b[") {
+		return i
+	}
+	for j := i + 2; j < len(s); j++ {
+		if c := s[j]; c >= 0x40 && c <= 0x7e {
+			return j + 1
+		}
+	}
+	return i
+}
+
+// Truncate shortens s to at most width columns, as measured by [Width], replacing the removed
+// characters with an ellipsis. ANSI escape sequences are kept, so truncated colored text is still
+// reset. If s fits in width columns, it is returned unchanged.
+func Truncate(s string, width int) string {
+	if Width(s) <= width {
+		return s
+	}
+	const ellipsis = "…"
+	budget := width - 1
+	var b strings.Builder
+	truncated := false
+	for i := 0; i < len(s); {
+		end, w := nextCell(s, i)
+		switch {
+		case w == 0 && (!truncated || ansiSequenceEnd(s, i) > i):
+			b.WriteString(s[i:end])
+		case !truncated && w <= budget:
+			b.WriteString(s[i:end])
+			budget -= w
+		case !truncated:
+			if width > 0 {
+				b.WriteString(ellipsis)
+			}
+			truncated = true
+		}
+		i = end
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// pluginPrefix returns the prefix of the executables that provide plugins for root, e.g., "git-".
+func pluginPrefix(root *Command) string {
+	return root.Name + "-"
+}
+
+// ListPlugins returns the names of the plugins for root discovered on PATH, sorted and without
+// duplicates. A plugin is an executable named "<root>-<name>", e.g., "app-foo" provides the "foo"
+// plugin for "app". Plugins shadowed by a subcommand of root are omitted. A root command without
+// subcommands takes positional arguments instead, so it has no plugins. See [Options.Plugins].
+func ListPlugins(root *Command) []string {
+	if len(root.SubCommands) == 0 {
+		return nil
+	}
+	prefix := pluginPrefix(root)
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), prefix)
+			if !ok || entry.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				ext := filepath.Ext(name)
+				if !strings.EqualFold(ext, ".exe") {
+					continue
+				}
+				name = strings.TrimSuffix(name, ext)
+			} else if info, err := entry.Info(); err != nil || info.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+			if isValidName(name) && root.findSubCommand(name) == nil && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// lookupPlugin returns the path of the plugin executable for the first argument, if the argument is
+// not a subcommand of root. Arguments that are not valid command names, e.g., "../foo", are never
+// looked up, so that a plugin cannot be resolved relative to the working directory.
+func lookupPlugin(root *Command, args []string) (string, bool) {
+	if len(args) == 0 || len(root.SubCommands) == 0 || !isValidName(args[0]) {
+		return "", false
+	}
+	if root.findSubCommand(args[0]) != nil {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix(root) + args[0])
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// runPlugin executes the plugin at path with args, connected to the standard streams of the
+// options. If the plugin exits with a non-zero exit code, an [ExitError] holding it is returned.
+// A nil ctx is treated as [context.Background].
+func runPlugin(ctx context.Context, path string, args []string, options *RunOptions) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = options.Stdin
+	cmd.Stdout = options.Stdout
+	cmd.Stderr = options.Stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitErr.ExitCode(), Err: err}
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugins(t *testing.T) {
+	// Not parallel, PATH is modified.
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	writeScript := func(name, script string, mode os.FileMode) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), mode))
+	}
+	writeScript("app-hello", "echo \"hello $*\"\n", 0o755)
+	writeScript("app-fail", "echo oops >&2\nexit 3\n", 0o755)
+	writeScript("app-noexec", "echo unreachable\n", 0o644)
+	writeScript("app-run", "echo shadowed\n", 0o755)
+	writeScript("app-bad.name", "echo invalid\n", 0o755)
+	t.Setenv("PATH", dir)
+
+	newRoot := func() *Command {
+		exec := func(ctx context.Context, s *State) error { return nil }
+		return &Command{
+			Name:        "app",
+			SubCommands: []*Command{{Name: "run", Exec: exec}},
+			Exec:        exec,
+		}
+	}
+	run := func(args ...string) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:       args,
+			Plugins:    true,
+			RunOptions: RunOptions{Stdout: &stdout, Stderr: &stderr},
+		})
+		return stdout.String(), stderr.String(), err
+	}
+
+	require.Equal(t, []string{"fail", "hello"}, ListPlugins(newRoot()))
+
+	t.Run("dispatch", func(t *testing.T) {
+		stdout, _, err := run("hello", "world", "--flag")
+		require.NoError(t, err)
+		require.Equal(t, "hello world --flag\n", stdout)
+	})
+	t.Run("exit code", func(t *testing.T) {
+		_, stderr, err := run("fail")
+		var exitErr *ExitError
+		require.ErrorAs(t, err, &exitErr)
+		require.Equal(t, 3, exitErr.Code)
+		require.Equal(t, "oops\n", stderr)
+	})
+	t.Run("subcommand takes precedence", func(t *testing.T) {
+		stdout, _, err := run("run")
+		require.NoError(t, err)
+		require.Empty(t, stdout)
+	})
+	t.Run("help", func(t *testing.T) {
+		stdout, _, err := run("--help")
+		require.NoError(t, err)
+		require.Contains(t, stdout, "Available Plugins:\n  fail\n  hello\n")
+	})
+	t.Run("invalid name", func(t *testing.T) {
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.NoError(t, os.Chdir(dir))
+		t.Cleanup(func() { require.NoError(t, os.Chdir(wd)) })
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "app-sub"), 0o755))
+		writeScript(filepath.Join("app-sub", "evil"), "echo evil\n", 0o755)
+
+		stdout, _, err := run("sub/evil")
+		require.ErrorContains(t, err, `unknown command "sub/evil"`)
+		require.Empty(t, stdout)
+		_, ok := lookupPlugin(newRoot(), []string{"../app-hello"})
+		require.False(t, ok)
+	})
+	t.Run("nil context", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := ParseAndRun(nil, newRoot(), &Options{
+			Args:       []string{"hello"},
+			Plugins:    true,
+			RunOptions: RunOptions{Stdout: &stdout, Stderr: &bytes.Buffer{}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "hello \n", stdout.String())
+	})
+	t.Run("root without subcommands", func(t *testing.T) {
+		var args []string
+		root := &Command{Name: "app", Exec: func(ctx context.Context, s *State) error {
+			args = s.Args
+			return nil
+		}}
+		require.Empty(t, ListPlugins(root))
+		err := ParseAndRun(context.Background(), root, &Options{
+			Args:       []string{"hello"},
+			Plugins:    true,
+			RunOptions: RunOptions{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"hello"}, args)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:       []string{"hello"},
+			RunOptions: RunOptions{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}},
+		})
+		require.ErrorContains(t, err, `unknown command "hello"`)
+	})
+}
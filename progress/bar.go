@@ -0,0 +1,151 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// logStep is the percentage of progress between log lines when a bar is not drawn in place.
+const logStep = 25
+
+// Bar is a progress bar for work with a known total, such as a number of files or bytes. On a
+// terminal it is redrawn in place, e.g.,
+//
+//	uploading [===============>              ] 52% (13/25)
+//
+// Otherwise a log line is written at every quarter of progress, e.g., "uploading: 52% (13/25)".
+// If the total is unknown, zero or negative, only the count is shown, and it is logged only when
+// the bar is created and finished.
+//
+// A Bar is safe for concurrent use.
+type Bar struct {
+	mu      sync.Mutex
+	w       io.Writer
+	mode    mode
+	opts    options
+	total   int
+	current int
+	// last holds the last drawn line in terminal mode, or the last logged step in log mode.
+	last     string
+	lastStep int
+	finished bool
+}
+
+// NewBar returns a progress bar for total units of work writing to w, typically the Stderr of a
+// command, and draws it.
+func NewBar(w io.Writer, total int, opts ...Option) *Bar {
+	o := newOptions(opts)
+	return newBar(w, total, outputMode(w, o), o)
+}
+
+func newBar(w io.Writer, total int, mode mode, o options) *Bar {
+	b := &Bar{w: w, mode: mode, opts: o, total: total, lastStep: -1}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.draw()
+	return b
+}
+
+// Add advances the bar by n units.
+func (b *Bar) Add(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current += n
+	b.draw()
+}
+
+// Set sets the progress of the bar to n units.
+func (b *Bar) Set(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = n
+	b.draw()
+}
+
+// Finish completes the bar, moving the cursor to the next line on a terminal, or writing a final
+// log line if the last one did not show the current progress. Calls after the first have no effect.
+func (b *Bar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return
+	}
+	switch b.mode {
+	case modeTerminal:
+		fmt.Fprintln(b.w)
+	case modeLog:
+		if line := b.logLine(); line != b.last {
+			fmt.Fprintln(b.w, line)
+		}
+	}
+	b.finished = true
+}
+
+func (b *Bar) draw() {
+	if b.finished {
+		return
+	}
+	switch b.mode {
+	case modeTerminal:
+		line := b.line()
+		if line != b.last {
+			fmt.Fprint(b.w, clearLine+line)
+			b.last = line
+		}
+	case modeLog:
+		step := 0
+		if b.total > 0 {
+			step = b.percent() / logStep
+		}
+		if step != b.lastStep {
+			b.last = b.logLine()
+			fmt.Fprintln(b.w, b.last)
+			b.lastStep = step
+		}
+	}
+}
+
+func (b *Bar) percent() int {
+	if b.total <= 0 {
+		return 0
+	}
+	return min(max(b.current*100/b.total, 0), 100)
+}
+
+// line returns the bar as drawn on a terminal.
+func (b *Bar) line() string {
+	var s strings.Builder
+	if b.opts.message != "" {
+		s.WriteString(b.opts.message + " ")
+	}
+	if b.total <= 0 {
+		fmt.Fprintf(&s, "%d", b.current)
+		return s.String()
+	}
+	width := max(b.opts.width, 1)
+	filled := min(max(b.current*width/b.total, 0), width)
+	s.WriteString("[")
+	s.WriteString(strings.Repeat("=", filled))
+	if filled < width {
+		s.WriteString(">")
+		s.WriteString(strings.Repeat(" ", width-filled-1))
+	}
+	fmt.Fprintf(&s, "] %d%% (%d/%d)", b.percent(), b.current, b.total)
+	return s.String()
+}
+
+// logLine returns the bar as a log line.
+func (b *Bar) logLine() string {
+	var s strings.Builder
+	if b.opts.message != "" {
+		s.WriteString(b.opts.message + ": ")
+	}
+	if b.total <= 0 {
+		fmt.Fprintf(&s, "%d", b.current)
+	} else {
+		fmt.Fprintf(&s, "%d%% (%d/%d)", b.percent(), b.current, b.total)
+	}
+	return s.String()
+}
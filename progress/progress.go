@@ -0,0 +1,91 @@
+// Package progress provides progress bars and spinners for long-running commands. Indicators are
+// drawn in place when writing to a terminal, and degrade to plain log lines otherwise, so output
+// redirected to a file or captured by CI stays readable.
+//
+//	bar := progress.NewBar(s.Stderr, len(files), progress.WithState(s), progress.WithMessage("uploading"))
+//	for _, f := range files {
+//	    upload(f)
+//	    bar.Add(1)
+//	}
+//	bar.Finish()
+//
+// With [WithState], indicators are silent when the flag marked with the cli.Quiet convention is set.
+package progress
+
+import (
+	"io"
+	"os"
+
+	"github.com/mfridman/cli"
+)
+
+// Option configures a [Bar] or [Spinner].
+type Option func(*options)
+
+type options struct {
+	message string
+	width   int
+	quiet   bool
+}
+
+// WithMessage sets the text shown before a progress bar.
+func WithMessage(message string) Option {
+	return func(o *options) { o.message = message }
+}
+
+// WithWidth sets the width of a progress bar, in columns, excluding its message and counters. The
+// default is 30.
+func WithWidth(width int) Option {
+	return func(o *options) { o.width = width }
+}
+
+// WithState ties the indicator to a command: it writes nothing if the flag marked with the
+// cli.Quiet convention is set, see [cli.State.Quiet].
+func WithState(s *cli.State) Option {
+	return func(o *options) { o.quiet = o.quiet || s.Quiet() }
+}
+
+// WithQuiet disables all output of the indicator if quiet is true.
+func WithQuiet(quiet bool) Option {
+	return func(o *options) { o.quiet = o.quiet || quiet }
+}
+
+// mode is how an indicator writes its progress.
+type mode int
+
+const (
+	// modeTerminal redraws the indicator in place.
+	modeTerminal mode = iota
+	// modeLog writes occasional log lines.
+	modeLog
+	// modeSilent writes nothing.
+	modeSilent
+)
+
+func newOptions(opts []Option) options {
+	o := options{width: 30}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// outputMode returns how an indicator with options o writes to w. Indicators are drawn in place
+// only if w is a terminal that supports cursor movement.
+func outputMode(w io.Writer, o options) mode {
+	if o.quiet {
+		return modeSilent
+	}
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return modeLog
+	}
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return modeLog
+	}
+	return modeTerminal
+}
+
+// clearLine returns the cursor to the start of the line and clears it.
+const clearLine = "\r\x1b[K"
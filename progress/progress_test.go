@@ -0,0 +1,114 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/mfridman/cli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("log lines", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		bar := NewBar(&buf, 8, WithMessage("uploading"))
+		for i := 0; i < 8; i++ {
+			bar.Add(1)
+		}
+		bar.Finish()
+		bar.Finish()
+		require.Equal(t, `uploading: 0% (0/8)
+uploading: 25% (2/8)
+uploading: 50% (4/8)
+uploading: 75% (6/8)
+uploading: 100% (8/8)
+`, buf.String())
+	})
+	t.Run("unknown total", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		bar := NewBar(&buf, 0)
+		bar.Set(41)
+		bar.Add(1)
+		bar.Finish()
+		require.Equal(t, "0\n42\n", buf.String())
+	})
+	t.Run("terminal", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		bar := newBar(&buf, 4, modeTerminal, newOptions([]Option{WithMessage("copying"), WithWidth(8)}))
+		bar.Add(1)
+		bar.Add(0)
+		bar.Set(4)
+		bar.Finish()
+		require.Equal(t, clearLine+"copying [>       ] 0% (0/4)"+
+			clearLine+"copying [==>     ] 25% (1/4)"+
+			clearLine+"copying [========] 100% (4/4)\n", buf.String())
+	})
+	t.Run("quiet", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		bar := NewBar(&buf, 2, WithQuiet(true))
+		bar.Add(2)
+		bar.Finish()
+		require.Empty(t, buf.String())
+	})
+}
+
+func TestSpinner(t *testing.T) {
+	t.Parallel()
+
+	t.Run("log lines", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		sp := NewSpinner(&buf, "connecting")
+		sp.Update("connecting")
+		sp.Update("authenticating")
+		sp.Stop("connected")
+		sp.Stop("again")
+		sp.Update("ignored")
+		require.Equal(t, "connecting\nauthenticating\nconnected\n", buf.String())
+	})
+	t.Run("terminal", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		sp := newSpinner(&buf, "waiting", modeTerminal)
+		sp.Update("still waiting")
+		sp.Stop("done")
+		got := buf.String()
+		require.True(t, strings.HasPrefix(got, clearLine+"⠋ waiting"+clearLine+"⠋ still waiting"), got)
+		require.True(t, strings.HasSuffix(got, clearLine+"done\n"), got)
+	})
+}
+
+func TestWithState(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	root := &cli.Command{
+		Name: "app",
+		Flags: cli.FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("quiet", false, "suppress output")
+		}),
+		FlagsMetadata: []cli.FlagMetadata{{Name: "quiet", Conventional: cli.Quiet}},
+		Exec: func(ctx context.Context, s *cli.State) error {
+			bar := NewBar(s.Stderr, 1, WithState(s))
+			bar.Add(1)
+			bar.Finish()
+			sp := NewSpinner(s.Stderr, "working", WithState(s))
+			sp.Stop("done")
+			return nil
+		},
+	}
+	require.NoError(t, cli.RunArgs(context.Background(), root, []string{"-quiet"}, &cli.RunOptions{Stderr: &buf}))
+	require.Empty(t, buf.String())
+
+	require.NoError(t, cli.RunArgs(context.Background(), root, nil, &cli.RunOptions{Stderr: &buf}))
+	require.Equal(t, "0% (0/1)\n100% (1/1)\nworking\ndone\n", buf.String())
+}
@@ -0,0 +1,118 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// spinnerFrames are the frames of a spinner, drawn in turn every spinnerInterval.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100 * time.Millisecond
+
+// Spinner indicates ongoing work of unknown length. On a terminal it is an animated frame followed
+// by a message, redrawn in place until Stop is called. Otherwise the message is written as a log
+// line when the spinner starts and whenever it changes.
+//
+// A Spinner is safe for concurrent use.
+type Spinner struct {
+	mu      sync.Mutex
+	w       io.Writer
+	mode    mode
+	message string
+	frame   int
+	stop    chan struct{}
+	done    chan struct{}
+	stopped bool
+}
+
+// NewSpinner starts a spinner with message writing to w, typically the Stderr of a command. Stop
+// must be called to stop it.
+func NewSpinner(w io.Writer, message string, opts ...Option) *Spinner {
+	return newSpinner(w, message, outputMode(w, newOptions(opts)))
+}
+
+func newSpinner(w io.Writer, message string, mode mode) *Spinner {
+	sp := &Spinner{
+		w:       w,
+		mode:    mode,
+		message: message,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	switch sp.mode {
+	case modeTerminal:
+		sp.draw()
+		go sp.animate()
+		return sp
+	case modeLog:
+		fmt.Fprintln(w, message)
+	}
+	close(sp.done)
+	return sp
+}
+
+// Update replaces the message of the spinner.
+func (sp *Spinner) Update(message string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.stopped || message == sp.message {
+		return
+	}
+	sp.message = message
+	switch sp.mode {
+	case modeTerminal:
+		sp.draw()
+	case modeLog:
+		fmt.Fprintln(sp.w, message)
+	}
+}
+
+// Stop stops the spinner and clears it from a terminal. If final is not empty, it is written as a
+// line in place of the spinner, e.g., "done". Calls after the first have no effect.
+func (sp *Spinner) Stop(final string) {
+	sp.mu.Lock()
+	if sp.stopped {
+		sp.mu.Unlock()
+		return
+	}
+	sp.stopped = true
+	if sp.mode == modeTerminal {
+		close(sp.stop)
+	}
+	sp.mu.Unlock()
+	<-sp.done
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.mode == modeTerminal {
+		fmt.Fprint(sp.w, clearLine)
+	}
+	if final != "" && sp.mode != modeSilent {
+		fmt.Fprintln(sp.w, final)
+	}
+}
+
+func (sp *Spinner) animate() {
+	defer close(sp.done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sp.stop:
+			return
+		case <-ticker.C:
+			sp.mu.Lock()
+			sp.frame = (sp.frame + 1) % len(spinnerFrames)
+			sp.draw()
+			sp.mu.Unlock()
+		}
+	}
+}
+
+// draw redraws the spinner in place. The caller must hold mu.
+func (sp *Spinner) draw() {
+	fmt.Fprint(sp.w, clearLine+spinnerFrames[sp.frame]+" "+sp.message)
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+)
+
+// promptFunc asks the user for the value of a missing required flag. An empty value leaves the
+// flag unset. See [Options.PromptMissing].
+type promptFunc func(f *flag.Flag, m FlagMetadata) (string, error)
+
+// terminalPrompt returns a promptFunc that reads values from the terminal in, writing prompts to
+// out. Values of secret flags are read without echoing them.
+func terminalPrompt(in *os.File, out io.Writer) promptFunc {
+	return func(f *flag.Flag, m FlagMetadata) (string, error) {
+		fmt.Fprintf(out, "%s (%s): ", formatFlagName(f.Name), f.Usage)
+		if !m.Secret {
+			return readLine(in)
+		}
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		defer signal.Stop(signals)
+		return readSecret(in, out, signals)
+	}
+}
+
+// disableEcho turns off echoing on the terminal f, see disableTerminalEcho. It is a variable that
+// can be mocked in tests.
+var disableEcho = disableTerminalEcho
+
+// readSecret reads a line from the terminal in without echoing it. Echo is restored when the line
+// has been read, or when an interrupt is received from signals, in which case the process exits
+// with exit code 130 like it would have without the prompt.
+func readSecret(in *os.File, out io.Writer, signals <-chan os.Signal) (string, error) {
+	restore, err := disableEcho(in)
+	if err != nil {
+		return "", fmt.Errorf("failed to disable echo: %w", err)
+	}
+	var once sync.Once
+	var restoreErr error
+	restoreEcho := func() {
+		once.Do(func() { restoreErr = restore() })
+	}
+	done := make(chan struct{})
+	defer close(done)
+	defer restoreEcho()
+	go func() {
+		select {
+		case <-signals:
+			restoreEcho()
+			fmt.Fprintln(out)
+			osExit(130)
+		case <-done:
+		}
+	}()
+	value, err := readLine(in)
+	// The newline typed by the user was not echoed either.
+	fmt.Fprintln(out)
+	restoreEcho()
+	if err == nil && restoreErr != nil {
+		err = fmt.Errorf("failed to enable echo: %w", restoreErr)
+	}
+	return value, err
+}
+
+// readLine reads a single line from r. It reads byte by byte, so input following the line is left
+// for the command; a terminal delivers input line by line anyway.
+func readLine(r io.Reader) (string, error) {
+	var sb strings.Builder
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			sb.WriteByte(b[0])
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptMissing(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("user", "", "user name")
+				f.String("token", "", "API token")
+				f.Int("port", 0, "port")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "user", Required: true},
+				{Name: "token", Required: true, Secret: true},
+				{Name: "port"},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+	answers := func(values map[string]string, asked *[]string) promptFunc {
+		return func(f *flag.Flag, m FlagMetadata) (string, error) {
+			name := f.Name
+			if m.Secret {
+				name += " (secret)"
+			}
+			*asked = append(*asked, name)
+			return values[f.Name], nil
+		}
+	}
+
+	t.Run("prompts for missing required flags", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		var asked []string
+		err := parse(root, []string{"--user=bob"}, &parseConfig{
+			prompt: answers(map[string]string{"token": "s3cret"}, &asked),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"token (secret)"}, asked)
+		assert.Equal(t, "s3cret", GetFlag[string](root.state, "token"))
+		assert.Equal(t, FlagSourcePrompt, root.state.FlagSources()["token"])
+		assert.Equal(t, FlagSourceCommandLine, root.state.FlagSources()["user"])
+		assert.False(t, root.state.IsSet("token"))
+	})
+	t.Run("empty answer", func(t *testing.T) {
+		t.Parallel()
+		var asked []string
+		err := parse(newRoot(), nil, &parseConfig{
+			prompt: answers(map[string]string{"user": "bob"}, &asked),
+		})
+		require.EqualError(t, err, `command "app": required flag "-token" not set`)
+		assert.Equal(t, []string{"user", "token (secret)"}, asked)
+	})
+	t.Run("prompt error", func(t *testing.T) {
+		t.Parallel()
+		err := parse(newRoot(), nil, &parseConfig{
+			prompt: func(*flag.Flag, FlagMetadata) (string, error) { return "", errors.New("closed") },
+		})
+		require.EqualError(t, err, `command "app": failed to read flag -user: closed`)
+	})
+	t.Run("not a terminal", func(t *testing.T) {
+		t.Parallel()
+		var stderr bytes.Buffer
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:          []string{},
+			PromptMissing: true,
+			RunOptions:    RunOptions{Stdin: strings.NewReader("bob\n"), Stdout: new(bytes.Buffer), Stderr: &stderr},
+		})
+		require.Error(t, err)
+		assert.Contains(t, stderr.String(), `required flags "-user, -token" not set`)
+	})
+}
+
+func TestTerminalPrompt(t *testing.T) {
+	t.Parallel()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+	_, err = w.WriteString("  bob \nrest\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var out bytes.Buffer
+	prompt := terminalPrompt(r, &out)
+	value, err := prompt(&flag.Flag{Name: "user", Usage: "user name"}, FlagMetadata{})
+	require.NoError(t, err)
+	assert.Equal(t, "bob", value)
+	assert.Equal(t, "-user (user name): ", out.String())
+	// Input after the line is left for the command.
+	rest, err := readLine(r)
+	require.NoError(t, err)
+	assert.Equal(t, "rest", rest)
+}
+
+func TestReadSecret(t *testing.T) {
+	// Not parallel, disableEcho and osExit are mocked.
+
+	var restored bool
+	disableEcho = func(*os.File) (func() error, error) {
+		restored = false
+		return func() error {
+			restored = true
+			return nil
+		}, nil
+	}
+	t.Cleanup(func() {
+		disableEcho = disableTerminalEcho
+		osExit = os.Exit
+	})
+
+	t.Run("restores echo", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Close() })
+		_, err = w.WriteString("s3cret\n")
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		var out bytes.Buffer
+		value, err := readSecret(r, &out, make(chan os.Signal))
+		require.NoError(t, err)
+		assert.Equal(t, "s3cret", value)
+		assert.Equal(t, "\n", out.String())
+		assert.True(t, restored)
+	})
+	t.Run("restores echo on interrupt", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Close() })
+
+		exitCode := make(chan int, 1)
+		osExit = func(code int) {
+			assert.True(t, restored)
+			exitCode <- code
+			// Unblock the read, the process would have exited.
+			w.Close()
+		}
+		signals := make(chan os.Signal, 1)
+		signals <- os.Interrupt
+		_, err = readSecret(r, io.Discard, signals)
+		require.NoError(t, err)
+		assert.Equal(t, 130, <-exitCode)
+	})
+	t.Run("not a terminal", func(t *testing.T) {
+		disableEcho = disableTerminalEcho
+		r, w, err := os.Pipe()
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Close(); w.Close() })
+		_, err = readSecret(r, io.Discard, make(chan os.Signal))
+		require.ErrorContains(t, err, "failed to disable echo")
+	})
+}
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	inputFlagName  = "input"
+	outputFlagName = "output"
+)
+
+// InputFlag defines the conventional -input flag, for filter-style commands that read from a file
+// or standard input. If the flag is set to a path other than "-", [Run] opens the file and uses it
+// as the command's Stdin, so Exec reads from s.Stdin either way. The file is closed when Exec
+// returns.
+//
+// The flag value can be retrieved with GetFlag[string].
+//
+//	cmd.Flags = cli.FlagsFunc(func(f *flag.FlagSet) {
+//	    cli.InputFlag(f)
+//	    cli.OutputFlag(f)
+//	})
+func InputFlag(f *flag.FlagSet) *string {
+	p := new(string)
+	f.Var(newRedirectValue(p, false), inputFlagName, "read input from `FILE`, - for stdin")
+	return p
+}
+
+// OutputFlag defines the conventional -output flag, for commands that write to a file or standard
+// output. If the flag is set to a path other than "-", [Run] creates or truncates the file and uses
+// it as the command's Stdout. The file is closed when Exec returns.
+//
+// The flag value can be retrieved with GetFlag[string].
+func OutputFlag(f *flag.FlagSet) *string {
+	p := new(string)
+	f.Var(newRedirectValue(p, true), outputFlagName, "write output to `FILE`, - for stdout")
+	return p
+}
+
+// redirectValue is the value of a flag defined by [InputFlag] or [OutputFlag].
+type redirectValue struct {
+	p      *string
+	output bool
+}
+
+func newRedirectValue(p *string, output bool) *redirectValue {
+	*p = "-"
+	return &redirectValue{p: p, output: output}
+}
+
+func (v *redirectValue) cloneValue() flag.Value {
+	return newRedirectValue(new(string), v.output)
+}
+
+func (v *redirectValue) Set(s string) error {
+	if s == "" {
+		return errors.New("path must not be empty")
+	}
+	*v.p = s
+	return nil
+}
+
+func (v *redirectValue) Get() any { return *v.p }
+
+func (v *redirectValue) String() string {
+	if v == nil || v.p == nil {
+		return "-"
+	}
+	return *v.p
+}
+
+// redirectStreams replaces Stdin and Stdout of s with the files named by the input and output flags
+// in the command hierarchy, if any, and then applies the [Command.Stdio] hooks. The returned
+// function closes the files and restores the original streams.
+func redirectStreams(s *State) (func() error, error) {
+	var input, output *redirectValue
+	for i := range s.path {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			fset.VisitAll(func(f *flag.Flag) {
+				if v, ok := f.Value.(*redirectValue); ok {
+					if v.output {
+						output = v
+					} else {
+						input = v
+					}
+				}
+			})
+		}
+	}
+	stdin, stdout, stderr := s.Stdin, s.Stdout, s.Stderr
+	var files []*os.File
+	restore := func() error {
+		s.Stdin, s.Stdout, s.Stderr = stdin, stdout, stderr
+		var errs []error
+		for _, f := range files {
+			if err := f.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close %s: %w", f.Name(), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	if input != nil && *input.p != "-" {
+		f, err := os.Open(*input.p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input: %w", err)
+		}
+		files = append(files, f)
+		s.Stdin = f
+		s.debugLog.printf("reading Stdin from %s", f.Name())
+	}
+	if output != nil && *output.p != "-" {
+		f, err := os.Create(*output.p)
+		if err != nil {
+			_ = restore()
+			return nil, fmt.Errorf("failed to create output: %w", err)
+		}
+		files = append(files, f)
+		s.Stdout = f
+		s.debugLog.printf("writing Stdout to %s", f.Name())
+	}
+	for i, cmd := range s.path {
+		if cmd.Stdio == nil {
+			continue
+		}
+		s.debugLog.printf("calling the Stdio hook of %q", getCommandPath(s.path[:i+1]))
+		if err := cmd.Stdio(s); err != nil {
+			_ = restore()
+			return nil, fmt.Errorf("command %q: failed to set up standard streams: %w", getCommandPath(s.path), err)
+		}
+	}
+	return restore, nil
+}
+
+// StdinIsTerminal reports whether Stdin is an interactive terminal, rather than a pipe, a file, or
+// some other reader.
+func (s *State) StdinIsTerminal() bool {
+	f, ok := s.Stdin.(*os.File)
+	return ok && isTerminal(f)
+}
+
+// ReadAllStdin reads Stdin until EOF. It returns an error if Stdin is a terminal, so a command
+// expecting piped input fails instead of silently waiting for the user to type it.
+func (s *State) ReadAllStdin() ([]byte, error) {
+	if s.StdinIsTerminal() {
+		return nil, errors.New("no input: stdin is a terminal, pipe input to the command or pass a file")
+	}
+	data, err := io.ReadAll(s.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return data, nil
+}
+
+// StdinOrFile opens the file named by the string flag flagName, or returns Stdin if the flag is
+// empty or "-", following the convention that "-" means standard input. The caller must close the
+// returned reader, which leaves Stdin open. For a flag defined with [InputFlag], Stdin already is
+// the named file, so Stdin is returned.
+//
+//	r, err := s.StdinOrFile("file")
+//	if err != nil {
+//	    return err
+//	}
+//	defer r.Close()
+func (s *State) StdinOrFile(flagName string) (io.ReadCloser, error) {
+	if f, ok := s.Flag(flagName); ok {
+		if _, ok := f.Value.(*redirectValue); ok {
+			s.markAccessed(flagName)
+			return io.NopCloser(s.Stdin), nil
+		}
+	}
+	path, err := LookupFlag[string](s, flagName)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" || path == "-" {
+		return io.NopCloser(s.Stdin), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input: %w", err)
+	}
+	return f, nil
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "upper",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				InputFlag(f)
+				OutputFlag(f)
+			}),
+			Exec: func(ctx context.Context, s *State) error {
+				data, err := io.ReadAll(s.Stdin)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(s.Stdout, strings.ToUpper(string(data)))
+				return err
+			},
+		}
+	}
+
+	t.Run("std streams", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--input=-"}))
+		require.Equal(t, "-", GetFlag[string](root.state, "input"))
+		require.Equal(t, "-", GetFlag[string](root.state, "output"))
+		var stdout bytes.Buffer
+		err := Run(context.Background(), root, &RunOptions{Stdin: strings.NewReader("hello"), Stdout: &stdout})
+		require.NoError(t, err)
+		require.Equal(t, "HELLO", stdout.String())
+	})
+	t.Run("files", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		in, out := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+		require.NoError(t, os.WriteFile(in, []byte("hello"), 0o644))
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--input", in, "--output", out}))
+		var stdout bytes.Buffer
+		stdin := strings.NewReader("ignored")
+		require.NoError(t, Run(context.Background(), root, &RunOptions{Stdin: stdin, Stdout: &stdout}))
+		require.Empty(t, stdout.String())
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		require.Equal(t, "HELLO", string(data))
+		// The original streams are restored after Exec returns.
+		require.Equal(t, stdin, root.state.Stdin)
+		require.Equal(t, &stdout, root.state.Stdout)
+	})
+	t.Run("missing input", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--input", filepath.Join(t.TempDir(), "missing.txt")}))
+		err := Run(context.Background(), root, &RunOptions{Stdout: io.Discard})
+		require.ErrorContains(t, err, "failed to open input")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("empty path", func(t *testing.T) {
+		t.Parallel()
+		err := Parse(newRoot(), []string{"--output="})
+		require.ErrorContains(t, err, "path must not be empty")
+	})
+}
+
+func TestStdioHook(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(hook func(s *State) error) *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name:  "export",
+					Stdio: hook,
+					Exec: func(ctx context.Context, s *State) error {
+						fmt.Fprintln(s.Stdout, "progress")
+						return nil
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("send stdout to stderr", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot(func(s *State) error {
+			s.Stdout = s.Stderr
+			return nil
+		})
+		require.NoError(t, Parse(root, []string{"export"}))
+		var stdout, stderr bytes.Buffer
+		require.NoError(t, Run(context.Background(), root, &RunOptions{Stdout: &stdout, Stderr: &stderr}))
+		require.Empty(t, stdout.String())
+		require.Equal(t, "progress\n", stderr.String())
+		// The original streams are restored.
+		require.Equal(t, &stdout, root.state.Stdout)
+	})
+	t.Run("parent hook runs first", func(t *testing.T) {
+		t.Parallel()
+		var calls []string
+		root := newRoot(func(s *State) error {
+			calls = append(calls, "export")
+			return nil
+		})
+		root.Stdio = func(s *State) error {
+			calls = append(calls, "app")
+			return nil
+		}
+		require.NoError(t, RunArgs(context.Background(), root, []string{"export"}, &RunOptions{Stdout: io.Discard}))
+		require.Equal(t, []string{"app", "export"}, calls)
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot(func(s *State) error { return errors.New("no terminal") })
+		err := RunArgs(context.Background(), root, []string{"export"}, nil)
+		require.EqualError(t, err, `command "app export": failed to set up standard streams: no terminal`)
+	})
+}
+
+func TestStdinHelpers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+	require.NoError(t, os.WriteFile(path, []byte("from file"), 0o644))
+
+	var got string
+	newRoot := func() *Command {
+		return &Command{
+			Name: "load",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("file", "", "tasks file, - for stdin")
+				InputFlag(f)
+			}),
+			Exec: func(ctx context.Context, s *State) error {
+				name := "file"
+				if s.Changed("input") {
+					name = "input"
+				}
+				r, err := s.StdinOrFile(name)
+				if err != nil {
+					return err
+				}
+				defer r.Close()
+				data, err := io.ReadAll(r)
+				got = string(data)
+				return err
+			},
+		}
+	}
+	run := func(args ...string) error {
+		return RunArgs(context.Background(), newRoot(), args, &RunOptions{Stdin: strings.NewReader("from stdin")})
+	}
+
+	require.NoError(t, run("-file", path))
+	require.Equal(t, "from file", got)
+	require.NoError(t, run("-file", "-"))
+	require.Equal(t, "from stdin", got)
+	require.NoError(t, run())
+	require.Equal(t, "from stdin", got)
+	require.NoError(t, run("-input", path))
+	require.Equal(t, "from file", got)
+	err := run("-file", filepath.Join(dir, "missing.json"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	s := &State{Stdin: strings.NewReader("piped")}
+	require.False(t, s.StdinIsTerminal())
+	data, err := s.ReadAllStdin()
+	require.NoError(t, err)
+	require.Equal(t, "piped", string(data))
+
+	_, err = (&State{}).StdinOrFile("file")
+	require.ErrorContains(t, err, "not found")
+}
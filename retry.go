@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy configures how [Run] retries Exec of a command that failed with a transient error,
+// see [Command.Retry].
+type RetryPolicy struct {
+	// Attempts is the maximum number of times Exec is called, including the first call. Values
+	// below 2 disable retries.
+	Attempts int
+	// Backoff returns how long to wait before the given retry, starting at 1 for the first retry.
+	// If nil, ExponentialBackoff(time.Second, 30*time.Second) is used.
+	Backoff func(retry int) time.Duration
+	// RetryIf reports whether an error returned by Exec is transient and Exec should be called
+	// again. If nil, all errors are retried.
+	RetryIf func(err error) bool
+}
+
+// ExponentialBackoff returns a [RetryPolicy] backoff function that waits base before the first
+// retry and doubles the delay for every further retry, up to limit.
+func ExponentialBackoff(base, limit time.Duration) func(retry int) time.Duration {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry && d < limit; i++ {
+			d *= 2
+		}
+		return min(d, limit)
+	}
+}
+
+// runWithRetry runs Exec of cmd like run, and calls it again according to the command's
+// [RetryPolicy] while it fails with a transient error. Each retry is announced on Stderr. Errors
+// that cannot be transient, such as [ErrShowHelp], a [*PanicError] or an error after the context is
+// done, are returned immediately.
+func runWithRetry(ctx context.Context, cmd *Command, s *State) error {
+	policy := cmd.Retry
+	if policy == nil || policy.Attempts < 2 {
+		return run(ctx, cmd, s)
+	}
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(time.Second, 30*time.Second)
+	}
+	cmdPath := getCommandPath(s.path)
+	for attempt := 1; ; attempt++ {
+		err := run(ctx, cmd, s)
+		if err == nil || attempt == policy.Attempts || !retryable(ctx, policy, err) {
+			return err
+		}
+		delay := backoff(attempt)
+		fmt.Fprintf(s.Stderr, "command %q failed (attempt %d of %d), retrying in %s: %v\n",
+			cmdPath, attempt, policy.Attempts, delay, err)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func retryable(ctx context.Context, policy *RetryPolicy, err error) bool {
+	var (
+		intErr   *internalError
+		panicErr *PanicError
+	)
+	if errors.Is(err, ErrShowHelp) || errors.As(err, &intErr) || errors.As(err, &panicErr) {
+		return false
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	return policy.RetryIf == nil || policy.RetryIf(err)
+}
+
+// sleepCtx waits for d, or returns an error like [CheckCtx] if ctx is done first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return CheckCtx(ctx)
+	}
+}
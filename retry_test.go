@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("connection reset")
+	newRoot := func(policy *RetryPolicy, exec func(ctx context.Context, s *State) error) *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{Name: "fetch", Retry: policy, Exec: exec},
+			},
+		}
+	}
+	failing := func(calls *int, failures int, err error) func(ctx context.Context, s *State) error {
+		return func(ctx context.Context, s *State) error {
+			*calls++
+			if *calls <= failures {
+				return err
+			}
+			return nil
+		}
+	}
+	noBackoff := func(int) time.Duration { return 0 }
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		t.Parallel()
+		var calls int
+		var stderr bytes.Buffer
+		root := newRoot(&RetryPolicy{Attempts: 3, Backoff: noBackoff}, failing(&calls, 2, errTransient))
+		require.NoError(t, RunArgs(context.Background(), root, []string{"fetch"}, &RunOptions{Stderr: &stderr}))
+		require.Equal(t, 3, calls)
+		require.Equal(t, `command "app fetch" failed (attempt 1 of 3), retrying in 0s: connection reset
+command "app fetch" failed (attempt 2 of 3), retrying in 0s: connection reset
+`, stderr.String())
+	})
+	t.Run("attempts exhausted", func(t *testing.T) {
+		t.Parallel()
+		var calls int
+		root := newRoot(&RetryPolicy{Attempts: 2, Backoff: noBackoff}, failing(&calls, 5, errTransient))
+		err := RunArgs(context.Background(), root, []string{"fetch"}, &RunOptions{Stderr: &bytes.Buffer{}})
+		require.ErrorIs(t, err, errTransient)
+		require.Equal(t, 2, calls)
+	})
+	t.Run("permanent error", func(t *testing.T) {
+		t.Parallel()
+		errPermanent := errors.New("not found")
+		policy := &RetryPolicy{
+			Attempts: 3,
+			Backoff:  noBackoff,
+			RetryIf:  func(err error) bool { return errors.Is(err, errTransient) },
+		}
+		var calls int
+		root := newRoot(policy, failing(&calls, 5, errPermanent))
+		err := RunArgs(context.Background(), root, []string{"fetch"}, nil)
+		require.ErrorIs(t, err, errPermanent)
+		require.Equal(t, 1, calls)
+
+		calls = 0
+		root = newRoot(&RetryPolicy{Attempts: 3, Backoff: noBackoff}, failing(&calls, 5, ShowHelp()))
+		err = RunArgs(context.Background(), root, []string{"fetch"}, &RunOptions{Stderr: &bytes.Buffer{}})
+		require.ErrorIs(t, err, flag.ErrHelp)
+		require.Equal(t, 1, calls)
+	})
+	t.Run("canceled during backoff", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var calls int
+		backoff := func(int) time.Duration {
+			cancel()
+			return time.Hour
+		}
+		root := newRoot(&RetryPolicy{Attempts: 3, Backoff: backoff}, failing(&calls, 5, errTransient))
+		err := RunArgs(ctx, root, []string{"fetch"}, &RunOptions{Stderr: &bytes.Buffer{}})
+		require.ErrorIs(t, err, ErrInterrupted)
+		require.Equal(t, 1, calls)
+	})
+	t.Run("timeout includes retries", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot(&RetryPolicy{Attempts: 3, Backoff: func(int) time.Duration { return time.Hour }},
+			func(ctx context.Context, s *State) error { return errTransient })
+		root.SubCommands[0].Timeout = 10 * time.Millisecond
+		err := RunArgs(context.Background(), root, []string{"fetch"}, &RunOptions{Stderr: &bytes.Buffer{}})
+		var timeoutErr *TimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+	})
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	var got []time.Duration
+	for retry := 1; retry <= 6; retry++ {
+		got = append(got, backoff(retry))
+	}
+	require.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, got)
+}
@@ -1,8 +1,10 @@
 package cli
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // RunOptions specifies options for running a command.
@@ -20,8 +23,59 @@ type RunOptions struct {
 	// and [os.Stderr], respectively).
 	Stdin          io.Reader
 	Stdout, Stderr io.Writer
+
+	// UnusedParentFlags controls how flags that were explicitly set on the command line, but
+	// belong to an ancestor of the terminal command and were never read during execution, are
+	// treated. This catches invocations like "todo --version add item", where --version is silently
+	// ignored. Since flag access is only known once Exec returns, the check runs after execution.
+	//
+	// Defaults to [UnusedFlagIgnore].
+	UnusedParentFlags UnusedFlagMode
+
+	// HelpPager pipes help text written by [ParseAndRun] through a pager when Stdout is a terminal
+	// and the text does not fit on the screen. The pager is taken from the PAGER environment
+	// variable, defaulting to "less -FRX".
+	HelpPager bool
+
+	// ShutdownGracePeriod bounds how long [RunWithSignals] waits for the command to return once its
+	// context is canceled. When the grace period expires, cleanup functions registered with
+	// [State.Cleanup] are run and an error wrapping [ErrAborted] is returned, even if Exec has not
+	// returned yet. If zero, RunWithSignals waits for Exec to return.
+	ShutdownGracePeriod time.Duration
+
+	// Trace holds optional hooks called at each stage of the invocation, see [Trace].
+	Trace *Trace
+	// Telemetry, if set, reports the command path and flag names of every invocation to a
+	// [UsageReporter], see [Telemetry].
+	Telemetry *Telemetry
+
+	// Debug writes diagnostics about how the command is run to Stderr, each line prefixed with
+	// "debug:" and the time elapsed, e.g., the [Command.Stdio] and [Trace] hooks in the order they
+	// are called, the timeout, cleanup functions and how long Exec took. [ParseAndRun] also reports
+	// the resolved command and the flags that were set, along with where their values came from.
+	// See [Options.DebugFlag] to enable it from the command line.
+	//
+	// Debug also makes [Run] panic again when Exec panics, instead of returning a [*PanicError], so
+	// the program crashes with the runtime's report of the original panic, or stops in a debugger.
+	Debug bool
+
+	// debugLog is the log started by ParseAndRun, so its timings cover parsing.
+	debugLog *debugLog
 }
 
+// UnusedFlagMode controls how unused parent flags are treated, see [RunOptions].
+type UnusedFlagMode int
+
+const (
+	// UnusedFlagIgnore ignores unused parent flags.
+	UnusedFlagIgnore UnusedFlagMode = iota
+	// UnusedFlagWarn writes a warning to Stderr for each unused parent flag.
+	UnusedFlagWarn
+	// UnusedFlagError returns an error listing the unused parent flags, unless Exec itself returned
+	// an error.
+	UnusedFlagError
+)
+
 // Run executes the current command. It returns an error if the command has not been parsed or if
 // the command has no execution function.
 //
@@ -42,25 +96,184 @@ func Run(ctx context.Context, root *Command, options *RunOptions) error {
 
 	options = checkAndSetRunOptions(options)
 	updateState(root.state, options)
+	return runState(ctx, cmd, root.state, options)
+}
+
+// runState runs the terminal command cmd with the parsed state s.
+func runState(ctx context.Context, cmd *Command, s *State, options *RunOptions) error {
+	s.debugLog = options.debugLog
+	if s.debugLog == nil && options.Debug {
+		s.debugLog = newDebugLog(options.Stderr)
+	}
+	if cmd.needsConfirmation() && !s.confirmed && !s.conventionalFlag(AssumeYes) {
+		s.debugLog.printf("asking to confirm %q", getCommandPath(s.path))
+		if err := confirmCommand(cmd, s); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.accessedFlags = nil
+	s.result, s.hasResult = nil, false
+	s.mu.Unlock()
+	restore, err := redirectStreams(s)
+	if err != nil {
+		s.runCleanups()
+		return err
+	}
+	if ctx != nil {
+		ctx = context.WithValue(ctx, stateContextKey{}, s)
+	}
+	start := time.Now()
+	err = options.Trace.exec(ctx, s, func(ctx context.Context) error {
+		s.debugLog.printf("running %q", getCommandPath(s.path))
+		err := runWithTimeout(ctx, cmd, s)
+		s.debugLog.printf("exec of %q returned after %s: %v", getCommandPath(s.path), time.Since(start).Round(time.Microsecond), err)
+		return err
+	})
+	options.Telemetry.report(ctx, s, time.Since(start), err)
+	s.runCleanups()
+	if closeErr := restore(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, ErrShowHelp) {
+		fmt.Fprintln(s.Stderr, formatUsage(s.path[0], s.path, s.usageOptions(s.Stderr)))
+		return flag.ErrHelp
+	}
+	if err != nil {
+		return err
+	}
+	return checkUnusedParentFlags(s, options)
+}
+
+// ErrShowHelp can be returned by Exec, directly or wrapped, to show the usage text of the command,
+// e.g., when it was invoked without required arguments. [Run] then writes the usage text to Stderr
+// and returns [flag.ErrHelp]. See [ShowHelp].
+var ErrShowHelp = errors.New("show help")
 
-	return run(ctx, cmd, root.state)
+// ShowHelp returns [ErrShowHelp], for use as "return cli.ShowHelp()" in Exec.
+func ShowHelp() error {
+	return ErrShowHelp
+}
+
+// ErrInterrupted is returned by [CheckCtx] when the command's context is done, typically because
+// the user interrupted the program. [ParseAndRun] maps it to exit code 130.
+var ErrInterrupted = errors.New("interrupted")
+
+// CheckCtx returns nil if ctx is still active, and an error wrapping both [ErrInterrupted] and
+// ctx.Err() otherwise. Exec functions can return it from long-running loops, so interruption is
+// reported consistently.
+func CheckCtx(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+	return nil
+}
+
+func checkUnusedParentFlags(s *State, opt *RunOptions) error {
+	if opt.UnusedParentFlags == UnusedFlagIgnore {
+		return nil
+	}
+	unused := s.unusedParentFlags()
+	if len(unused) == 0 {
+		return nil
+	}
+	cmdPath := getCommandPath(s.path)
+	if opt.UnusedParentFlags == UnusedFlagWarn {
+		for _, name := range unused {
+			fmt.Fprintf(s.Stderr, "warning: %s\n", s.messages.unusedFlag(cmdPath, formatFlagName(name)))
+		}
+		return nil
+	}
+	names := make([]string, 0, len(unused))
+	for _, name := range unused {
+		names = append(names, formatFlagName(name))
+	}
+	return errors.New(s.messages.unusedFlags(cmdPath, names))
+}
+
+const confirmFlagName = "yes"
+
+// needsConfirmation reports whether the command must be confirmed before Exec is called, see
+// [Command.Destructive] and [Command.Confirm].
+func (c *Command) needsConfirmation() bool {
+	return c.Destructive || c.Confirm != ""
+}
+
+// confirmFlagUsage returns the usage string of the -yes flag added to a command that needs
+// confirmation.
+func (c *Command) confirmFlagUsage() string {
+	if c.Destructive {
+		return "confirm the destructive operation without prompting"
+	}
+	return "confirm the operation without prompting"
+}
+
+// confirmCommand asks the user to confirm running a command that needs confirmation. It returns an
+// error if the user declines, or if Stdin is not a terminal and the user cannot be asked.
+func confirmCommand(cmd *Command, s *State) error {
+	cmdPath := getCommandPath(s.path)
+	f, ok := s.Stdin.(*os.File)
+	if !ok || !isTerminal(f) {
+		return errors.New(s.messages.confirmRequired(cmdPath, formatFlagName(confirmFlagName), cmd.Destructive))
+	}
+	prompt := cmd.Confirm
+	if prompt == "" {
+		prompt = s.messages.confirmPrompt(cmdPath)
+	}
+	fmt.Fprintf(s.Stderr, "%s [y/N]: ", prompt)
+	answer, _ := bufio.NewReader(f).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errors.New(s.messages.aborted(cmdPath))
+}
+
+// PanicError is returned by [Run] when Exec panics. It holds the value passed to panic along with
+// the stack trace at the time of the panic, which is otherwise lost once the panic is recovered.
+// Its message names the function that panicked; print Stack for the full trace:
+//
+//	var panicErr *cli.PanicError
+//	if errors.As(err, &panicErr) {
+//	    fmt.Fprintf(os.Stderr, "%v\n%s", panicErr, panicErr.Stack)
+//	}
+//
+// See [RunOptions.Debug] to let the panic crash the program instead.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked, formatted by [debug.Stack].
+	Stack []byte
+
+	// location is the function, file and line that panicked.
+	location string
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.location)
+}
+
+// Unwrap returns the value passed to panic if it is an error, so it can be matched with
+// [errors.Is] and [errors.As].
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
 }
 
 func run(ctx context.Context, cmd *Command, state *State) (retErr error) {
 	defer func() {
 		if r := recover(); r != nil {
-			switch err := r.(type) {
-			case error:
-				// If error is from cli package (e.g., flag type mismatch), don't add location info
-				var intErr *internalError
-				if errors.As(err, &intErr) {
-					retErr = err
-				} else {
-					retErr = fmt.Errorf("panic: %v\n\n%s", err, location(4))
-				}
-			default:
-				retErr = fmt.Errorf("panic: %v", r)
+			// Errors from the cli package (e.g., flag type mismatch) are returned as is.
+			var intErr *internalError
+			if err, ok := r.(error); ok && errors.As(err, &intErr) {
+				retErr = err
+				return
 			}
+			if state.debugLog != nil {
+				panic(r)
+			}
+			retErr = &PanicError{Value: r, Stack: debug.Stack(), location: panicLocation()}
 		}
 	}()
 	return cmd.Exec(ctx, state)
@@ -108,16 +321,26 @@ func getGoModuleName() string {
 	return goModuleName
 }
 
-func location(skip int) string {
-	var pcs [1]uintptr
-	// Need to add 2 to skip to account for this function and runtime.Callers
-	n := runtime.Callers(skip+2, pcs[:])
-	if n == 0 {
-		return "unknown:0"
+// panicLocation returns the function, file and line that panicked. It must be called by the deferred
+// function that recovered the panic. Frames of the runtime, such as those of a nil pointer
+// dereference, are skipped.
+func panicLocation() string {
+	var pcs [32]uintptr
+	// Skip runtime.Callers, this function and the deferred function.
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" && !strings.HasPrefix(frame.Function, "runtime.") {
+			return formatFrame(frame)
+		}
+		if !more {
+			return "unknown:0"
+		}
 	}
+}
 
-	frame, _ := runtime.CallersFrames(pcs[:n]).Next()
-
+func formatFrame(frame runtime.Frame) string {
 	// Trim the module name from both function and file paths for cleaner output
 	fn := strings.TrimPrefix(frame.Function, getGoModuleName()+"/")
 	file := strings.TrimPrefix(frame.File, getGoModuleName()+"/")
@@ -5,7 +5,11 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -128,6 +132,46 @@ func TestRun(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "panic")
 	})
+	t.Run("panic error", func(t *testing.T) {
+		t.Parallel()
+		errBoom := errors.New("boom")
+		var calls int
+		root := &Command{
+			Name: "panic",
+			Exec: func(ctx context.Context, s *State) error {
+				calls++
+				panic(fmt.Errorf("wrapped: %w", errBoom))
+			},
+			Retry: &RetryPolicy{Attempts: 3, Backoff: func(int) time.Duration { return 0 }},
+		}
+		err := RunArgs(context.Background(), root, nil, &RunOptions{Stderr: io.Discard})
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		require.ErrorIs(t, err, errBoom)
+		require.EqualError(t, panicErr.Value.(error), "wrapped: boom")
+		require.Contains(t, err.Error(), "panic: wrapped: boom\n\n")
+		require.Contains(t, err.Error(), "run_test.go:")
+		require.Contains(t, string(panicErr.Stack), "run_test.go:")
+		require.Equal(t, 1, calls, "panics are not retried")
+
+		require.PanicsWithError(t, "wrapped: boom", func() {
+			_ = RunArgs(context.Background(), root, nil, &RunOptions{Stderr: io.Discard, Debug: true})
+		})
+
+		// Runtime panics are located in the function that caused them.
+		root = &Command{
+			Name: "panic",
+			Exec: func(ctx context.Context, s *State) error {
+				var m map[string]int
+				m["x"] = 1
+				return nil
+			},
+		}
+		err = RunArgs(context.Background(), root, nil, nil)
+		require.ErrorAs(t, err, &panicErr)
+		require.Contains(t, err.Error(), "assignment to entry in nil map\n\n")
+		require.Contains(t, err.Error(), "run_test.go:")
+	})
 	t.Run("run before parse", func(t *testing.T) {
 		t.Parallel()
 		root := &Command{
@@ -236,3 +280,280 @@ func TestRun(t *testing.T) {
 		}
 	})
 }
+
+func TestUnusedParentFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "todo",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("version", false, "show version")
+				f.Bool("verbose", false, "verbose")
+			}),
+			SubCommands: []*Command{
+				{
+					Name: "add",
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.Bool("dry-run", false, "dry run")
+					}),
+					Exec: func(ctx context.Context, s *State) error {
+						_ = GetFlag[bool](s, "verbose")
+						return nil
+					},
+				},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	t.Run("ignore by default", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--version", "add", "item"}))
+		var stderr bytes.Buffer
+		require.NoError(t, Run(context.Background(), root, &RunOptions{Stderr: &stderr}))
+		require.Empty(t, stderr.String())
+	})
+	t.Run("warn", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--version", "add", "--verbose", "--dry-run", "item"}))
+		var stderr bytes.Buffer
+		err := Run(context.Background(), root, &RunOptions{Stderr: &stderr, UnusedParentFlags: UnusedFlagWarn})
+		require.NoError(t, err)
+		require.Equal(t, "warning: flag -version was set but not used by command \"todo add\"\n", stderr.String())
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--version", "add", "item"}))
+		err := Run(context.Background(), root, &RunOptions{UnusedParentFlags: UnusedFlagError})
+		require.ErrorContains(t, err, `command "todo add": flag "-version" set but not used`)
+	})
+	t.Run("used flags", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--verbose", "add", "--dry-run"}))
+		err := Run(context.Background(), root, &RunOptions{UnusedParentFlags: UnusedFlagError})
+		require.NoError(t, err)
+	})
+	t.Run("root command", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"--version"}))
+		err := Run(context.Background(), root, &RunOptions{UnusedParentFlags: UnusedFlagError})
+		require.NoError(t, err)
+	})
+}
+
+func TestDestructive(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(ran *bool) *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name:        "purge",
+					ShortHelp:   "Delete all data",
+					Destructive: true,
+					Exec: func(ctx context.Context, s *State) error {
+						*ran = true
+						return nil
+					},
+				},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	t.Run("requires confirmation", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		root := newRoot(&ran)
+		require.NoError(t, Parse(root, []string{"purge"}))
+		err := Run(context.Background(), root, &RunOptions{Stdin: strings.NewReader("y\n")})
+		require.EqualError(t, err, `command "app purge" is destructive: confirm with -yes`)
+		require.False(t, ran)
+	})
+	t.Run("yes flag", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		root := newRoot(&ran)
+		require.NoError(t, Parse(root, []string{"purge", "--yes"}))
+		require.NoError(t, Run(context.Background(), root, nil))
+		require.True(t, ran)
+
+		ran = false
+		require.NoError(t, Parse(root, []string{"purge", "--yes=false"}))
+		require.Error(t, Run(context.Background(), root, &RunOptions{Stdin: strings.NewReader("")}))
+		require.False(t, ran)
+	})
+	t.Run("yes flag only for destructive commands", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		err := Parse(newRoot(&ran), []string{"--yes"})
+		require.ErrorContains(t, err, "flag provided but not defined: -yes")
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		root := newRoot(&ran)
+		require.ErrorIs(t, Parse(root, []string{"--help"}), flag.ErrHelp)
+		require.Contains(t, DefaultUsage(root), "purge    Delete all data (destructive)")
+
+		require.ErrorIs(t, Parse(root, []string{"purge", "--help"}), flag.ErrHelp)
+		require.Contains(t, DefaultUsage(root), "-yes    confirm the destructive operation without prompting (default: false)")
+	})
+}
+
+func TestConfirm(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(ran *bool) *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("assume-yes", false, "answer yes to all prompts")
+			}),
+			FlagsMetadata: []FlagMetadata{{Name: "assume-yes", Conventional: AssumeYes}},
+			SubCommands: []*Command{
+				{
+					Name:    "reset",
+					Confirm: "This will reset all settings. Continue?",
+					Exec: func(ctx context.Context, s *State) error {
+						*ran = true
+						return nil
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("requires confirmation", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		err := RunArgs(context.Background(), newRoot(&ran), []string{"reset"}, &RunOptions{Stdin: strings.NewReader("y\n")})
+		require.EqualError(t, err, `command "app reset" requires confirmation: confirm with -yes`)
+		require.False(t, ran)
+	})
+	t.Run("yes flag", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		require.NoError(t, RunArgs(context.Background(), newRoot(&ran), []string{"reset", "-yes"}, nil))
+		require.True(t, ran)
+	})
+	t.Run("assume yes convention", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		require.NoError(t, RunArgs(context.Background(), newRoot(&ran), []string{"--assume-yes", "reset"}, nil))
+		require.True(t, ran)
+	})
+	t.Run("help", func(t *testing.T) {
+		t.Parallel()
+		var ran bool
+		root := newRoot(&ran)
+		require.ErrorIs(t, Parse(root, []string{"reset", "--help"}), flag.ErrHelp)
+		usage := DefaultUsage(root)
+		require.Contains(t, usage, "-yes           confirm the operation without prompting (default: false)")
+		require.NotContains(t, usage, "destructive")
+	})
+}
+
+func TestCheckCtx(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, CheckCtx(context.Background()))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := CheckCtx(ctx)
+	require.ErrorIs(t, err, ErrInterrupted)
+	require.ErrorIs(t, err, context.Canceled)
+	require.EqualError(t, err, "interrupted: context canceled")
+
+	var canceled bool
+	root := &Command{
+		Name: "app",
+		Exec: func(ctx context.Context, s *State) error {
+			canceled = s.Canceled()
+			return s.Err()
+		},
+	}
+	require.NoError(t, Parse(root, nil))
+	require.Nil(t, root.state.Err())
+	err = Run(ctx, root, nil)
+	require.ErrorIs(t, err, ErrInterrupted)
+	require.True(t, canceled)
+
+	err = ParseAndRun(ctx, root, &Options{Args: []string{}, RunOptions: RunOptions{Stderr: io.Discard}})
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 130, exitErr.Code)
+}
+
+func TestShowHelp(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name:  "add",
+					Usage: "app add <item>",
+					Exec: func(ctx context.Context, s *State) error {
+						if len(s.Args) == 0 {
+							return fmt.Errorf("missing item: %w", ShowHelp())
+						}
+						return nil
+					},
+				},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+
+	root := newRoot()
+	require.NoError(t, Parse(root, []string{"add"}))
+	var stdout, stderr bytes.Buffer
+	err := Run(context.Background(), root, &RunOptions{Stdout: &stdout, Stderr: &stderr})
+	require.ErrorIs(t, err, flag.ErrHelp)
+	require.Empty(t, stdout.String())
+	require.Equal(t, "Usage:\n  app add <item>\n", stderr.String())
+
+	stderr.Reset()
+	inv, err := ParseInvocation(newRoot(), []string{"add"})
+	require.NoError(t, err)
+	err = inv.Run(context.Background(), &RunOptions{Stderr: &stderr})
+	require.ErrorIs(t, err, flag.ErrHelp)
+	require.Equal(t, "Usage:\n  app add <item>\n", stderr.String())
+
+	stderr.Reset()
+	err = ParseAndRun(context.Background(), newRoot(), &Options{
+		Args:       []string{"add"},
+		RunOptions: RunOptions{Stdout: &stdout, Stderr: &stderr},
+	})
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, 2, exitErr.Code)
+	require.Equal(t, "Usage:\n  app add <item>\n", stderr.String())
+
+	// The usage text is rendered like the text shown for --help, with the same usage options.
+	usageOptions := &UsageOptions{Renderer: footerRenderer{footer: "footer"}}
+	stdout.Reset()
+	require.NoError(t, ParseAndRun(context.Background(), newRoot(), &Options{
+		Args:         []string{"add", "--help"},
+		RunOptions:   RunOptions{Stdout: &stdout},
+		UsageOptions: usageOptions,
+	}))
+	stderr.Reset()
+	err = ParseAndRun(context.Background(), newRoot(), &Options{
+		Args:         []string{"add"},
+		RunOptions:   RunOptions{Stderr: &stderr},
+		UsageOptions: usageOptions,
+	})
+	require.ErrorIs(t, err, flag.ErrHelp)
+	require.Equal(t, "Usage:\n  app add <item>\n\nfooter\n", stderr.String())
+	require.Equal(t, stdout.String(), stderr.String())
+}
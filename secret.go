@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// maskedValue replaces the value of a secret flag in diagnostic output.
+const maskedValue = "****"
+
+// secretFileFlagName returns the name of the flag that reads the value of the secret flag name from
+// a file, see [FlagMetadata.Secret].
+func secretFileFlagName(name string) string {
+	return name + "-file"
+}
+
+// secretFileFlag returns the flag that reads the value of the secret flag name from a file.
+func secretFileFlag(name string) *flag.Flag {
+	return &flag.Flag{
+		Name:  secretFileFlagName(name),
+		Usage: fmt.Sprintf("read the value of %s from `FILE`", formatFlagName(name)),
+	}
+}
+
+// pathSecretFlags returns the names of the secret flags available to the terminal command of path.
+func pathSecretFlags(path []*Command) map[string]bool {
+	secrets := make(map[string]bool)
+	for i := range path {
+		for _, m := range pathFlagsMetadata(path, i) {
+			// Metadata of deeper commands takes precedence, like their flags do.
+			secrets[m.Name] = m.Secret
+		}
+	}
+	for name, secret := range secrets {
+		if !secret {
+			delete(secrets, name)
+		}
+	}
+	return secrets
+}
+
+// treeSecretFlags returns the names of the secret flags of all commands in the tree.
+func treeSecretFlags(cmd *Command, secrets map[string]bool) map[string]bool {
+	if secrets == nil {
+		secrets = make(map[string]bool)
+	}
+	for _, m := range cmd.FlagsMetadata {
+		if m.Secret {
+			secrets[m.Name] = true
+		}
+	}
+	for _, g := range cmd.FlagGroups {
+		for _, m := range g.FlagsMetadata {
+			if m.Secret {
+				secrets[m.Name] = true
+			}
+		}
+	}
+	for _, sub := range cmd.SubCommands {
+		treeSecretFlags(sub, secrets)
+	}
+	return secrets
+}
+
+// maskSecretArgs returns a copy of args with the values of secret flags masked, in both the
+// "-name=value" and "-name value" forms.
+func maskSecretArgs(args []string, secrets map[string]bool) []string {
+	masked := make([]string, len(args))
+	copy(masked, args)
+	if len(secrets) == 0 {
+		return masked
+	}
+	for i := 0; i < len(masked); i++ {
+		arg := masked[i]
+		if arg == "--" {
+			break
+		}
+		flagPart, _, hasValue := strings.Cut(arg, "=")
+		name, ok := flagArgName(flagPart)
+		if !ok || !secrets[name] {
+			continue
+		}
+		if hasValue {
+			masked[i] = flagPart + "=" + maskedValue
+		} else if i+1 < len(masked) {
+			i++
+			masked[i] = maskedValue
+		}
+	}
+	return masked
+}
+
+// invalidValuePattern matches the value in the error the flag package returns for an invalid
+// flag value.
+var invalidValuePattern = regexp.MustCompile(`invalid value ".*?" for flag -([^:\s]+)`)
+
+// maskFlagError masks the value of a secret flag in a flag parsing error.
+func maskFlagError(err error, secrets map[string]bool) error {
+	msg := err.Error()
+	masked := invalidValuePattern.ReplaceAllStringFunc(msg, func(s string) string {
+		name := invalidValuePattern.FindStringSubmatch(s)[1]
+		if !secrets[name] {
+			return s
+		}
+		return fmt.Sprintf("invalid value %q for flag -%s", maskedValue, name)
+	})
+	if masked == msg {
+		return err
+	}
+	return &maskedError{msg: masked, err: err}
+}
+
+// maskedError is an error whose message has secret values masked, wrapping the original error.
+type maskedError struct {
+	msg string
+	err error
+}
+
+func (e *maskedError) Error() string { return e.msg }
+
+func (e *maskedError) Unwrap() error { return e.err }
+
+// readSecretFiles sets the named secret flags whose file flag was set on the command line to the
+// content of the file, without a trailing newline.
+func readSecretFiles(s *State, combined *flag.FlagSet, names []string) error {
+	for _, name := range names {
+		fileFlag := secretFileFlagName(name)
+		if !s.setFlags[fileFlag] {
+			continue
+		}
+		if s.setFlags[name] {
+			return fmt.Errorf("flags %s and %s cannot be used together",
+				formatFlagName(name), formatFlagName(fileFlag))
+		}
+		path := combined.Lookup(fileFlag).Value.String()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read flag %s: %w", formatFlagName(name), err)
+		}
+		if err := combined.Set(name, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return fmt.Errorf("invalid value for flag %s from file %s: %w", formatFlagName(name), path, err)
+		}
+		s.sources[name] = FlagSourceFile
+	}
+	return nil
+}
+
+// hasFlag reports whether a command in path defines the named flag.
+func hasFlag(path []*Command, name string) bool {
+	for i := range path {
+		for _, fset := range pathFlagSets(path, i) {
+			if fset.Lookup(name) != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("token", "default-token", "API `TOKEN`")
+				f.Int("pin", 0, "PIN code")
+				f.String("user", "admin", "user name")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "token", Secret: true, EnvVar: "APP_TOKEN"},
+				{Name: "pin", Secret: true},
+			},
+			SubCommands: []*Command{{
+				Name: "login",
+				Exec: func(ctx context.Context, s *State) error {
+					_, err := io.WriteString(s.Stdout, GetFlag[string](s, "token"))
+					return err
+				},
+			}},
+		}
+	}
+
+	t.Run("help hides default", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"login"}))
+		usage := FormatUsage(root, nil)
+		assert.NotContains(t, usage, "default-token")
+		assert.Contains(t, usage, "(default: admin)")
+		assert.Contains(t, usage, "-token-file FILE")
+		assert.Contains(t, usage, "read the value of -token from FILE")
+	})
+	t.Run("docs and spec hide default", func(t *testing.T) {
+		t.Parallel()
+		var b bytes.Buffer
+		require.NoError(t, GenerateDocs(&b, newRoot(), "markdown"))
+		assert.NotContains(t, b.String(), "default-token")
+		assert.Contains(t, b.String(), "token-file FILE")
+		data, err := EncodeSpec(newRoot())
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "default-token")
+		assert.Contains(t, string(data), `"secret": true`)
+	})
+	t.Run("read from file", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "token")
+		require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0o600))
+		inv, err := ParseInvocation(newRoot(), []string{"login", "--token-file", path})
+		require.NoError(t, err)
+		assert.Equal(t, FlagSourceFile, inv.state.FlagSources()["token"])
+		var stdout bytes.Buffer
+		require.NoError(t, inv.Run(context.Background(), &RunOptions{Stdout: &stdout}))
+		assert.Equal(t, "s3cret", stdout.String())
+	})
+	t.Run("file and flag", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseInvocation(newRoot(), []string{"login", "--token-file", "x", "--token", "y"})
+		require.EqualError(t, err, `command "app login": flags -token and -token-file cannot be used together`)
+	})
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseInvocation(newRoot(), []string{"login", "--token-file", filepath.Join(t.TempDir(), "missing")})
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("masked in errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseInvocation(newRoot(), []string{"login", "--pin", "12ab"})
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "12ab")
+		assert.Contains(t, err.Error(), `invalid value "****" for flag -pin`)
+	})
+	t.Run("masked in flag sources", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"login", "--token=s3cret"}))
+		var b bytes.Buffer
+		writeFlagSources(&b, root.state)
+		assert.NotContains(t, b.String(), "s3cret")
+		assert.Contains(t, b.String(), "****")
+	})
+	t.Run("masked in trace", func(t *testing.T) {
+		t.Parallel()
+		var traced []string
+		err := RunArgs(context.Background(), newRoot(), []string{"login", "--token=s3cret", "-pin", "1234", "--user", "bob"}, &RunOptions{
+			Stdout: new(bytes.Buffer),
+			Trace:  &Trace{OnParseStart: func(args []string) { traced = args }},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"login", "--token=****", "-pin", "****", "--user", "bob"}, traced)
+	})
+}
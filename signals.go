@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// osExit is a variable that can be mocked in tests.
+var osExit = os.Exit
+
+// ErrAborted is returned by [RunWithSignals] when the command did not return within the shutdown
+// grace period, see [RunOptions].
+var ErrAborted = errors.New("aborted")
+
+// RunWithSignals is like [Run], but cancels the context passed to the command when one of the
+// given signals is received. If no signals are given, it listens for [os.Interrupt] and, except on
+// Windows, [syscall.SIGTERM]. A nil ctx is treated as [context.Background].
+//
+// On the first signal, a notice is written to Stderr and the command is given the opportunity to
+// shut down cleanly by returning once its context is canceled. A second signal forces the process
+// to exit immediately with exit code 130. If [RunOptions] has a ShutdownGracePeriod, RunWithSignals
+// returns an error wrapping [ErrAborted] when the command has not returned in time, after running
+// the cleanup functions registered with [State.Cleanup].
+//
+// For long-running processes that need shutdown timeouts or standardized exit codes, see the
+// graceful package.
+func RunWithSignals(ctx context.Context, root *Command, options *RunOptions, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+		if runtime.GOOS != "windows" {
+			signals = append(signals, syscall.SIGTERM)
+		}
+	}
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, signals...)
+	defer signal.Stop(sigCh)
+	return runWithSignals(ctx, root, options, sigCh)
+}
+
+func runWithSignals(ctx context.Context, root *Command, options *RunOptions, sigCh <-chan os.Signal) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	options = checkAndSetRunOptions(options)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-sigCh:
+		case <-done:
+			return
+		}
+		fmt.Fprintln(options.Stderr, "shutting down (press ctrl+c again to force quit)")
+		cancel()
+		select {
+		case <-sigCh:
+			fmt.Fprintln(options.Stderr, "forced shutdown")
+			osExit(130)
+		case <-done:
+		}
+	}()
+	result := make(chan error, 1)
+	go func() {
+		result <- Run(ctx, root, options)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+	}
+	if options.ShutdownGracePeriod <= 0 {
+		return <-result
+	}
+	timer := time.NewTimer(options.ShutdownGracePeriod)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		return err
+	case <-timer.C:
+	}
+	if root != nil && root.state != nil {
+		root.state.runCleanups()
+	}
+	return fmt.Errorf("command did not return within %s of cancellation: %w", options.ShutdownGracePeriod, ErrAborted)
+}
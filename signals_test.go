@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWithSignals(t *testing.T) {
+	// Not parallel, osExit is mocked.
+
+	newRoot := func(exec func(ctx context.Context, s *State) error) *Command {
+		root := &Command{Name: "app", Exec: exec}
+		require.NoError(t, Parse(root, nil))
+		return root
+	}
+
+	t.Run("completes without signal", func(t *testing.T) {
+		root := newRoot(func(ctx context.Context, s *State) error { return nil })
+		var stderr bytes.Buffer
+		err := runWithSignals(context.Background(), root, &RunOptions{Stderr: &stderr}, make(chan os.Signal))
+		require.NoError(t, err)
+		require.Empty(t, stderr.String())
+	})
+	t.Run("nil context", func(t *testing.T) {
+		root := newRoot(func(ctx context.Context, s *State) error {
+			require.NotNil(t, ctx)
+			return ctx.Err()
+		})
+		err := RunWithSignals(nil, root, &RunOptions{Stderr: new(bytes.Buffer)})
+		require.NoError(t, err)
+	})
+	t.Run("first signal cancels context", func(t *testing.T) {
+		sigCh := make(chan os.Signal, 1)
+		root := newRoot(func(ctx context.Context, s *State) error {
+			sigCh <- os.Interrupt
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		var stderr bytes.Buffer
+		err := runWithSignals(context.Background(), root, &RunOptions{Stderr: &stderr}, sigCh)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, "shutting down (press ctrl+c again to force quit)\n", stderr.String())
+	})
+	t.Run("second signal forces exit", func(t *testing.T) {
+		exited := make(chan int, 1)
+		osExit = func(code int) { exited <- code }
+		t.Cleanup(func() { osExit = os.Exit })
+
+		sigCh := make(chan os.Signal, 2)
+		var code int
+		root := newRoot(func(ctx context.Context, s *State) error {
+			sigCh <- os.Interrupt
+			<-ctx.Done()
+			sigCh <- os.Interrupt
+			code = <-exited
+			return nil
+		})
+		var stderr bytes.Buffer
+		err := runWithSignals(context.Background(), root, &RunOptions{Stderr: &stderr}, sigCh)
+		require.NoError(t, err)
+		require.Equal(t, 130, code)
+		require.Contains(t, stderr.String(), "forced shutdown\n")
+	})
+	t.Run("grace period aborts", func(t *testing.T) {
+		sigCh := make(chan os.Signal, 1)
+		release := make(chan struct{})
+		defer close(release)
+		var cleaned []string
+		root := newRoot(func(ctx context.Context, s *State) error {
+			s.Cleanup(func() { cleaned = append(cleaned, "first") })
+			s.Cleanup(func() { cleaned = append(cleaned, "second") })
+			sigCh <- os.Interrupt
+			<-release
+			return nil
+		})
+		var stderr bytes.Buffer
+		err := runWithSignals(context.Background(), root, &RunOptions{
+			Stderr:              &stderr,
+			ShutdownGracePeriod: 10 * time.Millisecond,
+		}, sigCh)
+		require.ErrorIs(t, err, ErrAborted)
+		require.EqualError(t, err, "command did not return within 10ms of cancellation: aborted")
+		require.Equal(t, []string{"second", "first"}, cleaned)
+	})
+	t.Run("returns within grace period", func(t *testing.T) {
+		sigCh := make(chan os.Signal, 1)
+		var cleaned int
+		root := newRoot(func(ctx context.Context, s *State) error {
+			s.Cleanup(func() { cleaned++ })
+			sigCh <- os.Interrupt
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		err := runWithSignals(context.Background(), root, &RunOptions{
+			Stderr:              new(bytes.Buffer),
+			ShutdownGracePeriod: time.Minute,
+		}, sigCh)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, cleaned)
+	})
+}
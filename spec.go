@@ -0,0 +1,516 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SpecVersion is the version of the [Spec] format. It is incremented on incompatible changes, so
+// stored specs can be checked with [ValidateSchema] before they are interpreted.
+const SpecVersion = 1
+
+// Spec is a machine-readable description of a command tree, for use by external systems such as
+// web portals or approval workflows. Its JSON encoding is described by [SpecSchema].
+type Spec struct {
+	// Version is the spec format version, see [SpecVersion].
+	Version int `json:"version"`
+	// Command is the root command.
+	Command SpecCommand `json:"command"`
+}
+
+// SpecCommand describes a command in a [Spec]. A command's flags are also available to all of its
+// descendants.
+type SpecCommand struct {
+	// Name, Usage, ShortHelp and LongHelp are the command's [Command.Name], [Command.Usage],
+	// [Command.ShortHelp] and [Command.LongHelp].
+	Name      string `json:"name"`
+	Usage     string `json:"usage,omitempty"`
+	ShortHelp string `json:"shortHelp,omitempty"`
+	LongHelp  string `json:"longHelp,omitempty"`
+	// Destructive is the command's [Command.Destructive] marker.
+	Destructive bool `json:"destructive,omitempty"`
+	// Confirm is the command's [Command.Confirm] question.
+	Confirm string `json:"confirm,omitempty"`
+	// SeeAlso are the command's [Command.SeeAlso] cross references.
+	SeeAlso []string `json:"seeAlso,omitempty"`
+	// Timeout is the command's [Command.Timeout], formatted as a duration, e.g., "30s".
+	Timeout string `json:"timeout,omitempty"`
+	// Flags are the command's own flags, in lexicographical order.
+	Flags []SpecFlag `json:"flags,omitempty"`
+	// FlagGroups are the flag groups attached to the command.
+	FlagGroups []SpecFlagGroup `json:"flagGroups,omitempty"`
+	// Metadata is the application metadata, only set on the root command.
+	Metadata *AppMetadata `json:"metadata,omitempty"`
+	// SubCommands are the command's subcommands.
+	SubCommands []SpecCommand `json:"subCommands,omitempty"`
+}
+
+// SpecFlag describes a flag in a [Spec].
+type SpecFlag struct {
+	// Name, Usage and Default are the flag's name, usage string and default value, as registered
+	// in the flag set.
+	Name    string `json:"name"`
+	Usage   string `json:"usage,omitempty"`
+	Default string `json:"default,omitempty"`
+	// Bool reports whether the flag is a boolean flag, which does not take a value.
+	Bool bool `json:"bool,omitempty"`
+	// Type is the type of the flag's value, if it is one of the types [DecodeSpec] can recreate:
+	// "bool", "int", "int64", "uint", "uint64", "float64", "string", "duration", "[]string",
+	// "[]int" or "map[string]string".
+	Type string `json:"type,omitempty"`
+	// Choices are the allowed values of a flag defined with [Choice].
+	Choices []string `json:"choices,omitempty"`
+	// Required, Secret, EnvVar and Placeholder are taken from the flag's [FlagMetadata]. The
+	// default value of a secret flag is omitted.
+	Required    bool   `json:"required,omitempty"`
+	Secret      bool   `json:"secret,omitempty"`
+	EnvVar      string `json:"envVar,omitempty"`
+	Placeholder string `json:"placeholder,omitempty"`
+}
+
+// SpecFlagGroup describes a [FlagGroup] in a [Spec].
+type SpecFlagGroup struct {
+	Flags   []SpecFlag `json:"flags"`
+	Include []string   `json:"include,omitempty"`
+	Exclude []string   `json:"exclude,omitempty"`
+}
+
+// SpecSchema is the JSON Schema of the JSON encoding of a [Spec].
+const SpecSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "cli command tree spec",
+  "type": "object",
+  "required": ["version", "command"],
+  "additionalProperties": false,
+  "properties": {
+    "version": {"const": 1},
+    "command": {"$ref": "#/$defs/command"}
+  },
+  "$defs": {
+    "name": {"type": "string", "pattern": "^[a-zA-Z][a-zA-Z0-9_-]*$"},
+    "command": {
+      "type": "object",
+      "required": ["name"],
+      "additionalProperties": false,
+      "properties": {
+        "name": {"$ref": "#/$defs/name"},
+        "usage": {"type": "string"},
+        "shortHelp": {"type": "string"},
+        "longHelp": {"type": "string"},
+        "destructive": {"type": "boolean"},
+        "confirm": {"type": "string"},
+        "seeAlso": {"type": "array", "items": {"type": "string"}},
+        "timeout": {"type": "string"},
+        "flags": {"type": "array", "items": {"$ref": "#/$defs/flag"}},
+        "flagGroups": {"type": "array", "items": {"$ref": "#/$defs/flagGroup"}},
+        "metadata": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "version": {"type": "string"},
+            "author": {"type": "string"},
+            "homepage": {"type": "string"},
+            "license": {"type": "string"},
+            "bugReportURL": {"type": "string"}
+          }
+        },
+        "subCommands": {"type": "array", "items": {"$ref": "#/$defs/command"}}
+      }
+    },
+    "flag": {
+      "type": "object",
+      "required": ["name"],
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string", "minLength": 1},
+        "usage": {"type": "string"},
+        "default": {"type": "string"},
+        "bool": {"type": "boolean"},
+        "type": {"enum": ["bool", "int", "int64", "uint", "uint64", "float64", "string", "duration", "[]string", "[]int", "map[string]string"]},
+        "choices": {"type": "array", "items": {"type": "string"}},
+        "required": {"type": "boolean"},
+        "secret": {"type": "boolean"},
+        "envVar": {"type": "string"},
+        "placeholder": {"type": "string"}
+      }
+    },
+    "flagGroup": {
+      "type": "object",
+      "required": ["flags"],
+      "additionalProperties": false,
+      "properties": {
+        "flags": {"type": "array", "items": {"$ref": "#/$defs/flag"}},
+        "include": {"type": "array", "items": {"type": "string"}},
+        "exclude": {"type": "array", "items": {"type": "string"}}
+      }
+    }
+  }
+}
+`
+
+// NewSpec returns the [Spec] of the command tree.
+func NewSpec(root *Command) (*Spec, error) {
+	if root == nil {
+		return nil, errors.New("root command is nil")
+	}
+	if err := validateCommands(root, nil); err != nil {
+		return nil, err
+	}
+	return &Spec{Version: SpecVersion, Command: newSpecCommand(root)}, nil
+}
+
+// EncodeSpec returns the [Spec] of the command tree as indented JSON, e.g., to generate web docs or
+// to check the CLI surface in CI against an approved copy. The output is also valid YAML, and can
+// be checked with [ValidateSchema].
+func EncodeSpec(root *Command) ([]byte, error) {
+	spec, err := NewSpec(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+func newSpecCommand(cmd *Command) SpecCommand {
+	sc := SpecCommand{
+		Name:        cmd.Name,
+		Usage:       cmd.Usage,
+		ShortHelp:   cmd.ShortHelp,
+		LongHelp:    cmd.LongHelp,
+		Destructive: cmd.Destructive,
+		Confirm:     cmd.Confirm,
+		SeeAlso:     slices.Clone(cmd.SeeAlso),
+		Flags:       newSpecFlags(cmd.Flags, cmd.FlagsMetadata),
+		Metadata:    cmd.Metadata,
+	}
+	if cmd.Timeout > 0 {
+		sc.Timeout = cmd.Timeout.String()
+	}
+	for _, g := range cmd.FlagGroups {
+		sc.FlagGroups = append(sc.FlagGroups, SpecFlagGroup{
+			Flags:   newSpecFlags(g.Flags, g.FlagsMetadata),
+			Include: g.Include,
+			Exclude: g.Exclude,
+		})
+	}
+	for _, sub := range cmd.SubCommands {
+		sc.SubCommands = append(sc.SubCommands, newSpecCommand(sub))
+	}
+	return sc
+}
+
+func newSpecFlags(fset *flag.FlagSet, metadata []FlagMetadata) []SpecFlag {
+	if fset == nil {
+		return nil
+	}
+	byName := make(map[string]FlagMetadata)
+	for _, m := range metadata {
+		byName[m.Name] = m
+	}
+	var flags []SpecFlag
+	fset.VisitAll(func(f *flag.Flag) {
+		m := byName[f.Name]
+		placeholder, usage := flagPlaceholder(f, m)
+		defValue := f.DefValue
+		if m.Secret {
+			defValue = ""
+		}
+		flags = append(flags, SpecFlag{
+			Name:        f.Name,
+			Usage:       usage,
+			Default:     defValue,
+			Bool:        isBoolFlag(f),
+			Type:        specFlagType(f),
+			Choices:     slices.Clone(flagChoices(f)),
+			Required:    m.Required,
+			Secret:      m.Secret,
+			EnvVar:      m.EnvVar,
+			Placeholder: placeholder,
+		})
+	})
+	return flags
+}
+
+// ExecFunc is the signature of [Command.Exec].
+type ExecFunc func(ctx context.Context, s *State) error
+
+// DecodeSpec builds a command tree from the JSON encoding of a [Spec], e.g., as produced by
+// [EncodeSpec], so command trees can be generated from declarative definitions. The data is
+// checked with [ValidateSchema] first.
+//
+// Exec functions are bound by command path, e.g., "app deploy"; commands without a handler have no
+// Exec function. It is an error if a handler does not match any command. Flags are recreated
+// according to their type, and flags without a type are recreated as string flags, or as bool flags
+// if marked as such.
+func DecodeSpec(data []byte, handlers map[string]ExecFunc) (*Command, error) {
+	if err := ValidateSchema(data); err != nil {
+		return nil, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	used := make(map[string]bool)
+	root, err := decodeSpecCommand(spec.Command, nil, handlers, used)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode spec: %w", err)
+	}
+	for path := range handlers {
+		if !used[path] {
+			return nil, fmt.Errorf("failed to decode spec: handler for unknown command %q", path)
+		}
+	}
+	return root, nil
+}
+
+func decodeSpecCommand(sc SpecCommand, path []string, handlers map[string]ExecFunc, used map[string]bool) (*Command, error) {
+	path = append(slices.Clip(path), sc.Name)
+	cmdPath := strings.Join(path, " ")
+	cmd := &Command{
+		Name:        sc.Name,
+		Usage:       sc.Usage,
+		ShortHelp:   sc.ShortHelp,
+		LongHelp:    sc.LongHelp,
+		Destructive: sc.Destructive,
+		Confirm:     sc.Confirm,
+		SeeAlso:     slices.Clone(sc.SeeAlso),
+		Metadata:    sc.Metadata,
+	}
+	var err error
+	if sc.Timeout != "" {
+		if cmd.Timeout, err = time.ParseDuration(sc.Timeout); err != nil {
+			return nil, fmt.Errorf("command %q: invalid timeout: %w", cmdPath, err)
+		}
+	}
+	if cmd.Flags, cmd.FlagsMetadata, err = decodeSpecFlags(sc.Name, sc.Flags); err != nil {
+		return nil, fmt.Errorf("command %q: %w", cmdPath, err)
+	}
+	for _, g := range sc.FlagGroups {
+		group := &FlagGroup{Include: g.Include, Exclude: g.Exclude}
+		if group.Flags, group.FlagsMetadata, err = decodeSpecFlags(sc.Name, g.Flags); err != nil {
+			return nil, fmt.Errorf("command %q: %w", cmdPath, err)
+		}
+		cmd.FlagGroups = append(cmd.FlagGroups, group)
+	}
+	if h, ok := handlers[cmdPath]; ok {
+		cmd.Exec = h
+		used[cmdPath] = true
+	}
+	for _, sub := range sc.SubCommands {
+		subCmd, err := decodeSpecCommand(sub, path, handlers, used)
+		if err != nil {
+			return nil, err
+		}
+		cmd.SubCommands = append(cmd.SubCommands, subCmd)
+	}
+	return cmd, nil
+}
+
+func decodeSpecFlags(name string, flags []SpecFlag) (*flag.FlagSet, []FlagMetadata, error) {
+	if len(flags) == 0 {
+		return nil, nil, nil
+	}
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	var metadata []FlagMetadata
+	for _, sf := range flags {
+		if err := decodeSpecFlag(fset, sf); err != nil {
+			return nil, nil, fmt.Errorf("flag %s: %w", formatFlagName(sf.Name), err)
+		}
+		if sf.Required || sf.Secret || sf.EnvVar != "" || sf.Placeholder != "" {
+			metadata = append(metadata, FlagMetadata{
+				Name:        sf.Name,
+				Required:    sf.Required,
+				Secret:      sf.Secret,
+				EnvVar:      sf.EnvVar,
+				Placeholder: sf.Placeholder,
+			})
+		}
+	}
+	return fset, metadata, nil
+}
+
+func decodeSpecFlag(fset *flag.FlagSet, sf SpecFlag) error {
+	typ := sf.Type
+	if typ == "" {
+		typ = "string"
+		if sf.Bool {
+			typ = "bool"
+		}
+	}
+	var list []string
+	if sf.Default != "" {
+		list = strings.Split(sf.Default, ",")
+	}
+	if len(sf.Choices) > 0 {
+		if typ != "string" {
+			return fmt.Errorf("choices require type string, got %q", typ)
+		}
+		if sf.Default != "" && !slices.Contains(sf.Choices, sf.Default) {
+			return fmt.Errorf("default value %q is not one of: %s", sf.Default, strings.Join(sf.Choices, ", "))
+		}
+		Choice(fset, sf.Name, sf.Choices, sf.Default, sf.Usage)
+		return nil
+	}
+	switch typ {
+	case "string":
+		fset.String(sf.Name, sf.Default, sf.Usage)
+		return nil
+	case "[]string":
+		StringSlice(fset, sf.Name, list, sf.Usage)
+		return nil
+	case "[]int":
+		values := make([]int, 0, len(list))
+		for _, e := range list {
+			v, err := strconv.Atoi(e)
+			if err != nil {
+				return fmt.Errorf("invalid default value %q: %w", sf.Default, err)
+			}
+			values = append(values, v)
+		}
+		IntSlice(fset, sf.Name, values, sf.Usage)
+		return nil
+	case "map[string]string":
+		values := make(map[string]string, len(list))
+		for _, e := range list {
+			k, v, ok := strings.Cut(e, "=")
+			if !ok {
+				return fmt.Errorf("invalid default value %q: expected key=value pairs", sf.Default)
+			}
+			values[k] = v
+		}
+		StringMap(fset, sf.Name, values, sf.Usage)
+		return nil
+	}
+	// The remaining types are defined with their zero value, and set to the default value like
+	// a command line value.
+	switch typ {
+	case "bool":
+		fset.Bool(sf.Name, false, sf.Usage)
+	case "int":
+		fset.Int(sf.Name, 0, sf.Usage)
+	case "int64":
+		fset.Int64(sf.Name, 0, sf.Usage)
+	case "uint":
+		fset.Uint(sf.Name, 0, sf.Usage)
+	case "uint64":
+		fset.Uint64(sf.Name, 0, sf.Usage)
+	case "float64":
+		fset.Float64(sf.Name, 0, sf.Usage)
+	case "duration":
+		fset.Duration(sf.Name, 0, sf.Usage)
+	default:
+		return fmt.Errorf("unsupported type %q", typ)
+	}
+	if sf.Default == "" {
+		return nil
+	}
+	f := fset.Lookup(sf.Name)
+	if err := f.Value.Set(sf.Default); err != nil {
+		return fmt.Errorf("invalid default value %q: %w", sf.Default, err)
+	}
+	f.DefValue = f.Value.String()
+	return nil
+}
+
+// specFlagType returns the [SpecFlag] type of the flag's value, or "" if it is not supported.
+func specFlagType(f *flag.Flag) string {
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return ""
+	}
+	switch getter.Get().(type) {
+	case bool:
+		return "bool"
+	case int:
+		return "int"
+	case int64:
+		return "int64"
+	case uint:
+		return "uint"
+	case uint64:
+		return "uint64"
+	case float64:
+		return "float64"
+	case string:
+		return "string"
+	case time.Duration:
+		return "duration"
+	case []string:
+		return "[]string"
+	case []int:
+		return "[]int"
+	case map[string]string:
+		return "map[string]string"
+	}
+	return ""
+}
+
+// ValidateSchema reports whether data is a valid JSON encoding of a [Spec], as described by
+// [SpecSchema]: the version must be supported, unknown fields are not allowed, command names must
+// be valid, and command and flag names must be unique among their siblings.
+func ValidateSchema(data []byte) error {
+	var spec Spec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	if dec.More() {
+		return errors.New("invalid spec: unexpected data after spec")
+	}
+	if spec.Version != SpecVersion {
+		return fmt.Errorf("invalid spec: unsupported version %d, must be %d", spec.Version, SpecVersion)
+	}
+	if err := validateSpecCommand(spec.Command, nil); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	return nil
+}
+
+func validateSpecCommand(sc SpecCommand, path []string) error {
+	if !isValidName(sc.Name) {
+		if len(path) == 0 {
+			return fmt.Errorf("root command: invalid name %q", sc.Name)
+		}
+		return fmt.Errorf("command %q: invalid subcommand name %q", strings.Join(path, " "), sc.Name)
+	}
+	path = append(path, sc.Name)
+	flagSets := [][]SpecFlag{sc.Flags}
+	for _, g := range sc.FlagGroups {
+		flagSets = append(flagSets, g.Flags)
+	}
+	seenFlags := make(map[string]bool)
+	for _, flags := range flagSets {
+		for _, f := range flags {
+			if f.Name == "" {
+				return fmt.Errorf("command %q: flag has no name", strings.Join(path, " "))
+			}
+			if seenFlags[f.Name] {
+				return fmt.Errorf("command %q: duplicate flag %s", strings.Join(path, " "), formatFlagName(f.Name))
+			}
+			seenFlags[f.Name] = true
+		}
+	}
+	seenCommands := make(map[string]bool)
+	for _, sub := range sc.SubCommands {
+		if seenCommands[sub.Name] {
+			return fmt.Errorf("command %q: duplicate subcommand %q", strings.Join(path, " "), sub.Name)
+		}
+		seenCommands[sub.Name] = true
+		if err := validateSpecCommand(sub, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+	newRoot := func() *Command {
+		return &Command{
+			Name:     "app",
+			Metadata: &AppMetadata{Version: "v1.0.0"},
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("verbose", false, "enable verbose output")
+			}),
+			SubCommands: []*Command{
+				{
+					Name: "db",
+					FlagGroups: []*FlagGroup{{
+						Flags: FlagsFunc(func(f *flag.FlagSet) {
+							f.String("dsn", "", "database `URL`")
+						}),
+						FlagsMetadata: []FlagMetadata{{Name: "dsn", Required: true, EnvVar: "APP_DSN"}},
+						Exclude:       []string{"version"},
+					}},
+					SubCommands: []*Command{
+						{Name: "migrate", Exec: exec},
+						{Name: "version", Exec: exec},
+					},
+					Exec: exec,
+				},
+			},
+			Exec: exec,
+		}
+	}
+
+	t.Run("new spec", func(t *testing.T) {
+		t.Parallel()
+		spec, err := NewSpec(newRoot())
+		require.NoError(t, err)
+		require.Equal(t, SpecVersion, spec.Version)
+		require.Equal(t, "app", spec.Command.Name)
+		require.Equal(t, []SpecFlag{{Name: "verbose", Usage: "enable verbose output", Default: "false", Bool: true, Type: "bool"}}, spec.Command.Flags)
+		require.Len(t, spec.Command.SubCommands, 1)
+		db := spec.Command.SubCommands[0]
+		require.Equal(t, []SpecFlagGroup{{
+			Flags:   []SpecFlag{{Name: "dsn", Usage: "database URL", Required: true, EnvVar: "APP_DSN", Placeholder: "URL", Type: "string"}},
+			Exclude: []string{"version"},
+		}}, db.FlagGroups)
+		require.Len(t, db.SubCommands, 2)
+
+		_, err = NewSpec(nil)
+		require.Error(t, err)
+	})
+	t.Run("validate round trip", func(t *testing.T) {
+		t.Parallel()
+		spec, err := NewSpec(newRoot())
+		require.NoError(t, err)
+		data, err := json.Marshal(spec)
+		require.NoError(t, err)
+		require.NoError(t, ValidateSchema(data))
+	})
+	t.Run("encode spec", func(t *testing.T) {
+		t.Parallel()
+		data, err := EncodeSpec(&Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("region", "us-east-1", "cloud region")
+			}),
+			FlagsMetadata: []FlagMetadata{{Name: "region", Required: true}},
+			Exec:          exec,
+		})
+		require.NoError(t, err)
+		require.Equal(t, `{
+  "version": 1,
+  "command": {
+    "name": "app",
+    "flags": [
+      {
+        "name": "region",
+        "usage": "cloud region",
+        "default": "us-east-1",
+        "type": "string",
+        "required": true
+      }
+    ]
+  }
+}
+`, string(data))
+		require.NoError(t, ValidateSchema(data))
+
+		_, err = EncodeSpec(nil)
+		require.EqualError(t, err, "failed to encode spec: root command is nil")
+	})
+	t.Run("schema is valid JSON", func(t *testing.T) {
+		t.Parallel()
+		require.True(t, json.Valid([]byte(SpecSchema)))
+	})
+	t.Run("validate errors", func(t *testing.T) {
+		t.Parallel()
+		tests := []struct {
+			data string
+			want string
+		}{
+			{`not json`, "invalid spec: invalid character"},
+			{`{"version": 2, "command": {"name": "app"}}`, "unsupported version 2, must be 1"},
+			{`{"command": {"name": "app"}}`, "unsupported version 0"},
+			{`{"version": 1, "command": {"name": "app", "aliases": []}}`, `unknown field "aliases"`},
+			{`{"version": 1, "command": {"name": "1app"}}`, `root command: invalid name "1app"`},
+			{`{"version": 1, "command": {"name": "app", "subCommands": [{"name": "a b"}]}}`, `command "app": invalid subcommand name "a b"`},
+			{`{"version": 1, "command": {"name": "app", "subCommands": [{"name": "a"}, {"name": "a"}]}}`, `command "app": duplicate subcommand "a"`},
+			{`{"version": 1, "command": {"name": "app", "flags": [{"name": "v"}], "flagGroups": [{"flags": [{"name": "v"}]}]}}`, `command "app": duplicate flag -v`},
+			{`{"version": 1, "command": {"name": "app", "flags": [{"usage": "x"}]}}`, `command "app": flag has no name`},
+			{`{"version": 1, "command": {"name": "app"}} {}`, "unexpected data after spec"},
+		}
+		for _, tt := range tests {
+			require.ErrorContains(t, ValidateSchema([]byte(tt.data)), tt.want, tt.data)
+		}
+	})
+}
+
+func TestDecodeSpec(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+	original := &Command{
+		Name:      "app",
+		ShortHelp: "An example app",
+		LongHelp:  "An example app.\n\nIt deploys things.",
+		Metadata:  &AppMetadata{Version: "v1.0.0"},
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("verbose", false, "enable verbose output")
+			f.Duration("timeout", 5*time.Second, "request timeout")
+			Choice(f, "format", []string{"json", "text"}, "text", "output format")
+		}),
+		SubCommands: []*Command{
+			{
+				Name:        "deploy",
+				Destructive: true,
+				Timeout:     10 * time.Minute,
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Int("replicas", 2, "number of replicas")
+					f.Float64("ratio", 0.5, "canary ratio")
+					StringSlice(f, "tag", []string{"a", "b"}, "image tag")
+					IntSlice(f, "port", []int{80}, "port")
+					StringMap(f, "label", map[string]string{"env": "prod"}, "label")
+				}),
+				FlagsMetadata: []FlagMetadata{{Name: "replicas", Required: true, EnvVar: "APP_REPLICAS"}},
+				FlagGroups: []*FlagGroup{{
+					Flags: FlagsFunc(func(f *flag.FlagSet) {
+						f.String("dsn", "", "database `URL`")
+					}),
+				}},
+				Exec: exec,
+			},
+		},
+		Exec: exec,
+	}
+	data, err := EncodeSpec(original)
+	require.NoError(t, err)
+
+	var replicas int
+	var tags []string
+	root, err := DecodeSpec(data, map[string]ExecFunc{
+		"app": exec,
+		"app deploy": func(ctx context.Context, s *State) error {
+			replicas = GetFlag[int](s, "replicas")
+			tags = GetFlag[[]string](s, "tag")
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	roundTrip, err := EncodeSpec(root)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(roundTrip))
+
+	require.NoError(t, Parse(root, []string{"deploy", "--replicas=3", "--yes"}))
+	require.NoError(t, Run(context.Background(), root, nil))
+	require.Equal(t, 3, replicas)
+	require.Equal(t, []string{"a", "b"}, tags)
+	require.Equal(t, 10*time.Minute, root.SubCommands[0].Timeout)
+
+	_, err = DecodeSpec(data, map[string]ExecFunc{"app deplyo": exec})
+	require.EqualError(t, err, `failed to decode spec: handler for unknown command "app deplyo"`)
+
+	_, err = DecodeSpec([]byte(`{"version": 1, "command": {"name": "app", "flags": [{"name": "n", "type": "int", "default": "x"}]}}`), nil)
+	require.ErrorContains(t, err, `command "app": flag -n: invalid default value "x"`)
+
+	_, err = DecodeSpec([]byte(`{"version": 1, "command": {"name": "app", "timeout": "soon"}}`), nil)
+	require.ErrorContains(t, err, `command "app": invalid timeout`)
+}
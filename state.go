@@ -1,9 +1,15 @@
 package cli
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 // State holds command information during Exec function execution, allowing child commands to access
@@ -16,9 +22,310 @@ type State struct {
 	Stdin          io.Reader
 	Stdout, Stderr io.Writer
 
+	// dashArgs holds the arguments after the "--" delimiter, see ArgsAfterDash.
+	dashArgs []string
+
 	// path is the command hierarchy from the root command to the current command. The root command
 	// is the first element in the path, and the terminal command is the last element.
 	path []*Command
+	// setFlags holds the names of flags explicitly set on the command line during parsing.
+	setFlags map[string]bool
+	// sources records where the value of each flag not left at its default came from.
+	sources map[string]FlagSource
+	// clones holds the per-invocation copies of flag sets of a state created by
+	// [ParseInvocation], or nil if flag values are stored in the command tree's flag sets.
+	clones *flagClones
+	// ctx is the context Exec was called with, see Err.
+	ctx context.Context
+	// confirmed reports whether a destructive terminal command was confirmed with the -yes flag.
+	confirmed bool
+	// debugLog writes diagnostics while the command is run if [RunOptions.Debug] is set, and is
+	// nil otherwise.
+	debugLog *debugLog
+	// usage configures the usage text written when Exec requests it, see Usage. Its Output is
+	// set when the text is rendered.
+	usage *UsageOptions
+	// messages translates the text produced while the command is run, see [Options.Messages].
+	messages *Messages
+
+	// mu guards accessedFlags, since Exec may look up flags from multiple goroutines.
+	mu sync.Mutex
+	// accessedFlags holds the names of flags looked up during execution.
+	accessedFlags map[string]bool
+	// cleanups holds the functions registered with Cleanup, guarded by mu.
+	cleanups []func()
+	// result holds the value set with SetResult, guarded by mu.
+	result    any
+	hasResult bool
+}
+
+// ArgsAfterDash returns the arguments given after the "--" delimiter, which are also the last
+// elements of Args. Commands that run another program, like "app exec pod -- ls -l", can use it to
+// tell their own positional arguments from the command line to pass through:
+//
+//	passthrough := s.ArgsAfterDash()
+//	own := s.Args[:len(s.Args)-len(passthrough)]
+//
+// It returns nil if there was no delimiter, and an empty, non-nil slice if nothing followed it.
+func (s *State) ArgsAfterDash() []string {
+	return slices.Clone(s.dashArgs)
+}
+
+// SetResult records a structured result of the command, for programs embedding the CLI that would
+// otherwise parse Stdout to learn what a command produced. See [Options.OnResult]. Calling
+// SetResult again replaces the previous result.
+func (s *State) SetResult(v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.result = v
+	s.hasResult = true
+}
+
+// Result returns the value recorded with SetResult, and whether one was recorded.
+func (s *State) Result() (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.result, s.hasResult
+}
+
+// Cleanup registers a function to be called when Exec returns, or when [RunWithSignals] gives up
+// waiting for Exec after the shutdown grace period expired, see [RunOptions]. Cleanup functions are
+// called in last-added, first-called order and at most once.
+func (s *State) Cleanup(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cleanups = append(s.cleanups, fn)
+}
+
+// runCleanups calls and removes the registered cleanup functions.
+func (s *State) runCleanups() {
+	s.mu.Lock()
+	cleanups := s.cleanups
+	s.cleanups = nil
+	s.mu.Unlock()
+	if len(cleanups) > 0 {
+		s.debugLog.printf("running %d cleanup function(s)", len(cleanups))
+	}
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
+
+type stateContextKey struct{}
+
+// StateFromContext returns the [State] of the command being run from the context passed to its Exec
+// function, so helper functions can access flags and I/O streams without taking the state as a
+// parameter.
+func StateFromContext(ctx context.Context) (*State, bool) {
+	s, ok := ctx.Value(stateContextKey{}).(*State)
+	return s, ok
+}
+
+// Err returns nil while the context passed to Exec is active, and an error wrapping
+// [ErrInterrupted] once it is canceled or its deadline is exceeded, see [CheckCtx]. Loops inside
+// Exec can use it to bail out consistently:
+//
+//	for _, item := range items {
+//	    if err := s.Err(); err != nil {
+//	        return err
+//	    }
+//	    // ...
+//	}
+func (s *State) Err() error {
+	if s.ctx == nil {
+		return nil
+	}
+	return CheckCtx(s.ctx)
+}
+
+// Canceled reports whether the context passed to Exec is canceled or its deadline is exceeded.
+func (s *State) Canceled() bool {
+	return s.Err() != nil
+}
+
+// FlagSource describes where the final value of a flag came from, see [State.FlagSources].
+type FlagSource int
+
+const (
+	// FlagSourceDefault is a flag left at its default value.
+	FlagSourceDefault FlagSource = iota
+	// FlagSourceCommandLine is a flag set on the command line.
+	FlagSourceCommandLine
+	// FlagSourceEnv is a flag set from its environment variable, see [FlagMetadata].
+	FlagSourceEnv
+	// FlagSourceConfig is a flag set from a [ConfigSource].
+	FlagSourceConfig
+	// FlagSourcePrompt is a required flag the user was prompted for, see [Options.PromptMissing].
+	FlagSourcePrompt
+	// FlagSourceFile is a secret flag read from the file given by its "-file" flag, see
+	// [FlagMetadata.Secret].
+	FlagSourceFile
+)
+
+func (src FlagSource) String() string {
+	switch src {
+	case FlagSourceDefault:
+		return "default"
+	case FlagSourceCommandLine:
+		return "command line"
+	case FlagSourceEnv:
+		return "env"
+	case FlagSourceConfig:
+		return "config"
+	case FlagSourcePrompt:
+		return "prompt"
+	case FlagSourceFile:
+		return "file"
+	default:
+		return fmt.Sprintf("FlagSource(%d)", int(src))
+	}
+}
+
+// FlagSources returns where the final value of each flag in the command hierarchy came from, keyed
+// by flag name. This helps to debug why a value from the environment or a config file is not
+// picked up.
+func (s *State) FlagSources() map[string]FlagSource {
+	sources := make(map[string]FlagSource)
+	for i := range s.path {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			fset.VisitAll(func(f *flag.Flag) {
+				sources[f.Name] = s.sources[f.Name]
+			})
+		}
+	}
+	return sources
+}
+
+// IsSet reports whether the named flag was explicitly provided on the command line, as opposed to
+// left at its default value. A flag explicitly set to its default value (e.g., --count=0) is
+// considered set. The flag may belong to any command in the hierarchy.
+func (s *State) IsSet(name string) bool {
+	return s.setFlags[name]
+}
+
+// Changed reports whether the value of the named flag was set by any source: the command line, its
+// environment variable, a config source, a prompt or a file (see [State.FlagSources]), as opposed
+// to left at its default value. Like [State.IsSet], a flag explicitly set to its default value is
+// considered changed, and a value assigned with [flag.FlagSet.Set] before parsing is not.
+func (s *State) Changed(name string) bool {
+	_, ok := s.sources[name]
+	return ok
+}
+
+// Usage returns the usage text of the command being run, rendered exactly like the text shown for
+// the help flags and for [ShowHelp], with the command's UsageFunc or the renderer and options
+// configured in [Options.UsageOptions]. It is meant for commands that write their own usage text,
+// e.g., to Stdout as part of their output. Color and the terminal width are detected from Stdout.
+func (s *State) Usage() string {
+	if len(s.path) == 0 {
+		return ""
+	}
+	return formatUsage(s.path[0], s.path, s.usageOptions(s.Stdout))
+}
+
+// usageOptions returns the usage options of s for rendering usage text to w.
+func (s *State) usageOptions(w io.Writer) *UsageOptions {
+	var opt UsageOptions
+	if s.usage != nil {
+		opt = *s.usage
+	}
+	if opt.Output == nil {
+		opt.Output = w
+	}
+	return &opt
+}
+
+// CommandString reconstructs the invocation as a shell command line: the command path, followed by
+// every flag whose value was set by any source (see [State.Changed]) in lexicographical order, and
+// the remaining arguments. Words are quoted for POSIX shells where needed, so the result can be
+// pasted into a shell or split with [SplitCommandLine], e.g., for audit logs or a "re-run with:"
+// hint after missing values were prompted for. Values of secret flags are masked.
+func (s *State) CommandString() string {
+	var words []string
+	for _, cmd := range s.path {
+		words = append(words, cmd.Name)
+	}
+	names := make([]string, 0, len(s.sources))
+	for name := range s.sources {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	secrets := pathSecretFlags(s.path)
+	for _, name := range names {
+		f, ok := s.Flag(name)
+		if !ok {
+			continue
+		}
+		if secrets[name] {
+			words = append(words, formatFlagName(name)+"="+maskedValue)
+			continue
+		}
+		for _, value := range flagValueStrings(f.Value) {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && value == "true" {
+				words = append(words, formatFlagName(name))
+			} else {
+				words = append(words, formatFlagName(name)+"="+value)
+			}
+		}
+	}
+	if s.confirmed {
+		words = append(words, formatFlagName(confirmFlagName))
+	}
+	if slices.ContainsFunc(s.Args, func(arg string) bool { return strings.HasPrefix(arg, "-") && arg != "-" }) {
+		words = append(words, "--")
+	}
+	words = append(words, s.Args...)
+	for i, w := range words {
+		words[i] = shellQuote(w)
+	}
+	return strings.Join(words, " ")
+}
+
+// flagValueStrings returns the values to set a flag to, to reproduce its current value: one for
+// each element of repeatable flags, such as [StringSlice] and [StringMap], and the string
+// representation of the value otherwise.
+func flagValueStrings(v flag.Value) []string {
+	getter, ok := v.(flag.Getter)
+	if !ok {
+		return []string{v.String()}
+	}
+	switch value := getter.Get().(type) {
+	case map[string]string:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+value[k])
+		}
+		return pairs
+	case []string:
+		return value
+	case []int:
+		values := make([]string, 0, len(value))
+		for _, e := range value {
+			values = append(values, strconv.Itoa(e))
+		}
+		return values
+	}
+	return []string{v.String()}
+}
+
+// Flag returns the named flag from the command hierarchy, using the same lookup rules as [GetFlag],
+// so Exec can introspect a flag's usage string and default value, e.g., for a debug dump of the
+// current configuration. Unlike GetFlag, looking up a flag with Flag does not count as using it
+// (see [RunOptions.UnusedParentFlags]). The returned flag must not be modified.
+func (s *State) Flag(name string) (*flag.Flag, bool) {
+	for i := len(s.path) - 1; i >= 0; i-- {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			if f := fset.Lookup(name); f != nil {
+				return f, true
+			}
+		}
+	}
+	return nil, false
 }
 
 // GetFlag retrieves a flag value by name from the command hierarchy. It first checks the current
@@ -31,38 +338,138 @@ type State struct {
 //	verbose := GetFlag[bool](state, "verbose")
 //	count := GetFlag[int](state, "count")
 //	path := GetFlag[string](state, "path")
+//
+// The flag's [flag.Value] must implement [flag.Getter]. The value returned by Get is matched
+// against T using the following rules, in order:
+//   - a type assertion to T succeeds, i.e., the value has type T or T is an interface the value
+//     implements
+//   - the value is nil, in which case the zero value of T is returned if T is an interface,
+//     pointer, slice, map, channel or function type
+//   - the value is a non-nil pointer whose element type is assignable to T, in which case the
+//     dereferenced value is returned
+//   - the value's type is assignable to T, e.g., a named slice type requested as its unnamed
+//     underlying type
+//
+// Use [LookupFlag] to handle missing or mismatched flags without panicking.
 func GetFlag[T any](s *State, name string) T {
+	return MustGetFlag[T](s, name)
+}
+
+// MustGetFlag is like [LookupFlag] but panics if the flag doesn't exist or if the type doesn't match
+// the requested type T. The panic is recovered by [Run] and returned as an error.
+func MustGetFlag[T any](s *State, name string) T {
+	v, err := LookupFlag[T](s, name)
+	if err != nil {
+		panic(&internalError{err: err})
+	}
+	return v
+}
+
+// LookupFlag retrieves a flag value by name from the command hierarchy, using the same lookup rules
+// as [GetFlag]. Unlike GetFlag, it returns an error instead of panicking if the flag doesn't exist or
+// if the type doesn't match the requested type T. This is useful when flags are constructed
+// dynamically and their presence isn't known ahead of time.
+//
+//	count, err := LookupFlag[int](state, "count")
+//	if err != nil {
+//	    return err
+//	}
+func LookupFlag[T any](s *State, name string) (T, error) {
+	var zero T
+	if s == nil {
+		return zero, fmt.Errorf("flag %q: state is nil", formatFlagName(name))
+	}
 	// Try to find the flag in each command's flag set, starting from the current command
 	for i := len(s.path) - 1; i >= 0; i-- {
-		cmd := s.path[i]
-		if cmd.Flags == nil {
-			continue
-		}
-
-		if f := cmd.Flags.Lookup(name); f != nil {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			f := fset.Lookup(name)
+			if f == nil {
+				continue
+			}
+			s.markAccessed(name)
 			if getter, ok := f.Value.(flag.Getter); ok {
 				value := getter.Get()
-				if v, ok := value.(T); ok {
-					return v
+				if v, ok := matchFlagValue[T](value); ok {
+					return v, nil
 				}
-				err := fmt.Errorf("type mismatch for flag %q in command %q: registered %T, requested %T",
+				// Flag exists but type doesn't match
+				return zero, fmt.Errorf("type mismatch for flag %q in command %q: registered %T, requested %T",
 					formatFlagName(name),
 					getCommandPath(s.path),
 					value,
-					*new(T),
+					zero,
 				)
-				// Flag exists but type doesn't match - this is an internal error
-				panic(&internalError{err: err})
 			}
 		}
 	}
 
-	// If flag not found anywhere in hierarchy, panic with helpful message
-	err := fmt.Errorf("flag %q not found in command %q flag set",
+	return zero, fmt.Errorf("flag %q not found in command %q flag set",
 		formatFlagName(name),
 		getCommandPath(s.path),
 	)
-	panic(&internalError{err: err})
+}
+
+func (s *State) markAccessed(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessedFlags == nil {
+		s.accessedFlags = make(map[string]bool)
+	}
+	s.accessedFlags[name] = true
+}
+
+// unusedParentFlags returns the names of flags that were explicitly set on the command line, are
+// defined by an ancestor of the terminal command, and were never looked up during execution.
+func (s *State) unusedParentFlags() []string {
+	if len(s.path) < 2 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	terminal := s.clones.pathFlagSets(s.path, len(s.path)-1)
+	var unused []string
+	for i := 0; i < len(s.path)-1; i++ {
+		for _, fset := range s.clones.pathFlagSets(s.path, i) {
+			fset.VisitAll(func(f *flag.Flag) {
+				if !s.setFlags[f.Name] || s.accessedFlags[f.Name] || slices.Contains(unused, f.Name) {
+					return
+				}
+				for _, tf := range terminal {
+					if tf.Lookup(f.Name) != nil {
+						return
+					}
+				}
+				unused = append(unused, f.Name)
+			})
+		}
+	}
+	return unused
+}
+
+// matchFlagValue converts a value returned by [flag.Getter] to T. See [GetFlag] for the matching
+// rules.
+func matchFlagValue[T any](value any) (T, bool) {
+	if v, ok := value.(T); ok {
+		return v, true
+	}
+	var zero T
+	target := reflect.TypeOf(&zero).Elem()
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+			return zero, true
+		}
+		return zero, false
+	}
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Type().Elem().AssignableTo(target) {
+		rv = rv.Elem()
+	}
+	if rv.Type().AssignableTo(target) {
+		reflect.ValueOf(&zero).Elem().Set(rv)
+		return zero, true
+	}
+	return zero, false
 }
 
 // internalError is a marker type for errors that originate from the cli package itself. These are
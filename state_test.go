@@ -1,7 +1,10 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"flag"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,3 +51,320 @@ func TestGetFlag(t *testing.T) {
 		_ = GetFlag[int](state, "version")
 	})
 }
+
+func TestLookupFlag(t *testing.T) {
+	t.Parallel()
+
+	parent := &Command{
+		Name:  "root",
+		Flags: FlagsFunc(func(f *flag.FlagSet) { f.Int("count", 3, "count") }),
+	}
+	child := &Command{
+		Name:  "child",
+		Flags: FlagsFunc(func(f *flag.FlagSet) { f.String("name", "gopher", "name") }),
+	}
+	state := &State{
+		path: []*Command{parent, child},
+	}
+
+	t.Run("found in current command", func(t *testing.T) {
+		v, err := LookupFlag[string](state, "name")
+		require.NoError(t, err)
+		assert.Equal(t, "gopher", v)
+	})
+	t.Run("found in parent command", func(t *testing.T) {
+		v, err := LookupFlag[int](state, "count")
+		require.NoError(t, err)
+		assert.Equal(t, 3, v)
+	})
+	t.Run("flag not found", func(t *testing.T) {
+		v, err := LookupFlag[string](state, "missing")
+		require.Error(t, err)
+		assert.ErrorContains(t, err, `flag "-missing" not found in command "root child" flag set`)
+		assert.Empty(t, v)
+	})
+	t.Run("flag type mismatch", func(t *testing.T) {
+		v, err := LookupFlag[bool](state, "count")
+		require.Error(t, err)
+		assert.ErrorContains(t, err, `type mismatch for flag "-count" in command "root child": registered int, requested bool`)
+		assert.False(t, v)
+	})
+	t.Run("must get flag panics", func(t *testing.T) {
+		assert.Equal(t, 3, MustGetFlag[int](state, "count"))
+		assert.Panics(t, func() { _ = MustGetFlag[int](state, "missing") })
+	})
+}
+
+type point struct{ X, Y int }
+
+// pointValue is a custom flag.Value whose Getter returns a pointer.
+type pointValue struct{ p *point }
+
+func (v *pointValue) String() string   { return "" }
+func (v *pointValue) Set(string) error { return nil }
+func (v *pointValue) Get() any         { return v.p }
+
+// namesValue is a custom flag.Value whose Getter returns a named slice type.
+type names []string
+
+type namesValue struct{ n names }
+
+func (v *namesValue) String() string   { return "" }
+func (v *namesValue) Set(string) error { return nil }
+func (v *namesValue) Get() any         { return v.n }
+
+// nilValue is a custom flag.Value whose Getter returns nil.
+type nilValue struct{}
+
+func (v *nilValue) String() string   { return "" }
+func (v *nilValue) Set(string) error { return nil }
+func (v *nilValue) Get() any         { return nil }
+
+func TestGetFlagAssignability(t *testing.T) {
+	t.Parallel()
+
+	cmd := &Command{
+		Name: "root",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Var(&pointValue{p: &point{X: 1, Y: 2}}, "point", "point")
+			f.Var(&namesValue{n: names{"a", "b"}}, "names", "names")
+			f.Var(&nilValue{}, "nil", "nil")
+			f.Duration("timeout", 0, "timeout")
+		}),
+	}
+	state := &State{
+		path: []*Command{cmd},
+	}
+
+	t.Run("pointer requested as pointer", func(t *testing.T) {
+		assert.Equal(t, &point{X: 1, Y: 2}, GetFlag[*point](state, "point"))
+	})
+	t.Run("pointer requested as concrete type", func(t *testing.T) {
+		assert.Equal(t, point{X: 1, Y: 2}, GetFlag[point](state, "point"))
+	})
+	t.Run("named type requested as underlying type", func(t *testing.T) {
+		assert.Equal(t, []string{"a", "b"}, GetFlag[[]string](state, "names"))
+		assert.Equal(t, names{"a", "b"}, GetFlag[names](state, "names"))
+	})
+	t.Run("interface", func(t *testing.T) {
+		assert.Equal(t, "0s", GetFlag[fmt.Stringer](state, "timeout").String())
+		assert.Equal(t, names{"a", "b"}, GetFlag[any](state, "names"))
+	})
+	t.Run("nil value", func(t *testing.T) {
+		assert.Nil(t, GetFlag[fmt.Stringer](state, "nil"))
+		assert.Nil(t, GetFlag[*point](state, "nil"))
+		_, err := LookupFlag[int](state, "nil")
+		assert.ErrorContains(t, err, `type mismatch for flag "-nil" in command "root": registered <nil>, requested int`)
+	})
+	t.Run("not assignable", func(t *testing.T) {
+		_, err := LookupFlag[string](state, "point")
+		assert.ErrorContains(t, err, `registered *cli.point, requested string`)
+	})
+}
+
+func TestFlagSources(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "us-east-1", "cloud region")
+			f.Bool("verbose", false, "enable verbose output")
+		}),
+		FlagsMetadata: []FlagMetadata{{Name: "region", EnvVar: "APP_REGION"}},
+		SubCommands: []*Command{
+			{
+				Name: "deploy",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Int("replicas", 1, "number of replicas")
+				}),
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			},
+		},
+	}
+	cfg := &parseConfig{lookupEnv: func(key string) (string, bool) { return "eu-west-1", key == "APP_REGION" }}
+	require.NoError(t, parse(root, []string{"deploy", "--replicas=3"}, cfg))
+	require.Equal(t, map[string]FlagSource{
+		"region":   FlagSourceEnv,
+		"verbose":  FlagSourceDefault,
+		"replicas": FlagSourceCommandLine,
+	}, root.state.FlagSources())
+	require.Equal(t, "command line", FlagSourceCommandLine.String())
+	require.True(t, root.state.Changed("region"))
+	require.True(t, root.state.Changed("replicas"))
+	require.False(t, root.state.Changed("verbose"))
+	require.False(t, root.state.IsSet("region"))
+}
+
+func TestStateFlag(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "us-east-1", "cloud region")
+		}),
+		SubCommands: []*Command{
+			{
+				Name: "deploy",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.Int("replicas", 1, "number of replicas")
+				}),
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			},
+		},
+	}
+	require.NoError(t, Parse(root, []string{"--region=eu-west-1", "deploy"}))
+	f, ok := root.state.Flag("region")
+	require.True(t, ok)
+	require.Equal(t, "cloud region", f.Usage)
+	require.Equal(t, "us-east-1", f.DefValue)
+	require.Equal(t, "eu-west-1", f.Value.String())
+	f, ok = root.state.Flag("replicas")
+	require.True(t, ok)
+	require.Equal(t, "1", f.DefValue)
+	_, ok = root.state.Flag("unknown")
+	require.False(t, ok)
+	require.False(t, root.state.accessedFlags["region"])
+}
+
+func TestCommandString(t *testing.T) {
+	t.Parallel()
+
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.String("region", "us-east-1", "cloud region")
+			f.Bool("verbose", false, "enable verbose output")
+			f.String("token", "", "API token")
+		}),
+		FlagsMetadata: []FlagMetadata{
+			{Name: "region", EnvVar: "APP_REGION"},
+			{Name: "token", Secret: true},
+		},
+		SubCommands: []*Command{
+			{
+				Name:        "deploy",
+				Destructive: true,
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.String("message", "", "deploy message")
+					StringSlice(f, "tag", nil, "image tag")
+					StringMap(f, "label", nil, "label")
+				}),
+				Exec: func(ctx context.Context, s *State) error { return nil },
+			},
+		},
+	}
+	cfg := &parseConfig{lookupEnv: func(key string) (string, bool) { return "eu west", key == "APP_REGION" }}
+	require.NoError(t, parse(root, []string{
+		"deploy", "--verbose", "--token=s3cret", "--message", "it's done", "--tag=a", "--tag=b c",
+		"--label", "env=prod", "--yes", "web", "--", "-v",
+	}, cfg))
+	got := root.state.CommandString()
+	require.Equal(t, `app deploy -label=env=prod '-message=it'\''s done' '-region=eu west' -tag=a '-tag=b c' `+
+		`'-token=****' -verbose -yes -- web -v`, got)
+
+	args, err := SplitCommandLine(got)
+	require.NoError(t, err)
+	require.Contains(t, args, "-message=it's done")
+	require.Contains(t, args, "-region=eu west")
+
+	require.NoError(t, Parse(root, []string{"deploy"}))
+	require.Equal(t, "app deploy", root.state.CommandString())
+	require.NoError(t, Parse(root, []string{"deploy", ""}))
+	require.Equal(t, "app deploy ''", root.state.CommandString())
+}
+
+func TestStateFromContext(t *testing.T) {
+	t.Parallel()
+
+	verbose := func(ctx context.Context) bool {
+		s, ok := StateFromContext(ctx)
+		return ok && GetFlag[bool](s, "verbose")
+	}
+	var got bool
+	root := &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("verbose", false, "enable verbose output")
+		}),
+		Exec: func(ctx context.Context, s *State) error {
+			got = verbose(ctx)
+			return nil
+		},
+	}
+	require.NoError(t, Parse(root, []string{"--verbose"}))
+	require.NoError(t, Run(context.Background(), root, nil))
+	require.True(t, got)
+
+	_, ok := StateFromContext(context.Background())
+	require.False(t, ok)
+}
+
+func TestArgsAfterDash(t *testing.T) {
+	t.Parallel()
+
+	run := func(t *testing.T, args ...string) (own, passthrough []string) {
+		t.Helper()
+		root := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("it", false, "interactive")
+			}),
+			Exec: func(ctx context.Context, s *State) error {
+				passthrough = s.ArgsAfterDash()
+				own = s.Args[:len(s.Args)-len(passthrough)]
+				return nil
+			},
+		}
+		require.NoError(t, RunArgs(context.Background(), root, args, nil))
+		return own, passthrough
+	}
+
+	own, passthrough := run(t, "pod", "-it", "--", "ls", "-l", "--", "x")
+	require.Equal(t, []string{"pod"}, own)
+	require.Equal(t, []string{"ls", "-l", "--", "x"}, passthrough)
+
+	own, passthrough = run(t, "pod", "ls")
+	require.Equal(t, []string{"pod", "ls"}, own)
+	require.Nil(t, passthrough)
+
+	own, passthrough = run(t, "pod", "--")
+	require.Equal(t, []string{"pod"}, own)
+	require.NotNil(t, passthrough)
+	require.Empty(t, passthrough)
+}
+
+func TestStateUsage(t *testing.T) {
+	t.Parallel()
+
+	var usage string
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name:  "add",
+					Usage: "app add <item>",
+					Exec: func(ctx context.Context, s *State) error {
+						usage = s.Usage()
+						return nil
+					},
+				},
+			},
+		}
+	}
+
+	require.NoError(t, RunArgs(context.Background(), newRoot(), []string{"add"}, nil))
+	require.Equal(t, "Usage:\n  app add <item>", usage)
+
+	var stdout bytes.Buffer
+	require.NoError(t, ParseAndRun(context.Background(), newRoot(), &Options{
+		Args:         []string{"add"},
+		RunOptions:   RunOptions{Stdout: &stdout},
+		UsageOptions: &UsageOptions{Renderer: footerRenderer{footer: "footer"}},
+	}))
+	require.Equal(t, "Usage:\n  app add <item>\n\nfooter", usage)
+
+	require.Empty(t, (&State{}).Usage())
+}
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// StructFlags defines a flag for each field of the struct pointed to by v that has a "flag" tag,
+// and returns the flag set along with the metadata of the flags. Parsed values are written to the
+// fields, so the struct holds the values of the flags by the time Exec runs. The current values of
+// the fields are the default values of the flags.
+//
+// The following tags are recognized:
+//
+//   - flag: the flag's name. Fields without it are ignored, except embedded structs, whose fields
+//     are defined as if they were fields of v.
+//   - usage: the flag's usage string.
+//   - required: "true" marks the flag as required, see [FlagMetadata.Required].
+//   - env: the environment variable the flag's value is read from, see [FlagMetadata.EnvVar].
+//
+// Fields may be of type string, bool, int, int64, uint, uint64, float64, [time.Duration],
+// []string, []int or map[string]string, or any type whose pointer implements [flag.Value]. Slice
+// and map fields are repeatable flags, like those defined with [StringSlice], [IntSlice] and
+// [StringMap]. StructFlags panics if v is not a pointer to a struct, or if a tagged field has an
+// unsupported type.
+//
+//	type deployOptions struct {
+//	    Dir     string        `flag:"dir" usage:"working directory" required:"true" env:"APP_DIR"`
+//	    Timeout time.Duration `flag:"timeout" usage:"deploy timeout"`
+//	}
+//
+//	opts := &deployOptions{Timeout: time.Minute}
+//	cmd.Flags, cmd.FlagsMetadata = cli.StructFlags(opts)
+//
+// Unlike other variables bound to flags, the fields are also set before Exec runs when the command
+// is run by [RunArgs] or [Invocation.Run], which do not otherwise modify the command tree. The
+// struct is shared by all runs, so such commands must not run concurrently; [ParseInvocation] and
+// [ParseOnly] alone leave the fields untouched.
+func StructFlags(v any) (*flag.FlagSet, []FlagMetadata) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("StructFlags: v must be a non-nil pointer to a struct, got %T", v))
+	}
+	fset := flag.NewFlagSet("", flag.ContinueOnError)
+	var (
+		fields   []structField
+		metadata []FlagMetadata
+	)
+	defineStructFlags(fset, rv.Elem(), &fields, &metadata)
+	structFlagSets.Store(fset, fields)
+	return fset, metadata
+}
+
+// structFlagSets maps flag sets returned by StructFlags to the struct fields their flags are bound
+// to, so the fields can be set from the copies of the flag sets used by an [Invocation].
+var structFlagSets sync.Map
+
+// structField is a struct field bound to a flag by StructFlags.
+type structField struct {
+	name  string
+	field reflect.Value
+}
+
+// set sets the field to the value of v, a copy of the flag value the field is bound to.
+func (sf structField) set(v flag.Value) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.Elem().Type() == sf.field.Type() {
+		sf.field.Set(rv.Elem())
+		return
+	}
+	if getter, ok := v.(flag.Getter); ok {
+		if x := reflect.ValueOf(getter.Get()); x.IsValid() && x.Type().AssignableTo(sf.field.Type()) {
+			sf.field.Set(x)
+		}
+	}
+}
+
+// bindStructFlags sets the struct fields bound by StructFlags to the values of the flags of s. It
+// is a no-op for states without copies of the flag sets, whose flags write to the fields directly.
+func bindStructFlags(s *State) {
+	if s.clones == nil {
+		return
+	}
+	for i := range s.path {
+		clones := s.clones.pathFlagSets(s.path, i)
+		for j, fset := range pathFlagSets(s.path, i) {
+			fields, ok := structFlagSets.Load(fset)
+			if !ok {
+				continue
+			}
+			for _, sf := range fields.([]structField) {
+				if f := clones[j].Lookup(sf.name); f != nil {
+					sf.set(f.Value)
+				}
+			}
+		}
+	}
+}
+
+func defineStructFlags(fset *flag.FlagSet, rv reflect.Value, fields *[]structField, metadata *[]FlagMetadata) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, ok := field.Tag.Lookup("flag")
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				defineStructFlags(fset, rv.Field(i), fields, metadata)
+			}
+			continue
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			panic(fmt.Sprintf("flag -%s: field %s is not exported", name, field.Name))
+		}
+		if !varFlag(fset, rv.Field(i).Addr().Interface(), name, field.Tag.Get("usage")) {
+			panic(fmt.Sprintf("flag -%s: field %s has unsupported type %s", name, field.Name, field.Type))
+		}
+		*fields = append(*fields, structField{name: name, field: rv.Field(i)})
+		m := FlagMetadata{Name: name, EnvVar: field.Tag.Get("env")}
+		if s, ok := field.Tag.Lookup("required"); ok {
+			required, err := strconv.ParseBool(s)
+			if err != nil {
+				panic(fmt.Sprintf("flag -%s: field %s has invalid required tag %q", name, field.Name, s))
+			}
+			m.Required = required
+		}
+		if m.Required || m.EnvVar != "" {
+			*metadata = append(*metadata, m)
+		}
+	}
+}
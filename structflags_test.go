@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type commonOptions struct {
+	Verbose bool `flag:"verbose" usage:"enable verbose output"`
+}
+
+type deployOptions struct {
+	commonOptions
+	Dir      string            `flag:"dir" usage:"working directory" required:"true" env:"APP_DIR"`
+	Replicas int               `flag:"replicas" usage:"number of replicas"`
+	Timeout  time.Duration     `flag:"timeout" usage:"deploy timeout"`
+	Tags     []string          `flag:"tag" usage:"tag to apply, may be repeated"`
+	Labels   map[string]string `flag:"label" usage:"label to apply"`
+	Format   countValue        `flag:"v" usage:"increase verbosity"`
+	Ignored  string
+	Skipped  string `flag:"-"`
+}
+
+func TestStructFlags(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(opts *deployOptions, exec func(*State)) *Command {
+		root := &Command{
+			Name: "deploy",
+			Exec: func(ctx context.Context, s *State) error {
+				exec(s)
+				return nil
+			},
+		}
+		root.Flags, root.FlagsMetadata = StructFlags(opts)
+		return root
+	}
+
+	t.Run("binds values before exec", func(t *testing.T) {
+		t.Parallel()
+		opts := &deployOptions{Replicas: 3, Timeout: time.Minute}
+		var got deployOptions
+		root := newRoot(opts, func(s *State) {
+			got = *opts
+			require.Equal(t, "/srv", GetFlag[string](s, "dir"))
+			require.Equal(t, []string{"a", "b"}, GetFlag[[]string](s, "tag"))
+		})
+		args := []string{"-verbose", "-dir=/srv", "-timeout=5s", "-tag=a", "-tag=b", "-label", "env=prod", "-v", "-v"}
+		require.NoError(t, RunArgs(context.Background(), root, args, nil))
+		require.Equal(t, deployOptions{
+			commonOptions: commonOptions{Verbose: true},
+			Dir:           "/srv",
+			Replicas:      3,
+			Timeout:       5 * time.Second,
+			Tags:          []string{"a", "b"},
+			Labels:        map[string]string{"env": "prod"},
+			Format:        2,
+		}, got)
+	})
+	t.Run("defaults and metadata", func(t *testing.T) {
+		t.Parallel()
+		opts := &deployOptions{Replicas: 3, Tags: []string{"x"}}
+		fset, metadata := StructFlags(opts)
+		require.Equal(t, []FlagMetadata{{Name: "dir", Required: true, EnvVar: "APP_DIR"}}, metadata)
+		require.Equal(t, "3", fset.Lookup("replicas").DefValue)
+		require.Equal(t, "x", fset.Lookup("tag").DefValue)
+		require.Equal(t, "working directory", fset.Lookup("dir").Usage)
+		require.Nil(t, fset.Lookup("Ignored"))
+		require.Nil(t, fset.Lookup("-"))
+	})
+	t.Run("parse invocation leaves fields untouched", func(t *testing.T) {
+		t.Parallel()
+		opts := &deployOptions{Replicas: 3}
+		root := newRoot(opts, func(*State) {})
+		inv, err := ParseInvocation(root, []string{"-dir=/srv", "-replicas=5"})
+		require.NoError(t, err)
+		require.Equal(t, 5, GetFlag[int](inv.State(), "replicas"))
+		require.Equal(t, 3, opts.Replicas)
+		require.Empty(t, opts.Dir)
+	})
+	t.Run("required and env", func(t *testing.T) {
+		t.Parallel()
+		opts := &deployOptions{}
+		root := newRoot(opts, func(*State) {})
+		err := parse(root, nil, &parseConfig{lookupEnv: func(string) (string, bool) { return "", false }})
+		require.ErrorContains(t, err, `required flag "-dir" not set`)
+
+		opts = &deployOptions{}
+		root = newRoot(opts, func(*State) {})
+		err = parse(root, nil, &parseConfig{lookupEnv: func(key string) (string, bool) { return "/env", key == "APP_DIR" }})
+		require.NoError(t, err)
+		require.Equal(t, "/env", opts.Dir)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		require.PanicsWithValue(t, "StructFlags: v must be a non-nil pointer to a struct, got cli.deployOptions", func() {
+			StructFlags(deployOptions{})
+		})
+		require.PanicsWithValue(t, "flag -ch: field Ch has unsupported type chan int", func() {
+			StructFlags(&struct {
+				Ch chan int `flag:"ch"`
+			}{})
+		})
+		require.PanicsWithValue(t, `flag -dir: field Dir has invalid required tag "yes"`, func() {
+			StructFlags(&struct {
+				Dir string `flag:"dir" required:"yes"`
+			}{})
+		})
+	})
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"slices"
+	"time"
+)
+
+// Telemetry reports which commands and flags are used, so teams can learn which parts of a CLI
+// matter to their users. Only command paths and flag names are reported, never argument or flag
+// values. See [RunOptions.Telemetry].
+type Telemetry struct {
+	// Reporter receives a [UsageEvent] for every reported invocation.
+	Reporter UsageReporter
+	// SampleRate is the fraction of invocations that are reported, between 0 and 1. If zero, every
+	// invocation is reported.
+	SampleRate float64
+	// DisableEnv is the name of an environment variable, e.g., "APP_NO_TELEMETRY", that disables
+	// reporting when set to a non-empty value, so users can opt out.
+	DisableEnv string
+	// Disabled turns reporting off entirely.
+	Disabled bool
+}
+
+// UsageEvent describes a command invocation, see [Telemetry].
+type UsageEvent struct {
+	// Command is the full command path, e.g., "app deploy".
+	Command string
+	// Flags holds the sorted names of the flags set on the command line.
+	Flags []string
+	// Duration is the time Exec took.
+	Duration time.Duration
+	// Failed reports whether Exec returned an error.
+	Failed bool
+}
+
+// UsageReporter receives usage events, see [Telemetry].
+type UsageReporter interface {
+	ReportUsage(ctx context.Context, event UsageEvent)
+}
+
+// UsageReporterFunc is an adapter to allow the use of ordinary functions as a [UsageReporter].
+type UsageReporterFunc func(ctx context.Context, event UsageEvent)
+
+// ReportUsage calls f(ctx, event).
+func (f UsageReporterFunc) ReportUsage(ctx context.Context, event UsageEvent) {
+	f(ctx, event)
+}
+
+// enabled reports whether an invocation should be reported, taking the kill switches and the
+// sample rate into account.
+func (t *Telemetry) enabled() bool {
+	if t == nil || t.Reporter == nil || t.Disabled {
+		return false
+	}
+	if t.DisableEnv != "" && os.Getenv(t.DisableEnv) != "" {
+		return false
+	}
+	return t.SampleRate <= 0 || t.SampleRate >= 1 || rand.Float64() < t.SampleRate
+}
+
+// report sends a usage event for the invocation of the terminal command of s.
+func (t *Telemetry) report(ctx context.Context, s *State, d time.Duration, err error) {
+	if !t.enabled() {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	flags := make([]string, 0, len(s.setFlags))
+	for name := range s.setFlags {
+		flags = append(flags, name)
+	}
+	slices.Sort(flags)
+	t.Reporter.ReportUsage(ctx, UsageEvent{
+		Command:  getCommandPath(s.path),
+		Flags:    flags,
+		Duration: d,
+		Failed:   err != nil,
+	})
+}
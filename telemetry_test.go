@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTelemetryRoot() *Command {
+	return &Command{
+		Name: "app",
+		Flags: FlagsFunc(func(f *flag.FlagSet) {
+			f.Bool("verbose", false, "verbose output")
+		}),
+		SubCommands: []*Command{
+			{
+				Name: "deploy",
+				Flags: FlagsFunc(func(f *flag.FlagSet) {
+					f.String("env", "dev", "target environment")
+					f.Bool("fail", false, "fail")
+				}),
+				Exec: func(ctx context.Context, s *State) error {
+					if GetFlag[bool](s, "fail") {
+						return errors.New("boom")
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func TestTelemetry(t *testing.T) {
+	t.Parallel()
+
+	t.Run("reports command and flag names", func(t *testing.T) {
+		t.Parallel()
+		var events []UsageEvent
+		telemetry := &Telemetry{Reporter: UsageReporterFunc(func(ctx context.Context, e UsageEvent) {
+			events = append(events, e)
+		})}
+		opt := &RunOptions{Stdout: new(bytes.Buffer), Telemetry: telemetry}
+		require.NoError(t, RunArgs(context.Background(), newTelemetryRoot(), []string{"deploy", "--env", "prod-secret", "-verbose"}, opt))
+		require.Error(t, RunArgs(context.Background(), newTelemetryRoot(), []string{"deploy", "--fail"}, opt))
+		require.Len(t, events, 2)
+		assert.Equal(t, "app deploy", events[0].Command)
+		assert.Equal(t, []string{"env", "verbose"}, events[0].Flags)
+		assert.False(t, events[0].Failed)
+		assert.Equal(t, []string{"fail"}, events[1].Flags)
+		assert.True(t, events[1].Failed)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		telemetry := &Telemetry{
+			Reporter: UsageReporterFunc(func(ctx context.Context, e UsageEvent) { t.Error("unexpected report") }),
+			Disabled: true,
+		}
+		require.NoError(t, RunArgs(context.Background(), newTelemetryRoot(), []string{"deploy"}, &RunOptions{Telemetry: telemetry}))
+	})
+	t.Run("sampling", func(t *testing.T) {
+		t.Parallel()
+		telemetry := &Telemetry{Reporter: UsageReporterFunc(func(context.Context, UsageEvent) {}), SampleRate: 0.5}
+		var n int
+		for i := 0; i < 2000; i++ {
+			if telemetry.enabled() {
+				n++
+			}
+		}
+		assert.InDelta(t, 1000, n, 300)
+		assert.False(t, (*Telemetry)(nil).enabled())
+		assert.False(t, (&Telemetry{}).enabled())
+	})
+}
+
+func TestTelemetryDisableEnv(t *testing.T) {
+	t.Setenv("APP_NO_TELEMETRY", "1")
+	telemetry := &Telemetry{
+		Reporter:   UsageReporterFunc(func(ctx context.Context, e UsageEvent) { t.Error("unexpected report") }),
+		DisableEnv: "APP_NO_TELEMETRY",
+	}
+	require.NoError(t, RunArgs(context.Background(), newTelemetryRoot(), []string{"deploy"}, &RunOptions{Telemetry: telemetry}))
+	t.Setenv("APP_NO_TELEMETRY", "")
+	assert.True(t, telemetry.enabled())
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+const timeoutFlagName = "timeout"
+
+// TimeoutFlag defines the conventional -timeout flag, which bounds how long Exec may run, e.g.,
+// "-timeout 30s". If the flag is set, its value takes precedence over [Command.Timeout], and 0
+// disables the timeout. The flag is typically defined on the root command, so it applies to every
+// subcommand.
+//
+// The flag value can be retrieved with GetFlag[time.Duration].
+func TimeoutFlag(f *flag.FlagSet) *time.Duration {
+	p := new(time.Duration)
+	f.Var((*timeoutValue)(p), timeoutFlagName, "abort the command after `DURATION`, e.g., 30s or 5m")
+	return p
+}
+
+// timeoutValue is the value of a flag defined by [TimeoutFlag].
+type timeoutValue time.Duration
+
+func (v *timeoutValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.New("parse error")
+	}
+	if d < 0 {
+		return errors.New("must not be negative")
+	}
+	*v = timeoutValue(d)
+	return nil
+}
+
+func (v *timeoutValue) Get() any { return time.Duration(*v) }
+
+func (v *timeoutValue) String() string {
+	if v == nil {
+		return "0s"
+	}
+	return time.Duration(*v).String()
+}
+
+// TimeoutError is returned by [Run] when Exec did not finish within the timeout of the command,
+// see [Command.Timeout] and [TimeoutFlag]. It wraps [context.DeadlineExceeded].
+type TimeoutError struct {
+	// Command is the full path of the command, e.g., "app deploy".
+	Command string
+	// Timeout is the timeout that was exceeded.
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("command %q timed out after %s", e.Command, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// execTimeout returns the timeout for Exec of the terminal command: the value of the -timeout flag
+// if it was set, and [Command.Timeout] otherwise. The flag counts as used by the command, see
+// [RunOptions.UnusedParentFlags].
+func execTimeout(cmd *Command, s *State) time.Duration {
+	if f, ok := s.Flag(timeoutFlagName); ok && s.Changed(timeoutFlagName) {
+		if v, ok := f.Value.(*timeoutValue); ok {
+			s.markAccessed(timeoutFlagName)
+			return time.Duration(*v)
+		}
+	}
+	return cmd.Timeout
+}
+
+// runWithTimeout runs Exec of cmd like runWithRetry, with a context that is canceled once the
+// timeout of the command expires, so the timeout includes all retries. If Exec fails after the
+// timeout expired, a [*TimeoutError] is returned instead of its error.
+func runWithTimeout(ctx context.Context, cmd *Command, s *State) error {
+	timeout := execTimeout(cmd, s)
+	if timeout <= 0 {
+		s.ctx = ctx
+		return runWithRetry(ctx, cmd, s)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmdPath := getCommandPath(s.path)
+	s.debugLog.printf("running %q with a timeout of %s", cmdPath, timeout)
+	timeoutErr := &TimeoutError{Command: cmdPath, Timeout: timeout}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, timeoutErr)
+	defer cancel()
+	s.ctx = ctx
+	err := runWithRetry(ctx, cmd, s)
+	if err != nil && context.Cause(ctx) == timeoutErr {
+		return timeoutErr
+	}
+	return err
+}
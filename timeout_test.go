@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(timeout time.Duration, exec func(ctx context.Context, s *State) error) *Command {
+		return &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				TimeoutFlag(f)
+			}),
+			SubCommands: []*Command{
+				{Name: "wait", Timeout: timeout, Exec: exec},
+			},
+		}
+	}
+	wait := func(ctx context.Context, s *State) error {
+		<-ctx.Done()
+		return CheckCtx(ctx)
+	}
+
+	t.Run("exceeded", func(t *testing.T) {
+		t.Parallel()
+		err := RunArgs(context.Background(), newRoot(10*time.Millisecond, wait), []string{"wait"}, nil)
+		require.EqualError(t, err, `command "app wait" timed out after 10ms`)
+		var timeoutErr *TimeoutError
+		require.ErrorAs(t, err, &timeoutErr)
+		require.Equal(t, "app wait", timeoutErr.Command)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, ErrInterrupted)
+	})
+	t.Run("finished in time", func(t *testing.T) {
+		t.Parallel()
+		var hasDeadline bool
+		root := newRoot(time.Minute, func(ctx context.Context, s *State) error {
+			_, hasDeadline = ctx.Deadline()
+			return nil
+		})
+		require.NoError(t, RunArgs(context.Background(), root, []string{"wait"}, nil))
+		require.True(t, hasDeadline)
+
+		root = newRoot(time.Minute, func(ctx context.Context, s *State) error {
+			return errors.New("boom")
+		})
+		require.EqualError(t, RunArgs(context.Background(), root, []string{"wait"}, nil), "boom")
+	})
+	t.Run("parent context canceled", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := RunArgs(ctx, newRoot(time.Minute, wait), []string{"wait"}, nil)
+		require.ErrorIs(t, err, ErrInterrupted)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("flag overrides command", func(t *testing.T) {
+		t.Parallel()
+		options := &RunOptions{UnusedParentFlags: UnusedFlagError}
+		err := RunArgs(context.Background(), newRoot(time.Minute, wait), []string{"wait", "--timeout=10ms"}, options)
+		require.EqualError(t, err, `command "app wait" timed out after 10ms`)
+
+		var hasDeadline bool
+		var timeout time.Duration
+		root := newRoot(time.Millisecond, func(ctx context.Context, s *State) error {
+			_, hasDeadline = ctx.Deadline()
+			timeout = GetFlag[time.Duration](s, "timeout")
+			return nil
+		})
+		require.NoError(t, RunArgs(context.Background(), root, []string{"wait", "--timeout", "0"}, options))
+		require.False(t, hasDeadline)
+		require.Zero(t, timeout)
+	})
+	t.Run("invalid flag", func(t *testing.T) {
+		t.Parallel()
+		err := RunArgs(context.Background(), newRoot(0, wait), []string{"wait", "--timeout=soon"}, nil)
+		require.ErrorContains(t, err, `invalid value "soon" for flag -timeout: parse error`)
+		err = RunArgs(context.Background(), newRoot(0, wait), []string{"wait", "--timeout=-1s"}, nil)
+		require.ErrorContains(t, err, `invalid value "-1s" for flag -timeout: must not be negative`)
+	})
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"time"
+)
+
+// Trace holds optional hooks called at each stage of a command invocation, e.g., to emit
+// OpenTelemetry spans or usage metrics without wrapping every Exec function. Any hook may be nil.
+// See [RunOptions.Trace].
+//
+// The parse hooks are called by [ParseAndRun], [RunArgs] and [Interactive], which parse the
+// arguments themselves. The exec hooks are called whenever a command is run, including by [Run].
+type Trace struct {
+	// OnParseStart is called with the arguments before they are parsed. Values of secret flags
+	// (see [FlagMetadata.Secret]) are masked.
+	OnParseStart func(args []string)
+	// OnCommandResolved is called after parsing succeeded, with the command chain from the root
+	// command to the terminal command that will be run.
+	OnCommandResolved func(path []*Command)
+	// OnExecStart is called before Exec. The returned context, if not nil, is passed to Exec and
+	// OnExecEnd, e.g., to carry a span.
+	OnExecStart func(ctx context.Context, s *State) context.Context
+	// OnExecEnd is called after Exec returned, with the time it took and the error it returned.
+	OnExecEnd func(ctx context.Context, s *State, d time.Duration, err error)
+}
+
+func (t *Trace) parseStart(root *Command, args []string) {
+	if t != nil && t.OnParseStart != nil {
+		var secrets map[string]bool
+		if root != nil {
+			secrets = treeSecretFlags(root, nil)
+		}
+		t.OnParseStart(maskSecretArgs(args, secrets))
+	}
+}
+
+func (t *Trace) commandResolved(path []*Command) {
+	if t != nil && t.OnCommandResolved != nil {
+		t.OnCommandResolved(path)
+	}
+}
+
+// exec runs fn between the OnExecStart and OnExecEnd hooks.
+func (t *Trace) exec(ctx context.Context, s *State, fn func(context.Context) error) error {
+	if t == nil {
+		return fn(ctx)
+	}
+	if t.OnExecStart != nil {
+		s.debugLog.printf("calling Trace.OnExecStart")
+		if c := t.OnExecStart(ctx, s); c != nil {
+			ctx = c
+		}
+	}
+	start := time.Now()
+	err := fn(ctx)
+	if t.OnExecEnd != nil {
+		s.debugLog.printf("calling Trace.OnExecEnd")
+		t.OnExecEnd(ctx, s, time.Since(start), err)
+	}
+	return err
+}
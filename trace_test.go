@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type traceKey struct{}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name: "app",
+			SubCommands: []*Command{
+				{
+					Name:  "deploy",
+					Flags: FlagsFunc(func(f *flag.FlagSet) { f.Bool("fail", false, "fail") }),
+					Exec: func(ctx context.Context, s *State) error {
+						if ctx.Value(traceKey{}) != "span" {
+							return errors.New("missing span")
+						}
+						if GetFlag[bool](s, "fail") {
+							return errors.New("boom")
+						}
+						return nil
+					},
+				},
+			},
+		}
+	}
+	newTrace := func(events *[]string) *Trace {
+		return &Trace{
+			OnParseStart: func(args []string) {
+				*events = append(*events, "parse "+strings.Join(args, " "))
+			},
+			OnCommandResolved: func(path []*Command) {
+				*events = append(*events, "resolved "+getCommandPath(path))
+			},
+			OnExecStart: func(ctx context.Context, s *State) context.Context {
+				*events = append(*events, "start")
+				return context.WithValue(ctx, traceKey{}, "span")
+			},
+			OnExecEnd: func(ctx context.Context, s *State, d time.Duration, err error) {
+				assert.Equal(t, "span", ctx.Value(traceKey{}))
+				assert.GreaterOrEqual(t, d, time.Duration(0))
+				*events = append(*events, "end "+errString(err))
+			},
+		}
+	}
+
+	t.Run("parse and run", func(t *testing.T) {
+		t.Parallel()
+		var events []string
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:       []string{"deploy", "--fail"},
+			RunOptions: RunOptions{Trace: newTrace(&events), Stdout: new(bytes.Buffer), Stderr: new(bytes.Buffer)},
+		})
+		require.Error(t, err)
+		assert.Equal(t, []string{"parse deploy --fail", "resolved app deploy", "start", "end boom"}, events)
+	})
+	t.Run("parse error", func(t *testing.T) {
+		t.Parallel()
+		var events []string
+		err := RunArgs(context.Background(), newRoot(), []string{"unknown"}, &RunOptions{Trace: newTrace(&events)})
+		require.Error(t, err)
+		assert.Equal(t, []string{"parse unknown"}, events)
+	})
+	t.Run("run", func(t *testing.T) {
+		t.Parallel()
+		var events []string
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"deploy"}))
+		require.NoError(t, Run(context.Background(), root, &RunOptions{Trace: newTrace(&events)}))
+		assert.Equal(t, []string{"start", "end "}, events)
+	})
+	t.Run("nil hooks", func(t *testing.T) {
+		t.Parallel()
+		err := RunArgs(context.Background(), newRoot(), []string{"deploy"}, &RunOptions{Trace: &Trace{}})
+		require.EqualError(t, err, "missing span")
+	})
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
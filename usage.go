@@ -4,115 +4,272 @@ import (
 	"cmp"
 	"flag"
 	"fmt"
+	"io"
+	"os"
 	"slices"
 	"strings"
 
+	"github.com/mfridman/cli/pkg/style"
 	"github.com/mfridman/cli/pkg/textutil"
 )
 
+// UsageOptions specifies options for rendering usage text with [FormatUsage].
+type UsageOptions struct {
+	// ShowFlagOrigin annotates each global flag with the command that defines it, e.g., (from
+	// "todo"). This helps users of deep command trees understand where behavior comes from.
+	ShowFlagOrigin bool
+
+	// Style is an optional theme used to colorize headings, command names and flag names. If nil,
+	// the usage text is not colorized. Color is automatically disabled when the NO_COLOR
+	// environment variable is set or when Output is not a terminal. See [DefaultUsageStyle].
+	Style *UsageStyle
+	// Output is the writer the usage text will be written to. It is only used to detect whether
+	// color is supported and the terminal width, and defaults to [os.Stdout].
+	Output io.Writer
+
+	// Width is the number of columns to wrap usage text at. If zero, the width of the terminal
+	// Output is attached to is used (see [style.TerminalWidth]), falling back to 80 columns if it
+	// cannot be determined.
+	Width int
+	// MaxWidth caps the detected terminal width, so help text remains readable on very wide
+	// terminals. It does not apply to an explicit Width. Defaults to 120.
+	MaxWidth int
+
+	// Messages translates the headings and annotations of the usage text, see [Messages]. If nil,
+	// English text is used.
+	Messages *Messages
+
+	// Renderer renders the usage text of commands without a [Command.UsageFunc]. If nil,
+	// [DefaultRenderer] is used.
+	Renderer HelpRenderer
+
+	// MaxCommands summarizes the subcommands of a command with more than MaxCommands subcommands:
+	// instead of their commands, each [Command.Group] is listed with the number of commands it
+	// holds. Commands without a group are still listed. With [Options.HelpCommand] set, the help
+	// text closes with a hint on how to list the commands of a group or all commands. Zero lists
+	// all commands.
+	MaxCommands int
+	// AllCommands lists all subcommands, even if there are more than MaxCommands.
+	AllCommands bool
+
+	// helpCommand reports whether the hidden help command is enabled, see [Options.HelpCommand].
+	helpCommand bool
+}
+
+// HelpRenderer renders the usage text of a command. It is used for all usage text rendered by the
+// framework, including the text shown for the help flags and for [ShowHelp], so an application can
+// replace the layout of its help text in one place, see [UsageOptions.Renderer].
+type HelpRenderer interface {
+	// RenderUsage returns the usage text of the last command of path, which holds the commands from
+	// the root command to that command. The path is never empty, and options is never nil.
+	RenderUsage(path []*Command, options *UsageOptions) string
+}
+
+// DefaultRendererVersion is the version of the usage text layout of [DefaultRenderer]. It is
+// incremented whenever a change to DefaultRenderer alters its output for an unchanged command tree,
+// so golden tests of help text can record the version they were generated with and be regenerated
+// deliberately, rather than fail on unrelated upgrades.
+const DefaultRendererVersion = 1
+
+// DefaultRenderer is the [HelpRenderer] used when [UsageOptions.Renderer] is nil. It renders the
+// command's short and long help, usage pattern, subcommands, flags, environment variables and
+// related commands. Custom renderers may wrap it, e.g., to append a footer.
+type DefaultRenderer struct{}
+
+// RenderUsage implements [HelpRenderer].
+func (DefaultRenderer) RenderUsage(path []*Command, options *UsageOptions) string {
+	if len(path) == 0 {
+		return ""
+	}
+	if options == nil {
+		options = &UsageOptions{}
+	}
+	return renderUsage(path, options)
+}
+
+const (
+	defaultUsageWidth    = 80
+	defaultUsageMaxWidth = 120
+)
+
+// usageWidth returns the number of columns to wrap usage text at, based on the options.
+func usageWidth(options *UsageOptions, output io.Writer) int {
+	if options.Width > 0 {
+		return options.Width
+	}
+	width, ok := style.TerminalWidth(output)
+	if !ok {
+		return defaultUsageWidth
+	}
+	maxWidth := options.MaxWidth
+	if maxWidth <= 0 {
+		maxWidth = defaultUsageMaxWidth
+	}
+	return min(width, maxWidth)
+}
+
+// UsageStyle is a color theme for usage text, see [style.Theme].
+type UsageStyle = style.Theme
+
+// DefaultUsageStyle returns the default color theme, see [style.DefaultTheme].
+func DefaultUsageStyle() *UsageStyle {
+	return style.DefaultTheme()
+}
+
 // DefaultUsage returns the default usage string for the command hierarchy. It is used when the
 // command does not provide a custom usage function. The usage string includes the command's short
 // help, usage pattern, available subcommands, and flags.
 func DefaultUsage(root *Command) string {
+	return FormatUsage(root, nil)
+}
+
+// FormatUsage is like [DefaultUsage] but renders the usage string according to the given options.
+// The options parameter may be nil, in which case default values are used. See [UsageOptions] for
+// more details.
+func FormatUsage(root *Command, options *UsageOptions) string {
 	if root == nil {
 		return ""
 	}
+	return formatUsage(root, root.Path(), options)
+}
 
-	// Get terminal command from state
-	terminalCmd := root.terminal()
-
-	var b strings.Builder
-
+// formatUsage renders the usage string of the terminal command of path, or of root if path is
+// empty, with the command's UsageFunc or the renderer of the options.
+func formatUsage(root *Command, path []*Command, options *UsageOptions) string {
+	if options == nil {
+		options = &UsageOptions{}
+	}
+	if len(path) == 0 {
+		path = []*Command{root}
+	}
+	terminalCmd := path[len(path)-1]
 	if terminalCmd.UsageFunc != nil {
 		return terminalCmd.UsageFunc(terminalCmd)
 	}
+	if options.Renderer != nil {
+		return options.Renderer.RenderUsage(path, options)
+	}
+	return renderUsage(path, options)
+}
+
+// renderUsage renders the usage string of the terminal command of path in the layout of
+// DefaultRenderer.
+func renderUsage(path []*Command, options *UsageOptions) string {
+	output := options.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	var theme style.Theme
+	if options.Style != nil && style.Enabled(output) {
+		theme = *options.Style
+	}
+	width := usageWidth(options, output)
+	messages := options.Messages
+	terminalCmd := path[len(path)-1]
+
+	var b strings.Builder
 
 	if terminalCmd.ShortHelp != "" {
 		b.WriteString(terminalCmd.ShortHelp)
 		b.WriteString("\n\n")
 	}
-
-	b.WriteString("Usage:\n")
-	if terminalCmd.Usage != "" {
-		b.WriteString("  " + terminalCmd.Usage + "\n")
-	} else {
-		usage := terminalCmd.Name
-		if root.state != nil && len(root.state.path) > 0 {
-			usage = getCommandPath(root.state.path)
-		}
-		if terminalCmd.Flags != nil {
-			usage += " [flags]"
-		}
-		if len(terminalCmd.SubCommands) > 0 {
-			usage += " <command>"
-		}
-		b.WriteString("  " + usage + "\n")
+	if longHelp := textutil.WrapParagraphs(terminalCmd.LongHelp, width); longHelp != "" {
+		b.WriteString(longHelp)
+		b.WriteString("\n\n")
 	}
+
+	b.WriteString(theme.Heading.Apply(messages.usage()) + "\n")
+	cmdPath := getCommandPath(path)
+	b.WriteString("  " + usageLine(terminalCmd, cmdPath) + "\n")
 	b.WriteString("\n")
 
+	summarized := false
 	if len(terminalCmd.SubCommands) > 0 {
-		b.WriteString("Available Commands:\n")
-		sortedCommands := slices.Clone(terminalCmd.SubCommands)
-		slices.SortFunc(sortedCommands, func(a, b *Command) int {
-			return cmp.Compare(a.Name, b.Name)
-		})
-
-		maxNameLen := 0
-		for _, sub := range sortedCommands {
-			if len(sub.Name) > maxNameLen {
-				maxNameLen = len(sub.Name)
-			}
-		}
-
-		nameWidth := maxNameLen + 4
-		wrapWidth := 80 - nameWidth
-
-		for _, sub := range sortedCommands {
-			if sub.ShortHelp == "" {
-				fmt.Fprintf(&b, "  %s\n", sub.Name)
-				continue
-			}
-
-			lines := textutil.Wrap(sub.ShortHelp, wrapWidth)
-			padding := strings.Repeat(" ", maxNameLen-len(sub.Name)+4)
-			fmt.Fprintf(&b, "  %s%s%s\n", sub.Name, padding, lines[0])
-
-			indentPadding := strings.Repeat(" ", nameWidth+2)
-			for _, line := range lines[1:] {
-				fmt.Fprintf(&b, "%s%s\n", indentPadding, line)
-			}
-		}
-		b.WriteString("\n")
+		summarized = writeSubCommands(&b, terminalCmd, options, width, theme)
 	}
 
 	var flags []flagInfo
-	if root.state != nil && len(root.state.path) > 0 {
-		for i, cmd := range root.state.path {
-			if cmd.Flags == nil {
-				continue
-			}
-			isGlobal := i < len(root.state.path)-1
-			cmd.Flags.VisitAll(func(f *flag.Flag) {
+	metadata := make(map[string]FlagMetadata)
+	for i := range path {
+		for _, m := range pathFlagsMetadata(path, i) {
+			metadata[m.Name] = m
+		}
+	}
+	for i := range path {
+		start := len(flags)
+		isGlobal := i < len(path)-1
+		var origin string
+		if isGlobal && options.ShowFlagOrigin {
+			origin = getCommandPath(path[:i+1])
+		}
+		for _, fset := range pathFlagSets(path, i) {
+			fset.VisitAll(func(f *flag.Flag) {
+				m := metadata[f.Name]
+				placeholder, usage := flagPlaceholder(f, m)
+				if choices := flagChoices(f); len(choices) > 0 {
+					usage += " " + messages.flagChoices(choices)
+				}
+				name := "-" + f.Name
+				if placeholder != "" {
+					name += " " + placeholder
+				}
+				defval := f.DefValue
+				if m.DefaultText != "" {
+					defval = m.DefaultText
+				}
+				if m.Secret {
+					defval = ""
+				}
 				flags = append(flags, flagInfo{
-					name:   "-" + f.Name,
-					usage:  f.Usage,
-					defval: f.DefValue,
-					global: isGlobal,
+					key:      f.Name,
+					name:     name,
+					usage:    usage,
+					defval:   defval,
+					global:   isGlobal,
+					origin:   origin,
+					envVar:   m.EnvVar,
+					requires: m.Requires,
 				})
+				if m.Secret && !hasFlag(path, secretFileFlagName(f.Name)) {
+					placeholder, usage := flagPlaceholder(secretFileFlag(f.Name), FlagMetadata{})
+					flags = append(flags, flagInfo{
+						key:    f.Name,
+						name:   "-" + secretFileFlagName(f.Name) + " " + placeholder,
+						usage:  usage,
+						global: isGlobal,
+						origin: origin,
+					})
+				}
+			})
+		}
+		if path[i].DisableFlagSorting {
+			order := metadataOrder(pathFlagsMetadata(path, i))
+			slices.SortStableFunc(flags[start:], func(a, b flagInfo) int {
+				return order(a.key, b.key)
 			})
 		}
 	}
 
-	if len(flags) > 0 {
-		slices.SortFunc(flags, func(a, b flagInfo) int {
-			return cmp.Compare(a.name, b.name)
+	if terminalCmd.needsConfirmation() && !slices.ContainsFunc(flags, func(f flagInfo) bool {
+		return f.name == formatFlagName(confirmFlagName) || strings.HasPrefix(f.name, formatFlagName(confirmFlagName)+" ")
+	}) {
+		flags = append(flags, flagInfo{
+			name:   formatFlagName(confirmFlagName),
+			usage:  terminalCmd.confirmFlagUsage(),
+			defval: "false",
 		})
+	}
+
+	if len(flags) > 0 {
+		if !slices.ContainsFunc(path, func(c *Command) bool { return c.DisableFlagSorting }) {
+			slices.SortFunc(flags, func(a, b flagInfo) int {
+				return cmp.Compare(a.name, b.name)
+			})
+		}
 
 		maxFlagLen := 0
 		for _, f := range flags {
-			if len(f.name) > maxFlagLen {
-				maxFlagLen = len(f.name)
-			}
+			maxFlagLen = max(maxFlagLen, textutil.Width(f.name))
 		}
 
 		hasLocal := false
@@ -126,34 +283,218 @@ func DefaultUsage(root *Command) string {
 		}
 
 		if hasLocal {
-			b.WriteString("Flags:\n")
-			writeFlagSection(&b, flags, maxFlagLen, false)
+			b.WriteString(theme.Heading.Apply(messages.flags()) + "\n")
+			writeFlagSection(&b, flags, maxFlagLen, false, width, theme, messages)
 			b.WriteString("\n")
 		}
 
 		if hasGlobal {
-			b.WriteString("Global Flags:\n")
-			writeFlagSection(&b, flags, maxFlagLen, true)
+			b.WriteString(theme.Heading.Apply(messages.globalFlags()) + "\n")
+			writeFlagSection(&b, flags, maxFlagLen, true, width, theme, messages)
 			b.WriteString("\n")
 		}
 	}
 
+	if len(terminalCmd.EnvDocs) > 0 {
+		b.WriteString(theme.Heading.Apply(messages.environment()) + "\n")
+		rows := make([][2]string, 0, len(terminalCmd.EnvDocs))
+		for _, env := range terminalCmd.EnvDocs {
+			rows = append(rows, [2]string{env.Name, env.Usage})
+		}
+		writeColumns(&b, rows, width)
+		b.WriteString("\n")
+	}
+
+	if len(terminalCmd.SeeAlso) > 0 {
+		b.WriteString(theme.Heading.Apply(messages.seeAlso()) + "\n")
+		for _, p := range terminalCmd.SeeAlso {
+			fmt.Fprintf(&b, "  %s\n", theme.Command.Apply(p))
+		}
+		b.WriteString("\n")
+	}
+
 	if len(terminalCmd.SubCommands) > 0 {
-		cmdName := terminalCmd.Name
-		if root.state != nil && len(root.state.path) > 0 {
-			cmdName = getCommandPath(root.state.path)
+		b.WriteString(messages.moreHelp(cmdPath) + "\n")
+	}
+	if summarized && options.helpCommand {
+		helpPath := getCommandPath([]*Command{path[0], {Name: HelpCommandName}})
+		if len(path) > 1 {
+			helpPath += " " + getCommandPath(path[1:])
 		}
-		fmt.Fprintf(&b, "Use \"%s [command] --help\" for more information about a command.\n", cmdName)
+		b.WriteString(messages.groupHelp(helpPath) + "\n")
 	}
 
 	return strings.TrimRight(b.String(), "\n")
 }
 
-// writeFlagSection handles the formatting of flag descriptions
-func writeFlagSection(b *strings.Builder, flags []flagInfo, maxLen int, global bool) {
-	nameWidth := maxLen + 4
-	wrapWidth := 80 - nameWidth
+// writeSubCommands writes the subcommands of cmd: the commands without a group, followed by the
+// commands of each group, or by a summary of the groups if cmd has more than options.MaxCommands
+// subcommands. It reports whether the groups were summarized.
+func writeSubCommands(b *strings.Builder, cmd *Command, options *UsageOptions, width int, theme style.Theme) bool {
+	messages := options.Messages
+	ungrouped, groups, grouped := groupSubCommands(cmd)
+	summarize := len(groups) > 0 && options.MaxCommands > 0 &&
+		len(cmd.SubCommands) > options.MaxCommands && !options.AllCommands
+	if len(ungrouped) > 0 {
+		b.WriteString(theme.Heading.Apply(messages.availableCommands()) + "\n")
+		writeColumns(b, commandRows(ungrouped, theme, messages), width)
+		b.WriteString("\n")
+	}
+	if summarize {
+		b.WriteString(theme.Heading.Apply(messages.commandGroups()) + "\n")
+		rows := make([][2]string, 0, len(groups))
+		for _, group := range groups {
+			rows = append(rows, [2]string{theme.Command.Apply(group), messages.groupCount(len(grouped[group]))})
+		}
+		writeColumns(b, rows, width)
+		b.WriteString("\n")
+		return true
+	}
+	for _, group := range groups {
+		b.WriteString(theme.Heading.Apply(messages.groupHeading(group)) + "\n")
+		writeColumns(b, commandRows(grouped[group], theme, messages), width)
+		b.WriteString("\n")
+	}
+	return false
+}
 
+// groupSubCommands returns the sorted subcommands of cmd without a group, the names of the groups
+// of the others, sorted by name unless [Command.DisableCommandSorting] is set, and the sorted
+// subcommands of each group.
+func groupSubCommands(cmd *Command) (ungrouped []*Command, groups []string, grouped map[string][]*Command) {
+	grouped = make(map[string][]*Command)
+	for _, sub := range cmd.sortedSubCommands() {
+		if sub.Group == "" {
+			ungrouped = append(ungrouped, sub)
+			continue
+		}
+		if _, ok := grouped[sub.Group]; !ok {
+			groups = append(groups, sub.Group)
+		}
+		grouped[sub.Group] = append(grouped[sub.Group], sub)
+	}
+	if !cmd.DisableCommandSorting {
+		slices.Sort(groups)
+	}
+	return ungrouped, groups, grouped
+}
+
+// commandRows returns the rows listing cmds with their short help.
+func commandRows(cmds []*Command, theme style.Theme, messages *Messages) [][2]string {
+	rows := make([][2]string, 0, len(cmds))
+	for _, sub := range cmds {
+		shortHelp := sub.ShortHelp
+		if sub.Destructive {
+			shortHelp = strings.TrimSpace(shortHelp + " " + messages.destructive())
+		}
+		rows = append(rows, [2]string{theme.Command.Apply(sub.Name), shortHelp})
+	}
+	return rows
+}
+
+// formatGroupUsage renders the list of the subcommands of the terminal command of path in the
+// group named group, ignoring case, and reports whether the group exists.
+func formatGroupUsage(path []*Command, group string, options *UsageOptions) (string, bool) {
+	cmd := path[len(path)-1]
+	_, groups, grouped := groupSubCommands(cmd)
+	i := slices.IndexFunc(groups, func(g string) bool { return strings.EqualFold(g, group) })
+	if i < 0 {
+		return "", false
+	}
+	output := options.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	var theme style.Theme
+	if options.Style != nil && style.Enabled(output) {
+		theme = *options.Style
+	}
+	messages := options.Messages
+
+	var b strings.Builder
+	b.WriteString(theme.Heading.Apply(messages.groupHeading(groups[i])) + "\n")
+	writeColumns(&b, commandRows(grouped[groups[i]], theme, messages), usageWidth(options, output))
+	b.WriteString("\n")
+	b.WriteString(messages.moreHelp(getCommandPath(path)) + "\n")
+	return strings.TrimRight(b.String(), "\n"), true
+}
+
+// usageLine returns the command's usage pattern, or a default pattern derived from the command's
+// path, flags and subcommands if it has none.
+func usageLine(cmd *Command, cmdPath string) string {
+	if cmd.Usage != "" {
+		return cmd.Usage
+	}
+	usage := cmdPath
+	if cmd.Flags != nil {
+		usage += " [flags]"
+	}
+	if len(cmd.SubCommands) > 0 {
+		usage += " <command>"
+	}
+	return usage
+}
+
+// flagPlaceholder returns the name of the flag's value shown in help text and the flag's usage
+// string. The placeholder is taken from the metadata if set, otherwise from a back-quoted name in
+// the usage string, in which case the back quotes are removed from the returned usage.
+func flagPlaceholder(f *flag.Flag, m FlagMetadata) (placeholder, usage string) {
+	usage = f.Usage
+	if start := strings.IndexByte(usage, '`'); start >= 0 {
+		if end := strings.IndexByte(usage[start+1:], '`'); end >= 0 {
+			end += start + 1
+			placeholder = usage[start+1 : end]
+			usage = usage[:start] + placeholder + usage[end+1:]
+		}
+	}
+	if m.Placeholder != "" {
+		placeholder = m.Placeholder
+	}
+	return placeholder, usage
+}
+
+// sortedSubCommands returns the subcommands of c sorted by name, or in declaration order if
+// [Command.DisableCommandSorting] is set.
+func (c *Command) sortedSubCommands() []*Command {
+	subs := slices.Clone(c.SubCommands)
+	if !c.DisableCommandSorting {
+		slices.SortFunc(subs, func(a, b *Command) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+	}
+	return subs
+}
+
+// metadataOrder returns a comparison function for flag names that orders flags by the position of
+// their entry in metadata, followed by flags without metadata in lexicographical order. It is used
+// for commands with [Command.DisableFlagSorting], since a [flag.FlagSet] does not record the order
+// flags are defined in.
+func metadataOrder(metadata []FlagMetadata) func(a, b string) int {
+	index := make(map[string]int)
+	for i, m := range metadata {
+		if _, ok := index[m.Name]; !ok {
+			index[m.Name] = i
+		}
+	}
+	return func(a, b string) int {
+		ia, okA := index[a]
+		ib, okB := index[b]
+		switch {
+		case okA && okB:
+			return cmp.Compare(ia, ib)
+		case okA:
+			return -1
+		case okB:
+			return 1
+		}
+		return cmp.Compare(a, b)
+	}
+}
+
+// writeFlagSection handles the formatting of flag descriptions. Flag names are padded to maxLen,
+// so local and global flags are aligned alike.
+func writeFlagSection(b *strings.Builder, flags []flagInfo, maxLen int, global bool, width int, theme style.Theme, messages *Messages) {
+	var rows [][2]string
 	for _, f := range flags {
 		if f.global != global {
 			continue
@@ -161,23 +502,45 @@ func writeFlagSection(b *strings.Builder, flags []flagInfo, maxLen int, global b
 
 		description := f.usage
 		if f.defval != "" {
-			description += fmt.Sprintf(" (default: %s)", f.defval)
+			description += " " + messages.flagDefault(f.defval)
 		}
+		if f.envVar != "" {
+			description += " " + messages.flagEnv(f.envVar)
+		}
+		if len(f.requires) > 0 {
+			requires := make([]string, len(f.requires))
+			for i, name := range f.requires {
+				requires[i] = formatFlagName(name)
+			}
+			description += " " + messages.flagRequires(requires)
+		}
+		if f.origin != "" {
+			description += " " + messages.flagOrigin(f.origin)
+		}
+		name := theme.Flag.Apply(f.name) + strings.Repeat(" ", maxLen-textutil.Width(f.name))
+		rows = append(rows, [2]string{name, description})
+	}
+	writeColumns(b, rows, width)
+}
 
-		lines := textutil.Wrap(description, wrapWidth)
-		padding := strings.Repeat(" ", maxLen-len(f.name)+4)
-		fmt.Fprintf(b, "  %s%s%s\n", f.name, padding, lines[0])
-
-		indentPadding := strings.Repeat(" ", nameWidth+2)
-		for _, line := range lines[1:] {
-			fmt.Fprintf(b, "%s%s\n", indentPadding, line)
+// writeColumns writes rows as two columns, indented by two spaces, see [textutil.Columns].
+func writeColumns(b *strings.Builder, rows [][2]string, width int) {
+	for _, line := range strings.SplitAfter(textutil.Columns(rows, width, 4), "\n") {
+		if line != "" {
+			b.WriteString("  " + line)
 		}
 	}
 }
 
 type flagInfo struct {
+	// key is the name of the flag the entry belongs to, used to order flags by their metadata.
+	key    string
 	name   string
 	usage  string
 	defval string
 	global bool
+	origin string
+	envVar string
+	// requires holds the names of the flags the flag requires, see [FlagMetadata.Requires].
+	requires []string
 }
@@ -1,8 +1,12 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"flag"
+	"regexp"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -377,3 +381,369 @@ func TestWriteFlagSection(t *testing.T) {
 		require.NotContains(t, output, "Global Flags:")
 	})
 }
+
+func TestFormatUsage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("show flag origin", func(t *testing.T) {
+		t.Parallel()
+		s := newTestState()
+		err := Parse(s.root, []string{"nested", "sub", "--help"})
+		require.ErrorIs(t, err, flag.ErrHelp)
+
+		output := FormatUsage(s.root, &UsageOptions{ShowFlagOrigin: true})
+		require.Contains(t, output, `enable verbose mode (default: false) (from "todo")`)
+		require.Contains(t, output, `force the operation (default: false) (from "todo nested")`)
+		// Local flags are not annotated
+		require.Contains(t, output, "  -echo       echo the message\n")
+		require.NotContains(t, output, `(from "todo nested sub")`)
+
+		require.Equal(t, DefaultUsage(s.root), FormatUsage(s.root, nil))
+		require.NotContains(t, DefaultUsage(s.root), "(from ")
+	})
+	t.Run("style disabled when output is not a terminal", func(t *testing.T) {
+		t.Parallel()
+		s := newTestState()
+		err := Parse(s.root, []string{"--help"})
+		require.ErrorIs(t, err, flag.ErrHelp)
+
+		output := FormatUsage(s.root, &UsageOptions{Style: DefaultUsageStyle(), Output: &bytes.Buffer{}})
+		require.Equal(t, DefaultUsage(s.root), output)
+		require.NotContains(t, output, "\x1b[")
+	})
+	t.Run("flag placeholders", func(t *testing.T) {
+		t.Parallel()
+		root := &Command{
+			Name: "app",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("output", "", "write results to FILE")
+				f.String("config", "", "read `PATH` for settings")
+				f.Bool("verbose", false, "enable verbose output")
+			}),
+			FlagsMetadata: []FlagMetadata{
+				{Name: "output", Placeholder: "FILE"},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		err := Parse(root, []string{"--help"})
+		require.ErrorIs(t, err, flag.ErrHelp)
+
+		output := DefaultUsage(root)
+		require.Contains(t, output, "Flags:\n"+
+			"  -config PATH    read PATH for settings\n"+
+			"  -output FILE    write results to FILE\n"+
+			"  -verbose        enable verbose output (default: false)")
+	})
+	t.Run("environment", func(t *testing.T) {
+		t.Parallel()
+		root := &Command{
+			Name: "app",
+			EnvDocs: []EnvDoc{
+				{Name: "APP_HOME", Usage: "directory for app data"},
+				{Name: "NO_COLOR", Usage: "disable colored output"},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		require.NoError(t, Parse(root, nil))
+		require.Equal(t, "Usage:\n"+
+			"  app [flags]\n"+
+			"\n"+
+			"Environment:\n"+
+			"  APP_HOME    directory for app data\n"+
+			"  NO_COLOR    disable colored output", DefaultUsage(root))
+	})
+	t.Run("see also", func(t *testing.T) {
+		t.Parallel()
+		exec := func(ctx context.Context, s *State) error { return nil }
+		root := &Command{
+			Name: "app",
+			SubCommands: []*Command{{
+				Name: "backup",
+				SubCommands: []*Command{
+					{Name: "create", SeeAlso: []string{"app backup restore"}, Exec: exec},
+					{Name: "restore", SeeAlso: []string{"app backup create"}, Exec: exec},
+				},
+			}},
+		}
+		require.NoError(t, Parse(root, []string{"backup", "create"}))
+		require.Equal(t, "Usage:\n"+
+			"  app backup create [flags]\n"+
+			"\n"+
+			"See Also:\n"+
+			"  app backup restore", DefaultUsage(root))
+	})
+	t.Run("long help", func(t *testing.T) {
+		t.Parallel()
+		root := &Command{
+			Name:      "app",
+			ShortHelp: "Deploy the app",
+			LongHelp: `Deploys the app to every region that is
+enabled in the configuration.
+
+Example:
+
+  app --replicas=3`,
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		require.NoError(t, Parse(root, nil))
+		require.Equal(t, "Deploy the app\n"+
+			"\n"+
+			"Deploys the app to every region that is\n"+
+			"enabled in the configuration.\n"+
+			"\n"+
+			"Example:\n"+
+			"\n"+
+			"  app --replicas=3\n"+
+			"\n"+
+			"Usage:\n"+
+			"  app [flags]", FormatUsage(root, &UsageOptions{Width: 40}))
+	})
+}
+
+func TestUsageWidth(t *testing.T) {
+	longHelp := "this is a long description that should be wrapped differently depending on the width of the terminal"
+	newRoot := func() *Command {
+		root := &Command{
+			Name: "root",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("config", "", longHelp)
+			}),
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		require.NoError(t, Parse(root, nil))
+		return root
+	}
+
+	t.Run("explicit width", func(t *testing.T) {
+		output := FormatUsage(newRoot(), &UsageOptions{Width: 200})
+		require.Contains(t, output, "  -config    "+longHelp)
+
+		output = FormatUsage(newRoot(), &UsageOptions{Width: 40})
+		require.Contains(t, output, "  -config    this is a long description\n")
+	})
+	t.Run("default width", func(t *testing.T) {
+		t.Setenv("COLUMNS", "")
+		output := FormatUsage(newRoot(), &UsageOptions{Output: &bytes.Buffer{}})
+		require.Contains(t, output, "  -config    this is a long description that should be wrapped differently\n")
+	})
+	t.Run("terminal width capped", func(t *testing.T) {
+		t.Setenv("COLUMNS", "300")
+		output := FormatUsage(newRoot(), &UsageOptions{MaxWidth: 60})
+		require.Equal(t, FormatUsage(newRoot(), &UsageOptions{Width: 60}), output)
+		require.Equal(t, FormatUsage(newRoot(), &UsageOptions{Width: 120}), DefaultUsage(newRoot()))
+	})
+	t.Run("minimum wrap width", func(t *testing.T) {
+		output := FormatUsage(newRoot(), &UsageOptions{Width: 10})
+		require.Contains(t, output, "  -config    this is a long\n")
+	})
+}
+
+func TestUsageANSI(t *testing.T) {
+	t.Parallel()
+
+	ansi := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	newRoot := func(color func(string) string) *Command {
+		root := &Command{
+			Name:      "root",
+			ShortHelp: "root command",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("config", "", "read the configuration from this file, "+color("defaults to ./config.yaml")+" if unset")
+				f.Int("n", 1, color("number")+" of items")
+			}),
+			SubCommands: []*Command{
+				{Name: "add", ShortHelp: color("add") + " an item to the list, " + color("creating the list") + " if it does not exist"},
+				{Name: "remove", ShortHelp: "remove an item"},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		require.NoError(t, Parse(root, nil))
+		return root
+	}
+	plain := FormatUsage(newRoot(func(s string) string { return s }), &UsageOptions{Width: 50})
+	colored := FormatUsage(newRoot(func(s string) string { return "\x1b[2m" + s + "\x1b[0m" }), &UsageOptions{Width: 50})
+	require.NotEqual(t, plain, colored)
+	require.Equal(t, plain, ansi.ReplaceAllString(colored, ""))
+}
+
+func TestUsageDeclarationOrder(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(disable bool) *Command {
+		root := &Command{
+			Name:                  "root",
+			DisableCommandSorting: disable,
+			DisableFlagSorting:    disable,
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.String("input", "", "input file")
+				f.String("output", "", "output file")
+				f.Bool("verbose", false, "verbose output")
+				f.Bool("debug", false, "debug output")
+			}),
+			FlagsMetadata: []FlagMetadata{{Name: "output"}, {Name: "input"}},
+			SubCommands: []*Command{
+				{Name: "start"},
+				{Name: "stop"},
+				{Name: "restart"},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+		require.NoError(t, Parse(root, nil))
+		return root
+	}
+	order := func(output string, names ...string) []int {
+		var indexes []int
+		for _, name := range names {
+			indexes = append(indexes, strings.Index(output, name))
+		}
+		return indexes
+	}
+
+	sorted := DefaultUsage(newRoot(false))
+	require.IsIncreasing(t, order(sorted, "  restart", "  start", "  stop"))
+	require.IsIncreasing(t, order(sorted, "-debug", "-input", "-output", "-verbose"))
+
+	declared := DefaultUsage(newRoot(true))
+	require.IsIncreasing(t, order(declared, "  start", "  stop", "  restart"))
+	require.IsIncreasing(t, order(declared, "-output", "-input", "-debug", "-verbose"))
+
+	var b strings.Builder
+	require.NoError(t, GenerateDocs(&b, newRoot(true), "markdown"))
+	require.IsIncreasing(t, order(b.String(), "`root start`", "`root stop`", "`root restart`"))
+	require.IsIncreasing(t, order(b.String(), "`-output", "`-input", "`-debug", "`-verbose"))
+}
+
+// footerRenderer wraps the default renderer to append a footer to the usage text.
+type footerRenderer struct {
+	footer string
+}
+
+func (r footerRenderer) RenderUsage(path []*Command, options *UsageOptions) string {
+	return DefaultRenderer{}.RenderUsage(path, options) + "\n\n" + r.footer
+}
+
+func TestHelpRenderer(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *Command {
+		return &Command{
+			Name:      "app",
+			ShortHelp: "An application",
+			Flags: FlagsFunc(func(f *flag.FlagSet) {
+				f.Bool("verbose", false, "enable verbose output")
+			}),
+			SubCommands: []*Command{
+				{
+					Name:      "custom",
+					UsageFunc: func(*Command) string { return "custom usage" },
+					Exec:      func(ctx context.Context, s *State) error { return nil },
+				},
+			},
+			Exec: func(ctx context.Context, s *State) error { return nil },
+		}
+	}
+	renderer := footerRenderer{footer: "Docs: https://example.com"}
+
+	t.Run("default renderer", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, nil))
+		require.Equal(t, DefaultUsage(root), DefaultRenderer{}.RenderUsage(root.Path(), nil))
+		require.Equal(t, DefaultUsage(root), FormatUsage(root, &UsageOptions{Renderer: DefaultRenderer{}}))
+		require.Empty(t, DefaultRenderer{}.RenderUsage(nil, nil))
+	})
+	t.Run("unparsed root", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		output := DefaultUsage(root)
+		require.Contains(t, output, "app [flags] <command>")
+		require.Contains(t, output, "-verbose    enable verbose output (default: false)")
+	})
+	t.Run("custom renderer", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, nil))
+		output := FormatUsage(root, &UsageOptions{Renderer: renderer})
+		require.Equal(t, DefaultUsage(root)+"\n\nDocs: https://example.com", output)
+
+		var stdout bytes.Buffer
+		err := ParseAndRun(context.Background(), newRoot(), &Options{
+			Args:         []string{"--help"},
+			RunOptions:   RunOptions{Stdout: &stdout},
+			UsageOptions: &UsageOptions{Renderer: renderer},
+		})
+		require.NoError(t, err)
+		require.Equal(t, output+"\n", stdout.String())
+	})
+	t.Run("usage func takes precedence", func(t *testing.T) {
+		t.Parallel()
+		root := newRoot()
+		require.NoError(t, Parse(root, []string{"custom"}))
+		require.Equal(t, "custom usage", FormatUsage(root, &UsageOptions{Renderer: renderer}))
+	})
+}
+
+func TestCommandGroups(t *testing.T) {
+	t.Parallel()
+
+	exec := func(ctx context.Context, s *State) error { return nil }
+	root := &Command{
+		Name: "api",
+		SubCommands: []*Command{
+			{Name: "login", ShortHelp: "Log in", Exec: exec},
+			{Name: "objects", ShortHelp: "Manage objects", Group: "Storage", Exec: exec},
+			{Name: "buckets", ShortHelp: "Manage buckets", Group: "Storage", Exec: exec},
+			{Name: "vms", ShortHelp: "Manage virtual machines", Group: "Compute", Exec: exec},
+		},
+	}
+
+	t.Run("grouped", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, `Usage:
+  api <command>
+
+Available Commands:
+  login    Log in
+
+Compute Commands:
+  vms    Manage virtual machines
+
+Storage Commands:
+  buckets    Manage buckets
+  objects    Manage objects
+
+Use "api [command] --help" for more information about a command.`, FormatUsage(root, &UsageOptions{Width: 80}))
+	})
+	t.Run("summarized", func(t *testing.T) {
+		t.Parallel()
+		const summary = `Usage:
+  api <command>
+
+Available Commands:
+  login    Log in
+
+Command Groups:
+  Compute    1 command
+  Storage    2 commands
+
+Use "api [command] --help" for more information about a command.`
+		require.Equal(t, summary, FormatUsage(root, &UsageOptions{Width: 80, MaxCommands: 3}))
+		require.Equal(t, FormatUsage(root, &UsageOptions{Width: 80}),
+			FormatUsage(root, &UsageOptions{Width: 80, MaxCommands: 3, AllCommands: true}))
+		require.Equal(t, FormatUsage(root, &UsageOptions{Width: 80}),
+			FormatUsage(root, &UsageOptions{Width: 80, MaxCommands: 4}))
+
+		output := FormatUsage(root, &UsageOptions{Width: 80, MaxCommands: 3, helpCommand: true})
+		require.Equal(t, summary+"\n"+`Use "api help <group>" to list the commands of a group, or "api help --all" to list all commands.`, output)
+	})
+	t.Run("command order", func(t *testing.T) {
+		t.Parallel()
+		unsorted := &Command{
+			Name:                  "api",
+			DisableCommandSorting: true,
+			SubCommands:           slices.Clone(root.SubCommands),
+		}
+		output := FormatUsage(unsorted, &UsageOptions{Width: 80})
+		require.Less(t, strings.Index(output, "Storage Commands:"), strings.Index(output, "Compute Commands:"))
+		require.Less(t, strings.Index(output, "objects"), strings.Index(output, "buckets"))
+	})
+}